@@ -0,0 +1,106 @@
+// kusion-runtime-file is a reference runtime plugin which manages local files as resources of type File.
+// Each resource has two attributes: path of the file and content of the file.
+//
+// Install it into the plugins folder under the kusion data folder to make it work:
+//
+//	go build -o ~/.kusion/plugins/kusion-runtime-File ./cmd/kusion-runtime-file
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+const File models.Type = "File"
+
+var _ runtime.Runtime = (*FileRuntime)(nil)
+
+type FileRuntime struct{}
+
+func (f *FileRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	path, content, err := parse(request.PlanResource)
+	if err != nil {
+		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+	if !request.DryRun {
+		if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+		}
+		if err = ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+		}
+	}
+	return &runtime.ApplyResponse{Resource: request.PlanResource, Status: nil}
+}
+
+func (f *FileRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	path, _, err := parse(request.Resource)
+	if err != nil {
+		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &runtime.ReadResponse{Resource: nil, Status: nil}
+		}
+		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+	return &runtime.ReadResponse{Resource: &models.Resource{
+		ID:         request.Resource.ID,
+		Type:       request.Resource.Type,
+		Attributes: map[string]interface{}{"path": path, "content": string(content)},
+		DependsOn:  request.Resource.DependsOn,
+		Extensions: request.Resource.Extensions,
+	}, Status: nil}
+}
+
+func (f *FileRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	path, _, err := parse(request.Resource)
+	if err != nil {
+		return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
+	}
+	if request.DryRun {
+		return &runtime.DeleteResponse{Status: nil}
+	}
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
+	}
+	return &runtime.DeleteResponse{Status: nil}
+}
+
+// Watch returns the file as ready at once, since writing a file takes effect immediately
+func (f *FileRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	resultCh := make(chan runtime.WatchEvent, 1)
+	resultCh <- runtime.WatchEvent{Resource: request.Resource, Ready: true, Message: "file is written"}
+	close(resultCh)
+	return &runtime.WatchResponse{ResultChan: resultCh, Status: nil}
+}
+
+// parse returns path and content of the file resource
+func parse(resource *models.Resource) (string, string, error) {
+	if resource == nil {
+		return "", "", errors.New("resource is nil")
+	}
+	path, ok := resource.Attributes["path"].(string)
+	if !ok || path == "" {
+		return "", "", fmt.Errorf("path of resource:%s is not specified", resource.ResourceKey())
+	}
+	content, _ := resource.Attributes["content"].(string)
+	return path, content, nil
+}
+
+func main() {
+	if err := plugin.Serve(File, &FileRuntime{}); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"errors"
 	"math/rand"
 	"os"
 	"time"
 
 	_ "kusionstack.io/kcl-plugin"
 	"kusionstack.io/kusion/pkg/kusionctl/cmd"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
 	"kusionstack.io/kusion/pkg/util/pretty"
 )
 
@@ -16,6 +18,13 @@ func main() {
 	command := cmd.NewDefaultKusionctlCommand()
 
 	if err := command.Execute(); err != nil {
+		var exitErr *util.ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.Message != "" {
+				pretty.Error.Println(exitErr.Message)
+			}
+			os.Exit(exitErr.Code)
+		}
 		pretty.Error.Println(err.Error())
 		os.Exit(1)
 	}
@@ -16,10 +16,31 @@ import (
 
 const MaxLogLength = 3751
 
+// OutputsKey is the key of a KCL document declaring outputs of the stack instead of a resource
+const OutputsKey = "outputs"
+
 func ConvertKCLResult2Resources(resourceYAMLs []kcl.KCLResult) (*models.Spec, error) {
 	resources := []models.Resource{}
+	var outputs map[string]interface{}
 
 	for _, resourcesYamlMap := range resourceYAMLs {
+		if v, ok := resourcesYamlMap[OutputsKey]; ok && len(resourcesYamlMap) == 1 {
+			o, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s must be a map, got %T", OutputsKey, v)
+			}
+			if outputs == nil {
+				outputs = make(map[string]interface{}, len(o))
+			}
+			for name, value := range o {
+				if _, ok := outputs[name]; ok {
+					return nil, fmt.Errorf("duplicate output:%s", name)
+				}
+				outputs[name] = value
+			}
+			continue
+		}
+
 		// Convert kcl result to yaml string
 		msg := jsonUtil.MustMarshal2String(resourcesYamlMap)
 		if len(msg) > MaxLogLength {
@@ -53,5 +74,5 @@ func ConvertKCLResult2Resources(resourceYAMLs []kcl.KCLResult) (*models.Spec, er
 		resources = append(resources, *item)
 	}
 
-	return &models.Spec{Resources: resources}, nil
+	return &models.Spec{Resources: resources, Outputs: outputs}, nil
 }
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// HooksExtension is the key of hooks in Resource.Extensions, which are executed before or after the resource is applied
+const HooksExtension = "hooks"
+
+// HookPhase decides when a hook is executed. Hooks are executed in applies changing their resources, and skipped if
+// all their resources are unchanged
+type HookPhase string
+
+// HookPhase values
+const (
+	// PreApply hooks are executed before their resources are applied, and after resources they depend on are applied
+	PreApply HookPhase = "preApply"
+	// PostApply hooks are executed after their resources are applied, and before resources depending on them are applied
+	PostApply HookPhase = "postApply"
+)
+
+// Hook is a shell command or a Kubernetes Job executed during apply. Exactly one of Command and Job should be set
+type Hook struct {
+	// Name identifies the hook among hooks of the same stack or resource
+	Name string `json:"name" yaml:"name"`
+
+	// Phase decides when the hook is executed
+	Phase HookPhase `json:"phase" yaml:"phase"`
+
+	// Command is a shell command executed by `sh -c`. Its output is streamed into the apply progress
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+
+	// WorkDir is the working directory of the Command. Empty means the working directory of Kusion
+	WorkDir string `json:"workDir,omitempty" yaml:"workDir,omitempty"`
+
+	// Job is the manifest of a Kubernetes Job, which is created by the Kubernetes Runtime and deleted after it completes
+	Job map[string]interface{} `json:"job,omitempty" yaml:"job,omitempty"`
+
+	// Resources are IDs of resources this hook is executed before or after. It is only used by hooks of stacks,
+	// and empty means all resources of the stack
+	Resources []string `json:"resources,omitempty" yaml:"resources,omitempty"`
+}
+
+// Validate checks whether the hook is legal
+func (h *Hook) Validate() error {
+	if h.Name == "" {
+		return errors.New("hook name can't be empty")
+	}
+	if h.Phase != PreApply && h.Phase != PostApply {
+		return fmt.Errorf("phase of hook:%s must be one of %s and %s, got %s", h.Name, PreApply, PostApply, h.Phase)
+	}
+	if (h.Command == "") == (len(h.Job) == 0) {
+		return fmt.Errorf("exactly one of command and job should be set in hook:%s", h.Name)
+	}
+	return nil
+}
+
+// Hooks parses hooks of this resource in Extensions
+func (r *Resource) Hooks() ([]*Hook, error) {
+	v, ok := r.Extensions[HooksExtension]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	// hooks are compiled from the configuration as generic values, so convert them by a JSON round trip
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var hooks []*Hook
+	if err = json.Unmarshal(data, &hooks); err != nil {
+		return nil, fmt.Errorf("%s of resource:%s must be a list of hooks. %v", HooksExtension, r.ID, err)
+	}
+	for _, h := range hooks {
+		if len(h.Resources) != 0 {
+			return nil, fmt.Errorf("resources can't be set in hook:%s of resource:%s", h.Name, r.ID)
+		}
+		if err = h.Validate(); err != nil {
+			return nil, fmt.Errorf("illegal hook of resource:%s. %v", r.ID, err)
+		}
+	}
+	return hooks, nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResource_Hooks(t *testing.T) {
+	tests := []struct {
+		name       string
+		extensions map[string]interface{}
+		want       []*Hook
+		wantErr    bool
+	}{
+		{
+			name: "no-hooks",
+		},
+		{
+			name: "hooks",
+			extensions: map[string]interface{}{HooksExtension: []interface{}{
+				map[string]interface{}{"name": "migrate", "phase": "preApply", "command": "make migrate"},
+				map[string]interface{}{"name": "smoke", "phase": "postApply", "job": map[string]interface{}{"kind": "Job"}},
+			}},
+			want: []*Hook{
+				{Name: "migrate", Phase: PreApply, Command: "make migrate"},
+				{Name: "smoke", Phase: PostApply, Job: map[string]interface{}{"kind": "Job"}},
+			},
+		},
+		{
+			name:       "not-a-list",
+			extensions: map[string]interface{}{HooksExtension: "make migrate"},
+			wantErr:    true,
+		},
+		{
+			name: "illegal-phase",
+			extensions: map[string]interface{}{HooksExtension: []interface{}{
+				map[string]interface{}{"name": "migrate", "phase": "preDestroy", "command": "make migrate"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "both-command-and-job",
+			extensions: map[string]interface{}{HooksExtension: []interface{}{
+				map[string]interface{}{"name": "migrate", "phase": "preApply", "command": "make migrate", "job": map[string]interface{}{"kind": "Job"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "with-resources",
+			extensions: map[string]interface{}{HooksExtension: []interface{}{
+				map[string]interface{}{"name": "migrate", "phase": "preApply", "command": "make migrate", "resources": []interface{}{"foo"}},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Resource{ID: "foo", Extensions: tt.extensions}
+			got, err := r.Hooks()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
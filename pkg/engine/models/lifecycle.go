@@ -0,0 +1,88 @@
+package models
+
+import "fmt"
+
+// Keys of lifecycle settings in Resource.Extensions
+const (
+	// PreventDestroyExtension blocks deleting the resource, including deleting it for a replacement
+	PreventDestroyExtension = "preventDestroy"
+	// IgnoreChangesExtension is a list of attribute paths like "spec.replicas", which are excluded from diff and patch
+	IgnoreChangesExtension = "ignoreChanges"
+	// CreateBeforeDestroyExtension creates the replacement of the resource before deleting it when it must be replaced.
+	// It is ignored if the replacement has the same identity, e.g. the name of a Kubernetes object
+	CreateBeforeDestroyExtension = "createBeforeDestroy"
+	// DeletionPolicyExtension decides whether the resource is deleted from the actual infra when it is deleted
+	DeletionPolicyExtension = "deletionPolicy"
+)
+
+type DeletionPolicy string
+
+// DeletionPolicy values
+const (
+	// DeletionPolicyDelete deletes the resource from both the actual infra and the State, which is the default policy
+	DeletionPolicyDelete DeletionPolicy = "delete"
+	// DeletionPolicyRetain removes the resource from the State only, and it is kept in the actual infra
+	DeletionPolicyRetain DeletionPolicy = "retain"
+)
+
+// Lifecycle contains settings about how the resource is managed by Kusion, which are set in Resource.Extensions
+type Lifecycle struct {
+	PreventDestroy      bool
+	IgnoreChanges       []string
+	CreateBeforeDestroy bool
+	DeletionPolicy      DeletionPolicy
+}
+
+// Lifecycle parses lifecycle settings in Extensions. Settings with illegal values are reported as errors instead of
+// being ignored, since ignoring a misspelled preventDestroy may lead to deleting resources unexpectedly
+func (r *Resource) Lifecycle() (*Lifecycle, error) {
+	l := &Lifecycle{DeletionPolicy: DeletionPolicyDelete}
+	var err error
+	if l.PreventDestroy, err = r.boolExtension(PreventDestroyExtension); err != nil {
+		return nil, err
+	}
+	if l.CreateBeforeDestroy, err = r.boolExtension(CreateBeforeDestroyExtension); err != nil {
+		return nil, err
+	}
+
+	if v, ok := r.Extensions[IgnoreChangesExtension]; ok && v != nil {
+		switch paths := v.(type) {
+		case []string:
+			l.IgnoreChanges = paths
+		case []interface{}:
+			for _, p := range paths {
+				path, ok := p.(string)
+				if !ok || path == "" {
+					return nil, fmt.Errorf("%s of resource:%s must be a list of attribute paths, got %v", IgnoreChangesExtension, r.ID, v)
+				}
+				l.IgnoreChanges = append(l.IgnoreChanges, path)
+			}
+		default:
+			return nil, fmt.Errorf("%s of resource:%s must be a list of attribute paths, got %v", IgnoreChangesExtension, r.ID, v)
+		}
+	}
+
+	if v, ok := r.Extensions[DeletionPolicyExtension]; ok && v != nil {
+		policy, _ := v.(string)
+		switch DeletionPolicy(policy) {
+		case DeletionPolicyDelete, DeletionPolicyRetain:
+			l.DeletionPolicy = DeletionPolicy(policy)
+		default:
+			return nil, fmt.Errorf("%s of resource:%s must be one of %s and %s, got %v",
+				DeletionPolicyExtension, r.ID, DeletionPolicyDelete, DeletionPolicyRetain, v)
+		}
+	}
+	return l, nil
+}
+
+func (r *Resource) boolExtension(key string) (bool, error) {
+	v, ok := r.Extensions[key]
+	if !ok || v == nil {
+		return false, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s of resource:%s must be a bool, got %v", key, r.ID, v)
+	}
+	return b, nil
+}
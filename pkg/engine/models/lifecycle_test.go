@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResource_Lifecycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		extensions map[string]interface{}
+		want       *Lifecycle
+		wantErr    bool
+	}{
+		{
+			name: "default",
+			want: &Lifecycle{DeletionPolicy: DeletionPolicyDelete},
+		},
+		{
+			name: "all-settings",
+			extensions: map[string]interface{}{
+				PreventDestroyExtension:      true,
+				IgnoreChangesExtension:       []interface{}{"spec.replicas", "metadata.labels"},
+				CreateBeforeDestroyExtension: true,
+				DeletionPolicyExtension:      "retain",
+			},
+			want: &Lifecycle{
+				PreventDestroy:      true,
+				IgnoreChanges:       []string{"spec.replicas", "metadata.labels"},
+				CreateBeforeDestroy: true,
+				DeletionPolicy:      DeletionPolicyRetain,
+			},
+		},
+		{
+			name:       "illegal-prevent-destroy",
+			extensions: map[string]interface{}{PreventDestroyExtension: "yes"},
+			wantErr:    true,
+		},
+		{
+			name:       "illegal-ignore-changes",
+			extensions: map[string]interface{}{IgnoreChangesExtension: "spec.replicas"},
+			wantErr:    true,
+		},
+		{
+			name:       "illegal-deletion-policy",
+			extensions: map[string]interface{}{DeletionPolicyExtension: "orphan"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Resource{ID: "foo", Extensions: tt.extensions}
+			got, err := r.Lifecycle()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
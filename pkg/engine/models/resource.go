@@ -2,6 +2,11 @@ package models
 
 type Type string
 
+// Types of resources we supported
+const (
+	Kubernetes Type = "Kubernetes"
+)
+
 type Resources []Resource
 
 type Resource struct {
@@ -25,6 +30,14 @@ func (r *Resource) ResourceKey() string {
 	return r.ID
 }
 
+// RuntimeType returns the Type of the Runtime this resource belongs to. Resources without Type are regarded as Kubernetes resources
+func (r *Resource) RuntimeType() Type {
+	if r.Type == "" {
+		return Kubernetes
+	}
+	return r.Type
+}
+
 func (rs Resources) Index() map[string]*Resource {
 	m := make(map[string]*Resource)
 	for i := range rs {
@@ -3,4 +3,8 @@ package models
 // Spec represents desired state of resources in one stack and will be applied to the actual infrastructure by the Kusion Engine
 type Spec struct {
 	Resources Resources `json:"resources"`
+
+	// Outputs are values exposed by this stack after it is applied. Values may refer to attributes of resources
+	// by implicit references like `$kusion_path.resourceKey.attribute`, which are resolved after apply
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
 }
@@ -1,6 +1,7 @@
 package operation
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -15,6 +16,7 @@ import (
 	"github.com/hashicorp/terraform/tfdiags"
 
 	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/status"
@@ -50,12 +52,24 @@ func NewApplyGraph(m *models.Spec, priorState *states.State) (*dag.AcyclicGraph,
 	return g, s
 }
 
+// parseKindOrder orders Kubernetes resources in the graph by their kinds, unless it is disabled by the request
+func parseKindOrder(request *opsmodels.Request, g *dag.AcyclicGraph) status.Status {
+	if request.DisableKindOrder {
+		return nil
+	}
+	return parser.NewKindOrderParser().Parse(g)
+}
+
 // Apply means turn all actual infra resources into the desired state described in the request by invoking a specified Runtime.
 // Like other operations, Apply has 3 main steps during the whole process.
 //  1. parse resources and their relationship to build a DAG and should take care of those resources that will be deleted
 //  2. walk this DAG and execute all graph nodes concurrently, besides the entire process should follow dependencies in this DAG
 //  3. during the execution of each node, it will invoke different runtime according to the resource type
-func (ao *ApplyOperation) Apply(request *ApplyRequest) (rsp *ApplyResponse, st status.Status) {
+//
+// The ctx is passed to every Runtime call. Once it is done, nodes not started will be skipped, in-flight runtime calls
+// will be canceled, and a status.Canceled status will be returned. Once the StopCh of the operation is closed, nodes
+// not started will be skipped as well, but in-flight runtime calls will finish and their results will be saved
+func (ao *ApplyOperation) Apply(ctx context.Context, request *ApplyRequest) (rsp *ApplyResponse, st status.Status) {
 	log.Infof("engine: Apply start!")
 	o := ao.Operation
 
@@ -89,34 +103,67 @@ func (ao *ApplyOperation) Apply(request *ApplyRequest) (rsp *ApplyResponse, st s
 	if status.IsErr(s) {
 		return nil, s
 	}
+	if s = parser.NewTargetParser(request.Targets, request.Excludes).Parse(applyGraph); status.IsErr(s) {
+		return nil, s
+	}
+	if s = parseKindOrder(&request.Request, applyGraph); status.IsErr(s) {
+		return nil, s
+	}
+	if s = parser.NewHookParser(request.Hooks).Parse(applyGraph); status.IsErr(s) {
+		return nil, s
+	}
 	log.Infof("Apply Graph:\n%s", applyGraph.String())
 
+	// Jobs of hooks are executed by the Kubernetes Runtime, even if no resource of the Spec uses it
+	runtimeMap, err := runtime.InitRuntimes(o.RuntimeMap, request.Spec.Resources, priorState.Resources, hookJobs(applyGraph))
+	if err != nil {
+		return nil, status.NewErrorStatus(err)
+	}
+
 	applyOperation := &ApplyOperation{
 		Operation: opsmodels.Operation{
 			OperationType:           types.Apply,
 			StateStorage:            o.StateStorage,
+			StackStorage:            o.StackStorage,
 			CtxResourceIndex:        map[string]*models.Resource{},
 			PriorStateResourceIndex: priorStateResourceIndex,
 			StateResourceIndex:      priorStateResourceIndex,
-			Runtime:                 o.Runtime,
+			RuntimeMap:              runtimeMap,
 			MsgCh:                   o.MsgCh,
 			ResultState:             resultState,
+			PlannedOrder:            o.PlannedOrder,
 			Lock:                    &sync.Mutex{},
+			StopCh:                  o.StopCh,
+			WatchTimeout:            o.WatchTimeout,
+			ResourceTimeout:         o.ResourceTimeout,
+			Parallelism:             o.Parallelism,
+			RuntimeParallelism:      o.RuntimeParallelism,
+			DryRun:                  o.DryRun,
+			ServerSideApply:         o.ServerSideApply,
+			ForceConflicts:          o.ForceConflicts,
 		},
 	}
 
-	w := &dag.Walker{Callback: applyOperation.applyWalkFun}
+	scheduleCtx, cancelSchedule := scheduleContext(ctx, o.StopCh)
+	defer cancelSchedule()
+	w := &dag.Walker{Callback: limitWalkFun(scheduleCtx, &applyOperation.Operation, func(v dag.Vertex) tfdiags.Diagnostics {
+		return applyOperation.applyWalkFun(ctx, scheduleCtx, v)
+	})}
 	w.Update(applyGraph)
 	// Wait
 	if diags := w.Wait(); diags.HasErrors() {
-		st = status.NewErrorStatus(diags.Err())
+		st = walkErrStatus(scheduleCtx, diags)
 		return nil, st
 	}
+	if s = applyOperation.updateOutputs(ctx, request.Spec.Outputs); status.IsErr(s) {
+		return nil, s
+	}
 
 	return &ApplyResponse{State: resultState}, nil
 }
 
-func (ao *ApplyOperation) applyWalkFun(v dag.Vertex) (diags tfdiags.Diagnostics) {
+// applyWalkFun executes the node with the ctx. Nodes are only started before the scheduleCtx is done
+func (ao *ApplyOperation) applyWalkFun(ctx, scheduleCtx context.Context, v dag.Vertex) (diags tfdiags.Diagnostics) {
 	var s status.Status
 	if v == nil {
 		return nil
@@ -141,17 +188,24 @@ func (ao *ApplyOperation) applyWalkFun(v dag.Vertex) (diags tfdiags.Diagnostics)
 	}()
 
 	if node, ok := v.(graph.ExecutableNode); ok {
-		if rn, ok2 := v.(*graph.ResourceNode); ok2 {
-			o.MsgCh <- opsmodels.Message{ResourceID: rn.Hashcode().(string)}
+		switch v.(type) {
+		case *graph.ResourceNode, *graph.HookNode:
+			id := v.(dag.Hashable).Hashcode().(string)
+			// stop scheduling new resources once the operation is canceled or stopped, e.g. interrupted by users.
+			// No message is sent for them, so that they can be reported as not started
+			if err := scheduleCtx.Err(); err != nil {
+				return diags.Append(fmt.Errorf("skip resource:%s since the operation is canceled. %v", id, err))
+			}
+			o.MsgCh <- opsmodels.Message{ResourceID: id}
 
-			s = node.Execute(o)
+			s = node.Execute(ctx, o)
 			if status.IsErr(s) {
-				o.MsgCh <- opsmodels.Message{ResourceID: rn.Hashcode().(string), OpResult: opsmodels.Failed, OpErr: fmt.Errorf("node execte failed, status: %v", s)}
+				o.MsgCh <- opsmodels.Message{ResourceID: id, OpResult: opsmodels.Failed, OpErr: fmt.Errorf("node execte failed, status: %v", s)}
 			} else {
-				o.MsgCh <- opsmodels.Message{ResourceID: rn.Hashcode().(string), OpResult: opsmodels.Success}
+				o.MsgCh <- opsmodels.Message{ResourceID: id, OpResult: opsmodels.Success}
 			}
-		} else {
-			s = node.Execute(o)
+		default:
+			s = node.Execute(ctx, o)
 		}
 	}
 	if s != nil {
@@ -160,6 +214,43 @@ func (ao *ApplyOperation) applyWalkFun(v dag.Vertex) (diags tfdiags.Diagnostics)
 	return diags
 }
 
+// hookJobs returns Jobs of hooks in the graph
+func hookJobs(g *dag.AcyclicGraph) models.Resources {
+	var jobs models.Resources
+	for _, v := range g.Vertices() {
+		if hn, ok := v.(*graph.HookNode); ok {
+			if job := hn.JobResource(); job != nil {
+				jobs = append(jobs, *job)
+			}
+		}
+	}
+	return jobs
+}
+
+// scheduleContext returns the ctx to schedule nodes of an operation, which is done when the ctx is done or the stopCh
+// is closed. In-flight nodes keep running with the ctx, so that their runtime calls are not canceled by the stopCh
+func scheduleContext(ctx context.Context, stopCh <-chan struct{}) (context.Context, context.CancelFunc) {
+	scheduleCtx, cancel := context.WithCancel(ctx)
+	if stopCh != nil {
+		go func() {
+			select {
+			case <-stopCh:
+				cancel()
+			case <-scheduleCtx.Done():
+			}
+		}()
+	}
+	return scheduleCtx, cancel
+}
+
+// walkErrStatus converts errors of walking the DAG to a status. Errors after the ctx is done are regarded as canceled
+func walkErrStatus(ctx context.Context, diags tfdiags.Diagnostics) status.Status {
+	if ctx.Err() != nil {
+		return status.NewErrorStatusWithCode(status.Canceled, diags.Err())
+	}
+	return status.NewErrorStatus(diags.Err())
+}
+
 func validateRequest(request *opsmodels.Request) status.Status {
 	var s status.Status
 
@@ -178,6 +269,24 @@ func validateRequest(request *opsmodels.Request) status.Status {
 			return status.NewErrorStatusWithMsg(status.InvalidArgument, fmt.Sprintf("Duplicate resource:%s in request.", key))
 		}
 		resourceKeyMap[key] = true
+		if _, err := resource.Lifecycle(); err != nil {
+			return status.NewErrorStatusWithMsg(status.InvalidArgument, err.Error())
+		}
+		if _, err := resource.Hooks(); err != nil {
+			return status.NewErrorStatusWithMsg(status.InvalidArgument, err.Error())
+		}
+	}
+
+	resourceIndex := request.Spec.Resources.Index()
+	for _, hook := range request.Hooks {
+		if err := hook.Validate(); err != nil {
+			return status.NewErrorStatusWithMsg(status.InvalidArgument, err.Error())
+		}
+		for _, id := range hook.Resources {
+			if resourceIndex[id] == nil {
+				return status.NewErrorStatusWithMsg(status.InvalidArgument, fmt.Sprintf("resource:%s of hook:%s is not found in request.Spec", id, hook.Name))
+			}
+		}
 	}
 
 	return s
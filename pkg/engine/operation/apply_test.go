@@ -4,10 +4,13 @@
 package operation
 
 import (
+	"context"
+	"errors"
 	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"kusionstack.io/kusion/pkg/engine/states/local"
 
@@ -18,6 +21,7 @@ import (
 
 	"bou.ke/monkey"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/hashicorp/terraform/tfdiags"
 	"github.com/stretchr/testify/assert"
 
 	"kusionstack.io/kusion/pkg/engine/models"
@@ -70,7 +74,7 @@ func TestOperation_Apply(t *testing.T) {
 		PriorStateResourceIndex map[string]*models.Resource
 		StateResourceIndex      map[string]*models.Resource
 		Order                   *opsmodels.ChangeOrder
-		Runtime                 runtime.Runtime
+		RuntimeMap              map[models.Type]runtime.Runtime
 		MsgCh                   chan opsmodels.Message
 		resultState             *states.State
 		lock                    *sync.Mutex
@@ -122,7 +126,7 @@ func TestOperation_Apply(t *testing.T) {
 			fields: fields{
 				OperationType: types.Apply,
 				StateStorage:  &local.FileSystemState{Path: filepath.Join("test_data", local.KusionState)},
-				Runtime:       &runtime.KubernetesRuntime{},
+				RuntimeMap:    map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
 				MsgCh:         make(chan opsmodels.Message, 5),
 			},
 			args: args{applyRequest: &ApplyRequest{opsmodels.Request{
@@ -146,7 +150,7 @@ func TestOperation_Apply(t *testing.T) {
 				PriorStateResourceIndex: tt.fields.PriorStateResourceIndex,
 				StateResourceIndex:      tt.fields.StateResourceIndex,
 				ChangeOrder:             tt.fields.Order,
-				Runtime:                 tt.fields.Runtime,
+				RuntimeMap:              tt.fields.RuntimeMap,
 				MsgCh:                   tt.fields.MsgCh,
 				ResultState:             tt.fields.resultState,
 				Lock:                    tt.fields.lock,
@@ -155,14 +159,286 @@ func TestOperation_Apply(t *testing.T) {
 				Operation: *o,
 			}
 
-			monkey.Patch((*graph.ResourceNode).Execute, func(rn *graph.ResourceNode, operation *opsmodels.Operation) status.Status {
+			monkey.Patch((*graph.ResourceNode).Execute, func(rn *graph.ResourceNode, ctx context.Context, operation *opsmodels.Operation) status.Status {
 				o.ResultState = rs
 				return nil
 			})
 
-			gotRsp, gotSt := ao.Apply(tt.args.applyRequest)
+			gotRsp, gotSt := ao.Apply(context.Background(), tt.args.applyRequest)
 			assert.Equalf(t, tt.wantRsp.State.Stack, gotRsp.State.Stack, "Apply(%v)", tt.args.applyRequest)
 			assert.Equalf(t, tt.wantSt, gotSt, "Apply(%v)", tt.args.applyRequest)
 		})
 	}
 }
+
+func Test_walkErrStatus(t *testing.T) {
+	diags := tfdiags.Diagnostics{}.Append(errors.New("node execute failed"))
+
+	assert.Equal(t, status.Internal, walkErrStatus(context.Background(), diags).Code())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, status.Canceled, walkErrStatus(ctx, diags).Code())
+}
+
+func TestOperation_ApplyUnchangedReference(t *testing.T) {
+	jack := models.Resource{ID: "jack", Attributes: map[string]interface{}{"a": "b"}}
+	pony := models.Resource{ID: "pony", Attributes: map[string]interface{}{"ref": graph.ImplicitRefPrefix + "jack.a"}}
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	assert.NoError(t, storage.Apply(&states.State{
+		Project: "fakeProject",
+		Stack:   "fakeStack",
+		Serial:  1,
+		Resources: models.Resources{
+			jack,
+			{ID: "pony", Attributes: map[string]interface{}{"ref": "old"}},
+		},
+	}))
+
+	defer monkey.UnpatchAll()
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Read",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+			// jack is unchanged in the actual infra, and pony refers to a value changed since the last apply
+			if request.Resource.ID == jack.ID {
+				return &runtime.ReadResponse{Resource: &jack}
+			}
+			return &runtime.ReadResponse{Resource: &models.Resource{ID: "pony", Attributes: map[string]interface{}{"ref": "old"}}}
+		})
+	var applied []*models.Resource
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Apply",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+			applied = append(applied, request.PlanResource)
+			return &runtime.ApplyResponse{Resource: request.PlanResource}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Watch",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+			return nil
+		})
+
+	ao := &ApplyOperation{Operation: opsmodels.Operation{
+		StateStorage: storage,
+		RuntimeMap:   map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
+		MsgCh:        make(chan opsmodels.Message, 10),
+	}}
+	rsp, s := ao.Apply(context.Background(), &ApplyRequest{opsmodels.Request{
+		Project: "fakeProject",
+		Stack:   "fakeStack",
+		Spec:    &models.Spec{Resources: models.Resources{jack, pony}},
+	}})
+	assert.Nil(t, s)
+	if assert.Len(t, applied, 1) {
+		assert.Equal(t, "pony", applied[0].ID)
+		assert.Equal(t, "b", applied[0].Attributes["ref"])
+	}
+	assert.Equal(t, "b", rsp.State.Resources.Index()["pony"].Attributes["ref"])
+}
+
+func TestOperation_ApplyOutputs(t *testing.T) {
+	svc := models.Resource{
+		ID:         "v1:Service:default:nginx",
+		Type:       models.Kubernetes,
+		Attributes: map[string]interface{}{"spec": map[string]interface{}{"ports": []interface{}{80}}},
+	}
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+
+	defer monkey.UnpatchAll()
+	created := false
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Read",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+			if !created {
+				return &runtime.ReadResponse{}
+			}
+			// the clusterIP is assigned by the server, and it is missing in the applied attributes
+			return &runtime.ReadResponse{Resource: &models.Resource{
+				ID:   svc.ID,
+				Type: models.Kubernetes,
+				Attributes: map[string]interface{}{
+					"spec": map[string]interface{}{"ports": []interface{}{80}, "clusterIP": "10.0.0.1"},
+				},
+			}}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Apply",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+			created = true
+			return &runtime.ApplyResponse{Resource: request.PlanResource}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Watch",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+			return nil
+		})
+
+	ao := &ApplyOperation{Operation: opsmodels.Operation{
+		StateStorage: storage,
+		RuntimeMap:   map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
+		MsgCh:        make(chan opsmodels.Message, 10),
+	}}
+	rsp, s := ao.Apply(context.Background(), &ApplyRequest{opsmodels.Request{
+		Project: "fakeProject",
+		Stack:   "fakeStack",
+		Spec: &models.Spec{
+			Resources: models.Resources{svc},
+			Outputs: map[string]interface{}{
+				"cluster_ip": graph.ImplicitRefPrefix + svc.ID + ".spec.clusterIP",
+			},
+		},
+	}})
+	assert.Nil(t, s)
+	assert.Equal(t, map[string]interface{}{"cluster_ip": "10.0.0.1"}, rsp.State.Outputs)
+	latest, err := storage.GetLatestState(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"cluster_ip": "10.0.0.1"}, latest.Outputs)
+	// definitions are kept to evaluate outputs again on rollbacks
+	assert.Equal(t, map[string]interface{}{"cluster_ip": graph.ImplicitRefPrefix + svc.ID + ".spec.clusterIP"},
+		latest.OutputDefinitions)
+}
+
+func TestOperation_ApplyStopped(t *testing.T) {
+	jack := models.Resource{ID: "jack", Attributes: map[string]interface{}{"a": "b"}}
+	pony := models.Resource{ID: "pony", Attributes: map[string]interface{}{"c": "d"}, DependsOn: []string{"jack"}}
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	stopCh := make(chan struct{})
+
+	defer monkey.UnpatchAll()
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Read",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+			return &runtime.ReadResponse{}
+		})
+	var applied []string
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Apply",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+			applied = append(applied, request.PlanResource.ID)
+			// interrupted while jack is in flight, which should not cancel the runtime call
+			close(stopCh)
+			time.Sleep(10 * time.Millisecond)
+			assert.Nil(t, ctx.Err())
+			return &runtime.ApplyResponse{Resource: request.PlanResource}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Watch",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+			return nil
+		})
+
+	ao := &ApplyOperation{Operation: opsmodels.Operation{
+		StateStorage: storage,
+		RuntimeMap:   map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
+		MsgCh:        make(chan opsmodels.Message, 10),
+		StopCh:       stopCh,
+	}}
+	_, s := ao.Apply(context.Background(), &ApplyRequest{opsmodels.Request{
+		Project: "fakeProject",
+		Stack:   "fakeStack",
+		Spec:    &models.Spec{Resources: models.Resources{jack, pony}},
+	}})
+	assert.Equal(t, status.Canceled, s.Code())
+	// pony is not started, and jack in flight is saved in the state
+	assert.Equal(t, []string{"jack"}, applied)
+	latest, err := storage.GetLatestState(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, models.Resources{jack}, latest.Resources)
+}
+
+func TestOperation_ApplyHooks(t *testing.T) {
+	job := func(name string) map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "Job",
+			"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		}
+	}
+	jack := models.Resource{
+		ID:         "jack",
+		Attributes: map[string]interface{}{"a": "b"},
+		Extensions: map[string]interface{}{models.HooksExtension: []interface{}{
+			map[string]interface{}{"name": "pre", "phase": "preApply", "job": job("pre")},
+			map[string]interface{}{"name": "post", "phase": "postApply", "job": job("post")},
+		}},
+	}
+	// pony refers to jack, so the preApply hook of the stack computes the action of pony after that of jack
+	pony := models.Resource{ID: "pony", Attributes: map[string]interface{}{"ref": graph.ImplicitRefPrefix + "jack.a"}}
+	hooks := []*models.Hook{
+		{Name: "stack-pre", Phase: models.PreApply, Job: job("stack-pre")},
+		{Name: "stack-post", Phase: models.PostApply, Job: job("stack-post")},
+	}
+
+	tests := []struct {
+		name        string
+		liveA       string
+		wantApplied []string
+	}{
+		{
+			name:        "unchanged",
+			liveA:       "b",
+			wantApplied: nil,
+		},
+		{
+			name:  "changed out-of-band",
+			liveA: "c",
+			wantApplied: []string{
+				"hook:preApply:pre@jack",
+				"hook:preApply:stack-pre",
+				"jack",
+				"hook:postApply:post@jack",
+				"hook:postApply:stack-post",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+			assert.NoError(t, storage.Apply(&states.State{
+				Project: "fakeProject",
+				Stack:   "fakeStack",
+				Serial:  1,
+				Resources: models.Resources{
+					jack,
+					{ID: "pony", Attributes: map[string]interface{}{"ref": "b"}},
+				},
+			}))
+
+			defer monkey.UnpatchAll()
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Read",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+					switch request.Resource.ID {
+					case jack.ID:
+						return &runtime.ReadResponse{Resource: &models.Resource{ID: jack.ID, Attributes: map[string]interface{}{"a": tt.liveA}}}
+					case pony.ID:
+						return &runtime.ReadResponse{Resource: &models.Resource{ID: pony.ID, Attributes: map[string]interface{}{"ref": "b"}}}
+					default:
+						// jobs of hooks are gone once they are deleted
+						return &runtime.ReadResponse{}
+					}
+				})
+			var lock sync.Mutex
+			var applied []string
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Apply",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+					lock.Lock()
+					defer lock.Unlock()
+					applied = append(applied, request.PlanResource.ID)
+					return &runtime.ApplyResponse{Resource: request.PlanResource}
+				})
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Delete",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+					return &runtime.DeleteResponse{}
+				})
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Watch",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+					return nil
+				})
+
+			ao := &ApplyOperation{Operation: opsmodels.Operation{
+				StateStorage: storage,
+				RuntimeMap:   map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
+				MsgCh:        make(chan opsmodels.Message, 100),
+			}}
+			_, s := ao.Apply(context.Background(), &ApplyRequest{opsmodels.Request{
+				Project: "fakeProject",
+				Stack:   "fakeStack",
+				Spec:    &models.Spec{Resources: models.Resources{jack, pony}},
+				Hooks:   hooks,
+			}})
+			assert.Nil(t, s)
+			assert.ElementsMatch(t, tt.wantApplied, applied)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package operation
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -15,6 +16,8 @@ import (
 	"kusionstack.io/kusion/pkg/engine/operation/types"
 
 	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/states"
 
 	"github.com/hashicorp/terraform/dag"
 
@@ -42,9 +45,50 @@ func NewDestroyGraph(resource models.Resources) (*dag.AcyclicGraph, status.Statu
 	return ag, s
 }
 
-// Destroy will delete all resources in this request. The whole process is similar to the operation Apply,
-// but every node's execution is deleting the resource.
-func (do *DestroyOperation) Destroy(request *DestroyRequest) (st status.Status) {
+// newStateDestroyGraph builds the DAG to destroy resources in the latest state. If the spec is given, only resources
+// in both the state and the spec are destroyed, together with resources depending on them
+func newStateDestroyGraph(priorState *states.State, spec *models.Spec) (*dag.AcyclicGraph, status.Status) {
+	if spec == nil {
+		return NewDestroyGraph(priorState.Resources)
+	}
+
+	specIndex := spec.Resources.Index()
+	var targets []string
+	for _, res := range priorState.Resources {
+		if specIndex[res.ResourceKey()] != nil {
+			targets = append(targets, res.ResourceKey())
+		}
+	}
+	if len(targets) == 0 {
+		return NewDestroyGraph(nil)
+	}
+	ag, s := NewDestroyGraph(priorState.Resources)
+	if status.IsErr(s) {
+		return nil, s
+	}
+	if s = parser.NewTargetParser(targets, nil).Parse(ag); status.IsErr(s) {
+		return nil, s
+	}
+	return ag, nil
+}
+
+// validateDestroyRequest validates the request of destroy and destroy preview, whose Spec is optional
+func validateDestroyRequest(request *opsmodels.Request) status.Status {
+	if request != nil && request.Spec == nil {
+		return nil
+	}
+	return validateRequest(request)
+}
+
+// partialDestroy reports whether only some resources of the stack are destroyed by the request
+func partialDestroy(request *opsmodels.Request) bool {
+	return request.Spec != nil || len(request.Targets) != 0 || len(request.Excludes) != 0
+}
+
+// Destroy will delete all resources in the latest state, or only those in the Spec of this request if it is given.
+// The whole process is similar to the operation Apply, but every node's execution is deleting the resource.
+// After all resources are deleted, the state is marked empty
+func (do *DestroyOperation) Destroy(ctx context.Context, request *DestroyRequest) (st status.Status) {
 	o := do.Operation
 
 	defer func() {
@@ -63,21 +107,36 @@ func (do *DestroyOperation) Destroy(request *DestroyRequest) (st status.Status)
 		}
 	}()
 
-	if st = validateRequest(&request.Request); status.IsErr(st) {
+	if st = validateDestroyRequest(&request.Request); status.IsErr(st) {
 		return st
 	}
 
 	// 1. init & build Indexes
-	_, resultState := o.InitStates(&request.Request)
-	// replace priorState.Resources with models.Resources, so we do Delete in all nodes
-	resources := request.Request.Spec.Resources
-	priorStateResourceIndex := resources.Index()
+	priorState, resultState := o.InitStates(&request.Request)
+	partial := partialDestroy(&request.Request)
+	if !partial {
+		// outputs refer to resources of the stack, which are all destroyed
+		resultState.Outputs = nil
+		resultState.OutputDefinitions = nil
+	}
+	// resources are destroyed by the latest state, so that those removed from the KCL code are not left behind
+	priorStateResourceIndex := priorState.Resources.Index()
+	runtimeMap, err := runtime.InitRuntimes(o.RuntimeMap, priorState.Resources)
+	if err != nil {
+		return status.NewErrorStatus(err)
+	}
 
 	// 2. build & walk DAG
-	destroyGraph, s := NewDestroyGraph(resources)
+	destroyGraph, s := newStateDestroyGraph(priorState, request.Spec)
 	if status.IsErr(s) {
 		return s
 	}
+	if s = parser.NewTargetParser(request.Targets, request.Excludes).Parse(destroyGraph); status.IsErr(s) {
+		return s
+	}
+	if s = parseKindOrder(&request.Request, destroyGraph); status.IsErr(s) {
+		return s
+	}
 
 	newDo := &DestroyOperation{
 		Operation: opsmodels.Operation{
@@ -86,26 +145,41 @@ func (do *DestroyOperation) Destroy(request *DestroyRequest) (st status.Status)
 			CtxResourceIndex:        map[string]*models.Resource{},
 			PriorStateResourceIndex: priorStateResourceIndex,
 			StateResourceIndex:      priorStateResourceIndex,
-			Runtime:                 o.Runtime,
+			RuntimeMap:              runtimeMap,
 			MsgCh:                   o.MsgCh,
 			ResultState:             resultState,
 			Lock:                    &sync.Mutex{},
+			StopCh:                  o.StopCh,
+			ResourceTimeout:         o.ResourceTimeout,
+			Parallelism:             o.Parallelism,
+			RuntimeParallelism:      o.RuntimeParallelism,
 		},
 	}
 
-	w := &dag.Walker{Callback: newDo.destroyWalkFun}
+	scheduleCtx, cancelSchedule := scheduleContext(ctx, o.StopCh)
+	defer cancelSchedule()
+	w := &dag.Walker{Callback: limitWalkFun(scheduleCtx, &newDo.Operation, func(v dag.Vertex) tfdiags.Diagnostics {
+		return newDo.destroyWalkFun(ctx, scheduleCtx, v)
+	})}
 	w.Update(destroyGraph)
 	// Wait
 	if diags := w.Wait(); diags.HasErrors() {
-		st = status.NewErrorStatus(diags.Err())
+		st = walkErrStatus(scheduleCtx, diags)
 		return st
 	}
+
+	// 3. mark the state empty, since all resources of the stack are destroyed
+	if !partial && (len(priorState.Resources) != 0 || len(priorState.Outputs) != 0 || len(priorState.OutputDefinitions) != 0) {
+		if err = newDo.UpdateState(map[string]*models.Resource{}); err != nil {
+			return status.NewErrorStatus(err)
+		}
+	}
 	return nil
 }
 
-func (do *DestroyOperation) destroyWalkFun(v dag.Vertex) (diags tfdiags.Diagnostics) {
+func (do *DestroyOperation) destroyWalkFun(ctx, scheduleCtx context.Context, v dag.Vertex) (diags tfdiags.Diagnostics) {
 	ao := &ApplyOperation{
 		Operation: do.Operation,
 	}
-	return ao.applyWalkFun(v)
+	return ao.applyWalkFun(ctx, scheduleCtx, v)
 }
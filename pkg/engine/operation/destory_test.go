@@ -4,6 +4,7 @@
 package operation
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
@@ -21,6 +22,7 @@ import (
 
 	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/status"
 )
 
@@ -39,13 +41,32 @@ func TestOperation_Destroy(t *testing.T) {
 		},
 		DependsOn: nil,
 	}
-	mf := &models.Spec{Resources: []models.Resource{resourceState}}
-	o := &DestroyOperation{
-		opsmodels.Operation{
-			OperationType: types.Destroy,
-			StateStorage:  &local.FileSystemState{Path: filepath.Join("test_data", local.KusionState)},
-			Runtime:       &runtime.KubernetesRuntime{},
+	resourceState2 := models.Resource{
+		ID: "id2",
+
+		Attributes: map[string]interface{}{
+			"foo": "baz",
 		},
+		DependsOn: nil,
+	}
+	newOperation := func(t *testing.T) *DestroyOperation {
+		// resources are destroyed by the latest state
+		storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+		assert.NoError(t, storage.Apply(&states.State{
+			Tenant:    tenant,
+			Stack:     stack,
+			Project:   project,
+			Serial:    1,
+			Resources: models.Resources{resourceState, resourceState2},
+			Outputs:   map[string]interface{}{"foo": "bar"},
+		}))
+		return &DestroyOperation{
+			opsmodels.Operation{
+				OperationType: types.Destroy,
+				StateStorage:  storage,
+				RuntimeMap:    map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
+			},
+		}
 	}
 	r := &DestroyRequest{
 		opsmodels.Request{
@@ -53,31 +74,72 @@ func TestOperation_Destroy(t *testing.T) {
 			Stack:    stack,
 			Project:  project,
 			Operator: operator,
-			Spec:     mf,
 		},
 	}
 
 	t.Run("destroy success", func(t *testing.T) {
 		defer monkey.UnpatchAll()
-		monkey.Patch((*graph.ResourceNode).Execute, func(rn *graph.ResourceNode, operation *opsmodels.Operation) status.Status {
+		var destroyed []string
+		monkey.Patch((*graph.ResourceNode).Execute, func(rn *graph.ResourceNode, ctx context.Context, operation *opsmodels.Operation) status.Status {
+			operation.Lock.Lock()
+			destroyed = append(destroyed, rn.ID)
+			operation.Lock.Unlock()
+			return nil
+		})
+		o := newOperation(t)
+		o.MsgCh = make(chan opsmodels.Message, 1)
+		go readMsgCh(o.MsgCh)
+		st := o.Destroy(context.Background(), r)
+		assert.Nil(t, st)
+		assert.ElementsMatch(t, []string{"id1", "id2"}, destroyed)
+
+		// the state is marked empty
+		state, err := o.StateStorage.GetLatestState(&states.StateQuery{Tenant: tenant, Stack: stack, Project: project})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(2), state.Serial)
+		assert.Empty(t, state.Resources)
+		assert.Empty(t, state.Outputs)
+	})
+
+	t.Run("destroy resources in the spec", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		var destroyed []string
+		monkey.Patch((*graph.ResourceNode).Execute, func(rn *graph.ResourceNode, ctx context.Context, operation *opsmodels.Operation) status.Status {
+			operation.Lock.Lock()
+			destroyed = append(destroyed, rn.ID)
+			operation.Lock.Unlock()
 			return nil
 		})
+		o := newOperation(t)
 		o.MsgCh = make(chan opsmodels.Message, 1)
 		go readMsgCh(o.MsgCh)
-		st := o.Destroy(r)
+		request := *r
+		request.Spec = &models.Spec{Resources: models.Resources{resourceState, {ID: "id3"}}}
+		st := o.Destroy(context.Background(), &request)
 		assert.Nil(t, st)
+		assert.Equal(t, []string{"id1"}, destroyed)
+
+		// the state is kept, since the stack is partially destroyed
+		state, err := o.StateStorage.GetLatestState(&states.StateQuery{Tenant: tenant, Stack: stack, Project: project})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), state.Serial)
 	})
 
 	t.Run("destroy failed", func(t *testing.T) {
 		defer monkey.UnpatchAll()
-		monkey.Patch((*graph.ResourceNode).Execute, func(rn *graph.ResourceNode, operation *opsmodels.Operation) status.Status {
+		monkey.Patch((*graph.ResourceNode).Execute, func(rn *graph.ResourceNode, ctx context.Context, operation *opsmodels.Operation) status.Status {
 			return status.NewErrorStatus(errors.New("mock error"))
 		})
 
+		o := newOperation(t)
 		o.MsgCh = make(chan opsmodels.Message, 1)
 		go readMsgCh(o.MsgCh)
-		st := o.Destroy(r)
+		st := o.Destroy(context.Background(), r)
 		assert.True(t, status.IsErr(st))
+
+		state, err := o.StateStorage.GetLatestState(&states.StateQuery{Tenant: tenant, Stack: stack, Project: project})
+		assert.NoError(t, err)
+		assert.Len(t, state.Resources, 2)
 	})
 }
 
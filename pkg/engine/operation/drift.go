@@ -0,0 +1,167 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+type DriftOperation struct {
+	opsmodels.Operation
+}
+
+type DriftRequest struct {
+	opsmodels.Request `json:",inline" yaml:",inline"`
+}
+
+type DriftResponse struct {
+	// Resources are drift results of resources in the Spec and the prior State, in the order of the Spec
+	Resources []*ResourceDrift `json:"resources"`
+}
+
+// ResourceDrift describes whether the live resource drifts from the plan and the last-applied State
+type ResourceDrift struct {
+	// ID is the key of the resource
+	ID string `json:"id"`
+	// Live means the resource exists in the actual infra
+	Live bool `json:"live"`
+	// PlanDrifted means the live resource differs from the resource compiled from the configuration,
+	// and it will be changed by the next apply
+	PlanDrifted bool `json:"planDrifted"`
+	// StateDrifted means the live resource differs from the last-applied State, i.e. it is modified out-of-band
+	StateDrifted bool `json:"stateDrifted"`
+}
+
+func (d *ResourceDrift) Drifted() bool {
+	return d.PlanDrifted || d.StateDrifted
+}
+
+// Drifted reports whether any resource is drifted
+func (r *DriftResponse) Drifted() bool {
+	for _, d := range r.Resources {
+		if d.Drifted() {
+			return true
+		}
+	}
+	return false
+}
+
+// Drift detects differences among live resources, the plan and the last-applied State without making any changes.
+// Resources in the Spec are compared with both the plan and the State, and resources only in the State are
+// regarded as plan drifted if they still exist, since they will be deleted by the next apply.
+// Reading resources is canceled when the ctx is done
+func (do *DriftOperation) Drift(ctx context.Context, request *DriftRequest) (rsp *DriftResponse, st status.Status) {
+	log.Infof("engine: Drift start!")
+	o := do.Operation
+
+	defer func() {
+		if e := recover(); e != nil {
+			log.Error("drift panic:%v", e)
+
+			switch x := e.(type) {
+			case string:
+				st = status.NewErrorStatus(fmt.Errorf("drift panic:%s", e))
+			case error:
+				st = status.NewErrorStatus(x)
+			default:
+				st = status.NewErrorStatusWithCode(status.Unknown, errors.New("unknown panic"))
+			}
+		}
+	}()
+
+	if st = validateRequest(&request.Request); status.IsErr(st) {
+		return nil, st
+	}
+
+	// 1. init & build Indexes
+	priorState, _ := o.InitStates(&request.Request)
+	priorStateResourceIndex := priorState.Resources.Index()
+	planResourceIndex := request.Spec.Resources.Index()
+	runtimeMap, err := runtime.InitRuntimes(o.RuntimeMap, request.Spec.Resources, priorState.Resources)
+	if err != nil {
+		return nil, status.NewErrorStatus(err)
+	}
+
+	// 2. compare resources in the Spec, and then resources only in the State
+	rsp = &DriftResponse{Resources: []*ResourceDrift{}}
+	for i := range request.Spec.Resources {
+		plan := resolveImplicitRefs(&request.Spec.Resources[i], priorStateResourceIndex)
+		d, s := detectDrift(ctx, runtimeMap, priorStateResourceIndex[plan.ResourceKey()], plan)
+		if status.IsErr(s) {
+			return nil, s
+		}
+		rsp.Resources = append(rsp.Resources, d)
+	}
+	for i := range priorState.Resources {
+		prior := &priorState.Resources[i]
+		if _, ok := planResourceIndex[prior.ResourceKey()]; ok {
+			continue
+		}
+		d, s := detectDrift(ctx, runtimeMap, prior, nil)
+		if status.IsErr(s) {
+			return nil, s
+		}
+		rsp.Resources = append(rsp.Resources, d)
+	}
+	return rsp, nil
+}
+
+func detectDrift(ctx context.Context, runtimeMap map[models.Type]runtime.Runtime, prior, plan *models.Resource,
+) (*ResourceDrift, status.Status) {
+	target := plan
+	if target == nil {
+		target = prior
+	}
+	response := runtimeMap[target.RuntimeType()].Read(ctx, &runtime.ReadRequest{Resource: target})
+	if status.IsErr(response.Status) {
+		return nil, response.Status
+	}
+	live := response.Resource
+
+	d := &ResourceDrift{ID: target.ResourceKey(), Live: live != nil}
+	comparator := runtime.GetComparator(target.RuntimeType())
+	if plan == nil {
+		d.PlanDrifted = live != nil
+	} else {
+		d.PlanDrifted = live == nil || !equal(comparator, prior, plan, live)
+	}
+	if prior == nil {
+		d.StateDrifted = live != nil
+	} else {
+		d.StateDrifted = live == nil || !equal(comparator, prior, prior, live)
+	}
+	return d, nil
+}
+
+// equal compares resources by the comparator, and resources that can't be compared are regarded as different
+func equal(comparator runtime.Comparator, prior, plan, live *models.Resource) bool {
+	eq, err := comparator.Equal(prior, plan, live)
+	if err != nil {
+		log.Warnf("compare resource:%s failed, regard it as drifted. %v", plan.ResourceKey(), err)
+		return false
+	}
+	return eq
+}
+
+// resolveImplicitRefs replaces implicit references of the plan resource with values in the last-applied State.
+// References can't be resolved are kept as they are
+func resolveImplicitRefs(plan *models.Resource, resourceIndex map[string]*models.Resource) *models.Resource {
+	_, v, s := graph.ParseImplicitRef(reflect.ValueOf(plan.Attributes), resourceIndex, graph.ImplicitReplaceFun)
+	if status.IsErr(s) {
+		log.Infof("can't resolve implicit references of resource:%s. %s", plan.ResourceKey(), s.String())
+		return plan
+	}
+	resolved := *plan
+	resolved.Attributes = v.Interface().(map[string]interface{})
+	return &resolved
+}
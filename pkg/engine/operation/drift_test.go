@@ -0,0 +1,63 @@
+package operation
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+)
+
+func TestDriftOperation_Drift(t *testing.T) {
+	newResource := func(id, image string) *models.Resource {
+		return &models.Resource{ID: id, Type: fakeType, Attributes: map[string]interface{}{"image": image}}
+	}
+
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	prior := states.NewState()
+	prior.Resources = models.Resources{
+		*newResource("synced", "nginx:1.20"),
+		*newResource("edited", "nginx:1.20"),
+		*newResource("config-changed", "nginx:1.20"),
+		*newResource("gone", "nginx:1.20"),
+		*newResource("removed-from-config", "nginx:1.20"),
+	}
+	assert.Nil(t, storage.Apply(prior))
+
+	do := &DriftOperation{Operation: opsmodels.Operation{
+		StateStorage: storage,
+		RuntimeMap: map[models.Type]runtime.Runtime{fakeType: &fakeRefreshRuntime{t: t, live: map[string]*models.Resource{
+			"synced":              newResource("synced", "nginx:1.20"),
+			"edited":              newResource("edited", "nginx:1.21"),
+			"config-changed":      newResource("config-changed", "nginx:1.20"),
+			"removed-from-config": newResource("removed-from-config", "nginx:1.20"),
+		}}},
+	}}
+	rsp, s := do.Drift(context.Background(), &DriftRequest{Request: opsmodels.Request{
+		Tenant:  "fake-tenant",
+		Stack:   "fake-stack",
+		Project: "fake-project",
+		Spec: &models.Spec{Resources: models.Resources{
+			*newResource("synced", "nginx:1.20"),
+			*newResource("edited", "nginx:1.20"),
+			*newResource("config-changed", "nginx:1.21"),
+			*newResource("gone", "nginx:1.20"),
+		}},
+	}})
+	assert.Nil(t, s)
+	assert.True(t, rsp.Drifted())
+	assert.Equal(t, []*ResourceDrift{
+		{ID: "synced", Live: true, PlanDrifted: false, StateDrifted: false},
+		{ID: "edited", Live: true, PlanDrifted: true, StateDrifted: true},
+		{ID: "config-changed", Live: true, PlanDrifted: true, StateDrifted: false},
+		{ID: "gone", Live: false, PlanDrifted: true, StateDrifted: true},
+		{ID: "removed-from-config", Live: true, PlanDrifted: true, StateDrifted: false},
+	}, rsp.Resources)
+}
@@ -1,10 +1,14 @@
 package graph
 
 import (
+	"context"
+
 	"kusionstack.io/kusion/pkg/engine/operation/models"
 	"kusionstack.io/kusion/pkg/status"
 )
 
 type ExecutableNode interface {
-	Execute(operation *models.Operation) status.Status
+	// Execute executes this node in the operation. Implementations should stop and return a status.Canceled status
+	// as soon as possible when the ctx is done
+	Execute(ctx context.Context, operation *models.Operation) status.Status
 }
@@ -0,0 +1,274 @@
+package graph
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// HookNode executes a hook of the stack or a resource in the apply DAG. Hooks are ordered with resources by edges
+// according to their phases, and a failed hook fails the whole operation. A hook is skipped if all its resources
+// are unchanged
+type HookNode struct {
+	*baseNode
+	hook *models.Hook
+	// resourceID is the ID of the resource this hook belongs to, and empty for hooks of the stack
+	resourceID string
+	// resources are nodes of resources the hook is executed with
+	resources []*ResourceNode
+}
+
+var _ ExecutableNode = (*HookNode)(nil)
+
+// HookIDPrefix is the prefix of IDs of hook nodes, which separates them from resource nodes
+const HookIDPrefix = "hook:"
+
+// NewHookNode creates a node of the hook. resourceID is the ID of the resource the hook belongs to,
+// and empty for hooks of the stack
+func NewHookNode(hook *models.Hook, resourceID string) (*HookNode, status.Status) {
+	id := fmt.Sprintf("%s%s:%s", HookIDPrefix, hook.Phase, hook.Name)
+	if resourceID != "" {
+		id = fmt.Sprintf("%s@%s", id, resourceID)
+	}
+	node, s := NewBaseNode(id)
+	if status.IsErr(s) {
+		return nil, s
+	}
+	return &HookNode{baseNode: node, hook: hook, resourceID: resourceID}, nil
+}
+
+func (h *HookNode) Hook() *models.Hook {
+	return h.hook
+}
+
+// SetResources sets nodes of resources the hook is executed with, whose actions decide whether the hook is skipped
+func (h *HookNode) SetResources(resources []*ResourceNode) {
+	h.resources = resources
+}
+
+// JobResource returns the Job of this hook as a resource of the Kubernetes Runtime, and nil if it is a command hook
+func (h *HookNode) JobResource() *models.Resource {
+	if len(h.hook.Job) == 0 {
+		return nil
+	}
+	return &models.Resource{ID: h.ID, Type: models.Kubernetes, Attributes: h.hook.Job}
+}
+
+func (h *HookNode) Execute(ctx context.Context, operation *opsmodels.Operation) status.Status {
+	log.Debugf("execute hook:%s", h.ID)
+	if err := ctx.Err(); err != nil {
+		return status.NewErrorStatusWithCode(status.Canceled, err)
+	}
+	// hooks make changes out of Kusion, so they are never executed in previews or dry runs
+	if operation.OperationType != types.Apply || operation.DryRun {
+		log.Infof("skip hook:%s in operation:%v, dry run:%v", h.ID, operation.OperationType, operation.DryRun)
+		return nil
+	}
+	if operation.ResourceTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, operation.ResourceTimeout)
+		defer cancel()
+	}
+	changed, s := h.resourcesChanged(ctx, operation)
+	if status.IsErr(s) {
+		return canceledStatus(ctx, s)
+	}
+	if !changed {
+		log.Infof("skip hook:%s since its resources are unchanged", h.ID)
+		return nil
+	}
+
+	if len(h.hook.Job) != 0 {
+		s = h.runJob(ctx, operation)
+	} else {
+		s = h.runCommand(ctx, operation)
+	}
+	return canceledStatus(ctx, s)
+}
+
+// resourcesChanged reports whether any resource of this hook is changed in the operation. Hooks without resources
+// are always executed. Actions of resources are computed when they are executed, which is before postApply hooks,
+// and preApply hooks compute them ahead. A resource of a preApply hook referring to others of the hook is computed
+// after all of them are found unchanged, so that references are resolved by their values in the prior State, which
+// are the same as those after they are executed
+func (h *HookNode) resourcesChanged(ctx context.Context, operation *opsmodels.Operation) (bool, status.Status) {
+	if len(h.resources) == 0 {
+		return true, nil
+	}
+	if h.hook.Phase == models.PostApply {
+		for _, rn := range h.resources {
+			if rn.Action != types.UnChange {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	operation.Lock.Lock()
+	resourceIndex := make(map[string]*models.Resource, len(operation.CtxResourceIndex))
+	for key, res := range operation.CtxResourceIndex {
+		resourceIndex[key] = res
+	}
+	operation.Lock.Unlock()
+
+	pending := map[string]*ResourceNode{}
+	for _, rn := range h.resources {
+		pending[rn.ID] = rn
+	}
+	for len(pending) != 0 {
+		progressed := false
+		for _, rn := range h.resources {
+			if pending[rn.ID] == nil || refersTo(rn.state, pending) {
+				continue
+			}
+			change, s := rn.planChange(ctx, operation, resourceIndex)
+			if status.IsErr(s) {
+				return false, s
+			}
+			if rn.Action != types.UnChange {
+				return true, nil
+			}
+			unchanged := change.prior
+			if unchanged == nil {
+				unchanged = change.plan
+			}
+			resourceIndex[rn.ID] = unchanged
+			delete(pending, rn.ID)
+			progressed = true
+		}
+		if !progressed {
+			// never happens unless resources refer to each other circularly, and the hook is executed to be safe
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// refersTo reports whether the resource has implicit references to any of the nodes
+func refersTo(res *models.Resource, nodes map[string]*ResourceNode) bool {
+	keys, _, s := ParseImplicitRef(reflect.ValueOf(res.Attributes), nil,
+		func(_ map[string]*models.Resource, refPath string) (reflect.Value, status.Status) {
+			return reflect.ValueOf(refPath), nil
+		})
+	if status.IsErr(s) {
+		// illegal references are reported when the resource is computed
+		return false
+	}
+	for _, key := range keys {
+		if nodes[key] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// runCommand runs the command by `sh -c` and streams its output line by line. The command runs in its own process
+// group, which is killed once the command exits or the ctx is done, since background processes left by the command
+// may hold its output open and block the hook forever
+func (h *HookNode) runCommand(ctx context.Context, operation *opsmodels.Operation) status.Status {
+	cmd := exec.Command("sh", "-c", h.hook.Command)
+	cmd.Dir = h.hook.WorkDir
+	cmd.Env = append(os.Environ(),
+		"KUSION_HOOK_NAME="+h.hook.Name,
+		"KUSION_HOOK_PHASE="+string(h.hook.Phase),
+		"KUSION_RESOURCE_ID="+h.resourceID,
+	)
+	setProcessGroup(cmd)
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		return status.NewErrorStatus(err)
+	}
+	cmd.Stderr = cmd.Stdout
+	if err = cmd.Start(); err != nil {
+		return status.NewErrorStatusWithMsg(status.Internal, fmt.Sprintf("hook:%s failed. %v", h.ID, err))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(output)
+		for scanner.Scan() {
+			h.report(operation, scanner.Text())
+		}
+		// drain the output the scanner gives up, e.g. a too long line, so that the command is never blocked
+		_, _ = io.Copy(io.Discard, output)
+	}()
+
+	// cmd.Wait closes the output before it is drained, so the process is waited by itself
+	exited := make(chan struct{})
+	var state *os.ProcessState
+	go func() {
+		defer close(exited)
+		state, err = cmd.Process.Wait()
+	}()
+	select {
+	case <-exited:
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+		<-exited
+	}
+	killProcessGroup(cmd)
+	<-done
+	_ = output.Close()
+
+	if err == nil && !state.Success() {
+		err = &exec.ExitError{ProcessState: state}
+	}
+	if err != nil {
+		return status.NewErrorStatusWithMsg(status.Internal, fmt.Sprintf("hook:%s failed. %v", h.ID, err))
+	}
+	return nil
+}
+
+// runJob runs the Job by the Runtime and waits for it to complete. The Job left by the last execution is deleted
+// before creating it, since the template of a Job is immutable. The Job is deleted after it completes, and failed
+// ones are kept for debugging
+func (h *HookNode) runJob(ctx context.Context, operation *opsmodels.Operation) status.Status {
+	job := h.JobResource()
+	rt, s := operation.GetRuntime(job)
+	if status.IsErr(s) {
+		return s
+	}
+
+	if s = h.deleteJob(ctx, operation, rt, job); status.IsErr(s) {
+		return s
+	}
+	response := rt.Apply(ctx, &runtime.ApplyRequest{PlanResource: job})
+	if status.IsErr(response.Status) {
+		return response.Status
+	}
+	s = h.waitForReady(ctx, operation, rt, job, func(event runtime.WatchEvent) {
+		h.report(operation, event.Message)
+	})
+	if status.IsErr(s) {
+		return status.NewErrorStatusWithMsg(s.Code(), fmt.Sprintf("hook:%s failed. %s", h.ID, s.Message()))
+	}
+	return h.deleteJob(ctx, operation, rt, job)
+}
+
+func (h *HookNode) deleteJob(ctx context.Context, operation *opsmodels.Operation, rt runtime.Runtime, job *models.Resource) status.Status {
+	response := rt.Delete(ctx, &runtime.DeleteRequest{Resource: job})
+	if status.IsErr(response.Status) {
+		return response.Status
+	}
+	return h.waitForDeletion(ctx, operation, rt, job)
+}
+
+// report streams a line of logs of this hook into the progress of the operation
+func (h *HookNode) report(operation *opsmodels.Operation, line string) {
+	log.Infof("hook:%s | %s", h.ID, line)
+	if operation.MsgCh != nil {
+		operation.MsgCh <- opsmodels.Message{ResourceID: h.ID, Log: line}
+	}
+}
@@ -0,0 +1,80 @@
+//go:build !windows
+// +build !windows
+
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func TestHookNode_ExecuteCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		timeout time.Duration
+		code    status.Code
+		logs    []string
+	}{
+		{
+			name:    "stream output",
+			command: "echo hello; echo world >&2",
+			logs:    []string{"hello", "world"},
+		},
+		{
+			name:    "command failed",
+			command: "echo failed; exit 1",
+			code:    status.Internal,
+			logs:    []string{"failed"},
+		},
+		{
+			name:    "background process holding the output",
+			command: "sleep 30 & echo started",
+			logs:    []string{"started"},
+		},
+		{
+			name:    "timeout",
+			command: "echo started; sleep 30",
+			timeout: 100 * time.Millisecond,
+			code:    status.Canceled,
+			logs:    []string{"started"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, s := NewHookNode(&models.Hook{Name: "test", Phase: models.PostApply, Command: tt.command}, "")
+			assert.Nil(t, s)
+			msgCh := make(chan opsmodels.Message, 10)
+			operation := &opsmodels.Operation{
+				OperationType:   types.Apply,
+				MsgCh:           msgCh,
+				ResourceTimeout: tt.timeout,
+			}
+
+			start := time.Now()
+			s = node.Execute(context.Background(), operation)
+			assert.True(t, time.Since(start) < 10*time.Second)
+			if tt.code == "" {
+				assert.Nil(t, s)
+			} else {
+				assert.Equal(t, tt.code, s.Code())
+			}
+
+			close(msgCh)
+			var logs []string
+			for msg := range msgCh {
+				assert.Equal(t, node.ID, msg.ResourceID)
+				logs = append(logs, msg.Log)
+			}
+			assert.Equal(t, tt.logs, logs)
+		})
+	}
+}
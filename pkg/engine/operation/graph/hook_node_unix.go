@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package graph
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup runs the command in a new process group, whose ID is the PID of the command
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the command and all processes it started. Processes that have exited are ignored
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package graph
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing on Windows, where processes started by the command are not tracked
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command only, since Windows has no process groups
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
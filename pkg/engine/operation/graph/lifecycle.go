@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// enforceLifecycle enforces lifecycle settings on the computed action. Resources with preventDestroy can't be deleted
+// or replaced, and resources retained by their deletion policy are removed from the State without deleting them
+func (rn *ResourceNode) enforceLifecycle(lifecycle *models.Lifecycle) status.Status {
+	if rn.Action == types.Delete && lifecycle.DeletionPolicy == models.DeletionPolicyRetain {
+		rn.Action = types.Retain
+		return nil
+	}
+	if (rn.Action == types.Delete || rn.Action.IsReplace()) && lifecycle.PreventDestroy {
+		msg := fmt.Sprintf("%s resource:%s is prevented by %s. Unset it before deleting this resource",
+			strings.ToLower(rn.Action.Ing()), rn.ID, models.PreventDestroyExtension)
+		return status.NewErrorStatusWithMsg(status.PermissionDenied, msg)
+	}
+	return nil
+}
+
+// ignoreChanges returns copies of the prior and planed resources, in which values of ignored attribute paths are
+// replaced with values of the live resource, so that changes of these paths are neither reported nor applied.
+// Paths not found in the live resource are removed
+func ignoreChanges(paths []string, prior, plan, live *models.Resource) (*models.Resource, *models.Resource) {
+	return ignorePaths(paths, prior, live), ignorePaths(paths, plan, live)
+}
+
+func ignorePaths(paths []string, resource, live *models.Resource) *models.Resource {
+	if resource == nil {
+		return nil
+	}
+	copied := *resource
+	copied.Attributes = deepCopyValue(resource.Attributes).(map[string]interface{})
+	for _, path := range paths {
+		fields := strings.Split(path, ".")
+		if v, ok := nestedField(live.Attributes, fields); ok {
+			setNestedField(copied.Attributes, deepCopyValue(v), fields)
+		} else {
+			removeNestedField(copied.Attributes, fields)
+		}
+	}
+	return &copied
+}
+
+func nestedField(m map[string]interface{}, fields []string) (interface{}, bool) {
+	var v interface{} = m
+	for _, field := range fields {
+		current, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = current[field]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func setNestedField(m map[string]interface{}, value interface{}, fields []string) {
+	for _, field := range fields[:len(fields)-1] {
+		next, ok := m[field].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[field] = next
+		}
+		m = next
+	}
+	m[fields[len(fields)-1]] = value
+}
+
+func removeNestedField(m map[string]interface{}, fields []string) {
+	for _, field := range fields[:len(fields)-1] {
+		next, ok := m[field].(map[string]interface{})
+		if !ok {
+			return
+		}
+		m = next
+	}
+	delete(m, fields[len(fields)-1])
+}
+
+// deepCopyValue copies maps and slices in the value, so that the copy can be modified without changing the original one
+func deepCopyValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if x == nil {
+			return x
+		}
+		copied := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			copied[k] = deepCopyValue(e)
+		}
+		return copied
+	case []interface{}:
+		if x == nil {
+			return x
+		}
+		copied := make([]interface{}, len(x))
+		for i, e := range x {
+			copied[i] = deepCopyValue(e)
+		}
+		return copied
+	default:
+		return v
+	}
+}
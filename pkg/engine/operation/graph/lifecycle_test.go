@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func TestResourceNode_enforceLifecycle(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    types.ActionType
+		lifecycle *models.Lifecycle
+		want      types.ActionType
+		wantCode  status.Code
+	}{
+		{
+			name:      "delete",
+			action:    types.Delete,
+			lifecycle: &models.Lifecycle{DeletionPolicy: models.DeletionPolicyDelete},
+			want:      types.Delete,
+		},
+		{
+			name:      "retain",
+			action:    types.Delete,
+			lifecycle: &models.Lifecycle{DeletionPolicy: models.DeletionPolicyRetain},
+			want:      types.Retain,
+		},
+		{
+			name:      "retain-with-prevent-destroy",
+			action:    types.Delete,
+			lifecycle: &models.Lifecycle{PreventDestroy: true, DeletionPolicy: models.DeletionPolicyRetain},
+			want:      types.Retain,
+		},
+		{
+			name:      "prevent-delete",
+			action:    types.Delete,
+			lifecycle: &models.Lifecycle{PreventDestroy: true, DeletionPolicy: models.DeletionPolicyDelete},
+			wantCode:  status.PermissionDenied,
+		},
+		{
+			name:      "prevent-replace",
+			action:    types.Replace,
+			lifecycle: &models.Lifecycle{PreventDestroy: true, DeletionPolicy: models.DeletionPolicyDelete},
+			wantCode:  status.PermissionDenied,
+		},
+		{
+			name:      "update-with-prevent-destroy",
+			action:    types.Update,
+			lifecycle: &models.Lifecycle{PreventDestroy: true, DeletionPolicy: models.DeletionPolicyDelete},
+			want:      types.Update,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rn := &ResourceNode{baseNode: &baseNode{ID: "foo"}, Action: tt.action}
+			s := rn.enforceLifecycle(tt.lifecycle)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, s.Code())
+				return
+			}
+			assert.Nil(t, s)
+			assert.Equal(t, tt.want, rn.Action)
+		})
+	}
+}
+
+func Test_ignoreChanges(t *testing.T) {
+	prior := &models.Resource{ID: "foo", Attributes: map[string]interface{}{
+		"spec":     map[string]interface{}{"replicas": 1, "image": "foo:v1"},
+		"metadata": map[string]interface{}{"annotations": map[string]interface{}{"a": "b"}},
+	}}
+	plan := &models.Resource{ID: "foo", Attributes: map[string]interface{}{
+		"spec":     map[string]interface{}{"replicas": 2, "image": "foo:v2"},
+		"metadata": map[string]interface{}{"annotations": map[string]interface{}{"a": "c"}},
+	}}
+	live := &models.Resource{ID: "foo", Attributes: map[string]interface{}{
+		"spec":     map[string]interface{}{"replicas": 5, "image": "foo:v1"},
+		"metadata": map[string]interface{}{},
+	}}
+
+	gotPrior, gotPlan := ignoreChanges([]string{"spec.replicas", "metadata.annotations", "status.phase"}, prior, plan, live)
+	assert.Equal(t, map[string]interface{}{
+		"spec":     map[string]interface{}{"replicas": 5, "image": "foo:v1"},
+		"metadata": map[string]interface{}{},
+	}, gotPrior.Attributes)
+	assert.Equal(t, map[string]interface{}{
+		"spec":     map[string]interface{}{"replicas": 5, "image": "foo:v2"},
+		"metadata": map[string]interface{}{},
+	}, gotPlan.Attributes)
+
+	// the original resources are not changed
+	assert.Equal(t, 2, plan.Attributes["spec"].(map[string]interface{})["replicas"])
+	assert.Equal(t, "b", prior.Attributes["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})["a"])
+
+	gotPrior, _ = ignoreChanges([]string{"spec.replicas"}, nil, plan, live)
+	assert.Nil(t, gotPrior)
+}
@@ -1,6 +1,16 @@
 package graph
 
-import "kusionstack.io/kusion/pkg/status"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
 
 type baseNode struct {
 	ID string
@@ -21,6 +31,108 @@ func (b *baseNode) Name() string {
 	return b.ID
 }
 
+// waitForDeletion polls the resource by the Runtime until it is gone. Deletion may be asynchronous in the actual infra,
+// e.g. kubernetes objects with finalizers, and a replacement with the same identity can't be created before that
+func (b *baseNode) waitForDeletion(parent context.Context, operation *opsmodels.Operation, rt runtime.Runtime, resource *models.Resource) status.Status {
+	timeout := operation.WatchTimeout
+	if timeout <= 0 {
+		timeout = DefaultWatchTimeout
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(deletionPollInterval)
+	defer ticker.Stop()
+	for {
+		response := rt.Read(ctx, &runtime.ReadRequest{Resource: resource})
+		if status.IsErr(response.Status) && ctx.Err() == nil {
+			return response.Status
+		}
+		if !status.IsErr(response.Status) && response.Resource == nil {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+			continue
+		case <-ctx.Done():
+		}
+		if err := parent.Err(); err != nil {
+			msg := fmt.Sprintf("stop waiting for resource:%s to be deleted. %v", b.ID, err)
+			return status.NewErrorStatusWithMsg(status.Canceled, msg)
+		}
+		msg := fmt.Sprintf("timeout waiting for resource:%s to be deleted after %v", b.ID, timeout)
+		return status.NewErrorStatusWithMsg(status.DeadlineExceeded, msg)
+	}
+}
+
+// waitForReady watches the resource by the Runtime and blocks until the resource is ready, the watch timeout expires,
+// the ctx is done or the operation is stopped. onEvent is invoked with every event if it is not nil
+func (b *baseNode) waitForReady(parent context.Context, operation *opsmodels.Operation, rt runtime.Runtime,
+	resource *models.Resource, onEvent func(event runtime.WatchEvent),
+) status.Status {
+	if resource == nil {
+		return nil
+	}
+
+	timeout := operation.WatchTimeout
+	if timeout <= 0 {
+		timeout = DefaultWatchTimeout
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	response := rt.Watch(ctx, &runtime.WatchRequest{Resource: resource})
+	// Watch is optional for a Runtime, regard this resource as ready if it is not supported
+	if response == nil {
+		return nil
+	}
+	if status.IsErr(response.Status) {
+		return response.Status
+	}
+	if response.ResultChan == nil {
+		return nil
+	}
+
+	lastMsg := ""
+	for {
+		select {
+		case event, ok := <-response.ResultChan:
+			if !ok {
+				if ctx.Err() != nil {
+					break
+				}
+				msg := fmt.Sprintf("stop watching resource:%s before it is ready. %s", b.ID, lastMsg)
+				return status.NewErrorStatusWithMsg(status.Unavailable, msg)
+			}
+			log.Infof("watch resource:%s, ready:%v, msg:%s", b.ID, event.Ready, event.Message)
+			if onEvent != nil {
+				onEvent(event)
+			}
+			lastMsg = event.Message
+			if event.Ready {
+				return nil
+			}
+			if event.Failed {
+				msg := fmt.Sprintf("resource:%s failed before it is ready. %s", b.ID, lastMsg)
+				return status.NewErrorStatusWithMsg(status.Internal, msg)
+			}
+			continue
+		case <-operation.StopCh:
+			// the resource is already applied and saved, so it is safe to stop waiting
+			msg := fmt.Sprintf("stop waiting for resource:%s to be ready since the operation is stopped", b.ID)
+			return status.NewErrorStatusWithMsg(status.Canceled, msg)
+		case <-ctx.Done():
+		}
+		if err := parent.Err(); err != nil {
+			msg := fmt.Sprintf("stop waiting for resource:%s to be ready. %v", b.ID, err)
+			return status.NewErrorStatusWithMsg(status.Canceled, msg)
+		}
+		msg := fmt.Sprintf("timeout waiting for resource:%s to be ready after %v. %s", b.ID, timeout, lastMsg)
+		return status.NewErrorStatusWithMsg(status.DeadlineExceeded, msg)
+	}
+}
+
 type RootNode struct{}
 
 func (r *RootNode) Hashcode() interface{} {
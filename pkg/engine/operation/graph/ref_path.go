@@ -0,0 +1,252 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// KnownAfterApply replaces implicit references in previews whose values don't exist until resources are applied
+const KnownAfterApply = "(known after apply)"
+
+// RefPath is a parsed implicit reference without the ImplicitRefPrefix. Its grammar is:
+//
+//	ref     := key { segment } [ "|" default ]
+//	key     := bare | quoted
+//	segment := ( "." ( bare | quoted ) | "[" ( index | quoted ) "]" ) [ "?" ]
+//
+// A bare name can't contain ".", "[", "]", "?", "|" or quotes, and a quoted name is a Go string literal which may
+// contain these characters, e.g. `v1:Service:default:nginx.spec.ports[0].nodePort` or
+// `v1:Pod:default:nginx.metadata.annotations["app.kubernetes.io/name"]`.
+// A segment followed by "?" is optional, and the default value, or nil if absent, is used if it is missing.
+// A default value is a YAML or JSON literal, and makes all segments optional
+type RefPath struct {
+	// ResourceKey is the key of the referenced resource
+	ResourceKey string
+
+	// Segments are keys and indexes to get the value in attributes of the resource
+	Segments []RefSegment
+
+	// Default is used when the referenced value is missing if HasDefault is true
+	Default    interface{}
+	HasDefault bool
+
+	raw string
+}
+
+// RefSegment is a map key or a list index in a RefPath
+type RefSegment struct {
+	Key      string
+	Index    int
+	IsIndex  bool
+	Optional bool
+}
+
+func (s RefSegment) String() string {
+	if s.IsIndex {
+		return fmt.Sprintf("[%d]", s.Index)
+	}
+	return strconv.Quote(s.Key)
+}
+
+// ParseRefPath parses an implicit reference without the ImplicitRefPrefix
+func ParseRefPath(ref string) (*RefPath, error) {
+	illegal := func(reason string) error {
+		return fmt.Errorf("illegal implicit ref:%s, %s. Implicit ref format: %sresourceKey.attribute", ref, reason, ImplicitRefPrefix)
+	}
+
+	path := &RefPath{raw: ref}
+	body := ref
+	if i := indexOutsideQuotes(ref, '|'); i >= 0 {
+		body = strings.TrimRight(ref[:i], " ")
+		path.HasDefault = true
+		if d := strings.TrimSpace(ref[i+1:]); d != "" {
+			if err := yaml.Unmarshal([]byte(d), &path.Default); err != nil {
+				// not a literal, so regard it as a plain string
+				path.Default = d
+			}
+		} else {
+			path.Default = ""
+		}
+	}
+
+	key, i, err := readName(body, 0)
+	if err != nil {
+		return nil, illegal(err.Error())
+	}
+	if key == "" {
+		return nil, illegal("empty resource key")
+	}
+	path.ResourceKey = key
+
+	for i < len(body) {
+		var seg RefSegment
+		switch body[i] {
+		case '.':
+			seg.Key, i, err = readName(body, i+1)
+			if err != nil {
+				return nil, illegal(err.Error())
+			}
+		case '[':
+			i++
+			if i < len(body) && body[i] == '"' {
+				seg.Key, i, err = readQuoted(body, i)
+				if err != nil {
+					return nil, illegal(err.Error())
+				}
+			} else {
+				end := strings.IndexByte(body[i:], ']')
+				if end < 0 {
+					return nil, illegal("unclosed [")
+				}
+				seg.Index, err = strconv.Atoi(body[i : i+end])
+				if err != nil || seg.Index < 0 {
+					return nil, illegal(fmt.Sprintf("illegal index %q", body[i:i+end]))
+				}
+				seg.IsIndex = true
+				i += end
+			}
+			if i >= len(body) || body[i] != ']' {
+				return nil, illegal("unclosed [")
+			}
+			i++
+		default:
+			return nil, illegal(fmt.Sprintf("unexpected %q at %d", body[i], i))
+		}
+		if i < len(body) && body[i] == '?' {
+			seg.Optional = true
+			i++
+		}
+		path.Segments = append(path.Segments, seg)
+	}
+	return path, nil
+}
+
+// readName reads a bare or quoted name from the start, and returns the name and the position after it
+func readName(s string, start int) (string, int, error) {
+	if start < len(s) && s[start] == '"' {
+		return readQuoted(s, start)
+	}
+	i := start
+	for i < len(s) && !strings.ContainsRune(`.[]?|"`, rune(s[i])) {
+		i++
+	}
+	if i == start {
+		return "", i, fmt.Errorf("empty name at %d", start)
+	}
+	return s[start:i], i, nil
+}
+
+// readQuoted reads a Go string literal from the start, and returns the unquoted string and the position after it
+func readQuoted(s string, start int) (string, int, error) {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			unquoted, err := strconv.Unquote(s[start : i+1])
+			if err != nil {
+				return "", i, fmt.Errorf("illegal quoted name %s", s[start:i+1])
+			}
+			return unquoted, i + 1, nil
+		}
+	}
+	return "", len(s), fmt.Errorf("unclosed quote at %d", start)
+}
+
+// indexOutsideQuotes returns the index of the first c which is not quoted, or -1 if there is none
+func indexOutsideQuotes(s string, c byte) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == c:
+			return i
+		}
+	}
+	return -1
+}
+
+// Resolve returns the referenced value in the resourceIndex. If it is missing, the default value is returned if the
+// missing segment is optional or a default value is given, otherwise missing is called to get the value
+func (p *RefPath) Resolve(resourceIndex map[string]*models.Resource,
+	missing func(reason string) (interface{}, status.Status),
+) (interface{}, status.Status) {
+	res := resourceIndex[p.ResourceKey]
+	if res == nil {
+		if p.HasDefault {
+			return p.Default, nil
+		}
+		return missing(fmt.Sprintf("can't find state by key:%s when replacing %s", p.ResourceKey, p.raw))
+	}
+
+	var value interface{} = res.Attributes
+	for _, seg := range p.Segments {
+		var next interface{}
+		var ok bool
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if seg.IsIndex {
+				return nil, p.typeError(seg, value)
+			}
+			next, ok = v[seg.Key]
+		case []interface{}:
+			if !seg.IsIndex {
+				return nil, p.typeError(seg, value)
+			}
+			if seg.Index < len(v) {
+				next, ok = v[seg.Index], true
+			}
+		default:
+			return nil, p.typeError(seg, value)
+		}
+
+		if !ok || next == nil {
+			if seg.Optional || p.HasDefault {
+				return p.Default, nil
+			}
+			return missing(fmt.Sprintf("can't find specified value in resource:%s by ref:%s", p.ResourceKey, p.raw))
+		}
+		value = next
+	}
+	return value, nil
+}
+
+func (p *RefPath) typeError(seg RefSegment, value interface{}) status.Status {
+	return status.NewErrorStatusWithMsg(status.IllegalManifest,
+		fmt.Sprintf("can't get %s of %T in resource:%s by ref:%s", seg, value, p.ResourceKey, p.raw))
+}
+
+// refValue returns the reflect.Value of a replaced implicit reference. A nil value is kept as a nil interface,
+// so that it is not dropped from maps or slices
+func refValue(v interface{}) reflect.Value {
+	if v == nil {
+		return reflect.ValueOf(&v).Elem()
+	}
+	return reflect.ValueOf(v)
+}
+
+// PreviewReplaceFun replaces implicit references in previews. Values missing in the resourceIndex, e.g. attributes
+// of resources not created yet, are replaced with KnownAfterApply instead of failing the preview
+var PreviewReplaceFun = func(resourceIndex map[string]*models.Resource, refPath string) (reflect.Value, status.Status) {
+	path, err := ParseRefPath(refPath)
+	if err != nil {
+		return reflect.Value{}, status.NewErrorStatusWithMsg(status.InvalidArgument, err.Error())
+	}
+	v, s := path.Resolve(resourceIndex, func(string) (interface{}, status.Status) {
+		return KnownAfterApply, nil
+	})
+	if status.IsErr(s) {
+		return reflect.Value{}, s
+	}
+	return refValue(v), nil
+}
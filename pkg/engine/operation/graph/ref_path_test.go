@@ -0,0 +1,194 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func TestParseRefPath(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    *RefPath
+		wantErr bool
+	}{
+		{
+			ref:  "jack",
+			want: &RefPath{ResourceKey: "jack"},
+		},
+		{
+			ref: "v1:Service:default:nginx.spec.ports[0].nodePort",
+			want: &RefPath{ResourceKey: "v1:Service:default:nginx", Segments: []RefSegment{
+				{Key: "spec"}, {Key: "ports"}, {Index: 0, IsIndex: true}, {Key: "nodePort"},
+			}},
+		},
+		{
+			ref: `"v1:ConfigMap:default:a.b".metadata.annotations["app.kubernetes.io/name"]`,
+			want: &RefPath{ResourceKey: "v1:ConfigMap:default:a.b", Segments: []RefSegment{
+				{Key: "metadata"}, {Key: "annotations"}, {Key: "app.kubernetes.io/name"},
+			}},
+		},
+		{
+			ref: `jack.metadata."a.b|c\"d"`,
+			want: &RefPath{ResourceKey: "jack", Segments: []RefSegment{
+				{Key: "metadata"}, {Key: `a.b|c"d`},
+			}},
+		},
+		{
+			ref: "jack.status.loadBalancer?.ingress[0]?.ip",
+			want: &RefPath{ResourceKey: "jack", Segments: []RefSegment{
+				{Key: "status"}, {Key: "loadBalancer", Optional: true}, {Key: "ingress"}, {Index: 0, IsIndex: true, Optional: true}, {Key: "ip"},
+			}},
+		},
+		{
+			ref: "jack.spec.replicas | 3",
+			want: &RefPath{ResourceKey: "jack", Segments: []RefSegment{
+				{Key: "spec"}, {Key: "replicas"},
+			}, Default: 3, HasDefault: true},
+		},
+		{
+			ref: "jack.spec.ports|[80, 443]",
+			want: &RefPath{ResourceKey: "jack", Segments: []RefSegment{
+				{Key: "spec"}, {Key: "ports"},
+			}, Default: []interface{}{80, 443}, HasDefault: true},
+		},
+		{
+			ref: "jack.spec.clusterIP|",
+			want: &RefPath{ResourceKey: "jack", Segments: []RefSegment{
+				{Key: "spec"}, {Key: "clusterIP"},
+			}, Default: "", HasDefault: true},
+		},
+		{ref: "", wantErr: true},
+		{ref: "jack.", wantErr: true},
+		{ref: "jack..a", wantErr: true},
+		{ref: "jack?.a", wantErr: true},
+		{ref: "jack.a[", wantErr: true},
+		{ref: "jack.a[x]", wantErr: true},
+		{ref: "jack.a[-1]", wantErr: true},
+		{ref: `jack.a["b]`, wantErr: true},
+		{ref: `jack.a["b"`, wantErr: true},
+		{ref: `jack.a"b"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseRefPath(tt.ref)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			tt.want.raw = tt.ref
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestImplicitReplaceFun(t *testing.T) {
+	resourceIndex := map[string]*models.Resource{
+		"v1:Service:default:nginx": {
+			ID: "v1:Service:default:nginx",
+			Attributes: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"app.kubernetes.io/name": "nginx"},
+				},
+				"spec": map[string]interface{}{
+					"clusterIP": "10.0.0.1",
+					"ports":     []interface{}{map[string]interface{}{"port": 80, "nodePort": 30080}},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name        string
+		ref         string
+		want        interface{}
+		wantPreview interface{}
+		wantCode    status.Code
+	}{
+		{
+			name:        "list index",
+			ref:         "v1:Service:default:nginx.spec.ports[0].nodePort",
+			want:        30080,
+			wantPreview: 30080,
+		},
+		{
+			name:        "quoted key",
+			ref:         `v1:Service:default:nginx.metadata.annotations["app.kubernetes.io/name"]`,
+			want:        "nginx",
+			wantPreview: "nginx",
+		},
+		{
+			name:        "optional segment",
+			ref:         "v1:Service:default:nginx.spec.loadBalancer?.ingress",
+			want:        nil,
+			wantPreview: nil,
+		},
+		{
+			name:        "optional index",
+			ref:         "v1:Service:default:nginx.spec.ports[1]?.nodePort",
+			want:        nil,
+			wantPreview: nil,
+		},
+		{
+			name:        "default value",
+			ref:         "v1:Service:default:nginx.spec.loadBalancerIP|0.0.0.0",
+			want:        "0.0.0.0",
+			wantPreview: "0.0.0.0",
+		},
+		{
+			name:        "default value of missing resource",
+			ref:         "v1:Service:default:redis.spec.clusterIP|None",
+			want:        "None",
+			wantPreview: "None",
+		},
+		{
+			name:        "missing value",
+			ref:         "v1:Service:default:nginx.spec.ports[1].nodePort",
+			wantPreview: KnownAfterApply,
+			wantCode:    status.IllegalManifest,
+		},
+		{
+			name:        "missing resource",
+			ref:         "v1:Service:default:redis.spec.clusterIP",
+			wantPreview: KnownAfterApply,
+			wantCode:    status.IllegalManifest,
+		},
+		{
+			name:     "index of a map",
+			ref:      "v1:Service:default:nginx.spec[0]",
+			wantCode: status.IllegalManifest,
+		},
+		{
+			name:     "key of a string",
+			ref:      "v1:Service:default:nginx.spec.clusterIP.ip",
+			wantCode: status.IllegalManifest,
+		},
+		{
+			name:     "illegal ref",
+			ref:      "v1:Service:default:nginx.spec.ports[",
+			wantCode: status.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, s := ImplicitReplaceFun(resourceIndex, tt.ref)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, s.Code())
+			} else {
+				assert.Nil(t, s)
+				assert.Equal(t, tt.want, got.Interface())
+			}
+
+			got, s = PreviewReplaceFun(resourceIndex, tt.ref)
+			if tt.wantCode != "" && tt.wantPreview == nil {
+				assert.Equal(t, tt.wantCode, s.Code())
+				return
+			}
+			assert.Nil(t, s)
+			assert.Equal(t, tt.wantPreview, got.Interface())
+		})
+	}
+}
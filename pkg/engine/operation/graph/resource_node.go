@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"kusionstack.io/kusion/pkg/engine/runtime"
 
-	"kusionstack.io/kusion/pkg/util"
-
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
 
 	"kusionstack.io/kusion/pkg/engine/operation/types"
@@ -25,39 +25,136 @@ type ResourceNode struct {
 	*baseNode
 	Action types.ActionType
 	state  *models.Resource
+
+	// planLock guards the change computed by planChange
+	planLock sync.Mutex
+	change   *resourceChange
 }
 
 var _ ExecutableNode = (*ResourceNode)(nil)
 
 const (
 	ImplicitRefPrefix = "$kusion_path."
+
+	// DefaultWatchTimeout is the max duration to wait for an applied resource to be ready
+	DefaultWatchTimeout = 10 * time.Minute
 )
 
-func (rn *ResourceNode) Execute(operation *opsmodels.Operation) status.Status {
+// deletionPollInterval is the interval to check whether a deleted resource is gone
+var deletionPollInterval = time.Second
+
+func (rn *ResourceNode) Execute(ctx context.Context, operation *opsmodels.Operation) status.Status {
 	log.Debugf("execute node:%s", rn.ID)
-	if operation.OperationType == types.Apply {
-		// replace implicit references
-		value := reflect.ValueOf(rn.state.Attributes)
-		_, implicitValue, s := ParseImplicitRef(value, operation.CtxResourceIndex, ImplicitReplaceFun)
-		if status.IsErr(s) {
-			return s
+	// don't start this node if the operation is already canceled
+	if err := ctx.Err(); err != nil {
+		return status.NewErrorStatusWithCode(status.Canceled, err)
+	}
+	if operation.ResourceTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, operation.ResourceTimeout)
+		defer cancel()
+	}
+
+	// 1. compute ActionType of current resource node, which may be computed by its preApply hooks already
+	change, s := rn.planChange(ctx, operation, operation.CtxResourceIndex)
+	if status.IsErr(s) {
+		return s
+	}
+	priorState, planedState, liveState := change.prior, change.plan, change.live
+	key := rn.state.ResourceKey()
+
+	// 2. apply or return
+	switch operation.OperationType {
+	case types.ApplyPreview, types.DestroyPreview:
+		fillResponseChangeSteps(operation, rn, priorState, planedState, liveState)
+	case types.Apply, types.Destroy:
+		switch rn.Action {
+		case types.Create, types.Delete, types.Update, types.Replace, types.ReplaceCreateBeforeDelete, types.Retain:
+			s := rn.applyResource(ctx, operation, priorState, planedState)
+			if status.IsErr(s) {
+				return canceledStatus(ctx, s)
+			}
+		case types.UnChange:
+			log.Infof("PriorAttributes and PlanAttributes are equal.")
+			// unchanged resources are not applied, but resources referring to them still resolve references by
+			// the CtxResourceIndex
+			unchanged := priorState
+			if unchanged == nil {
+				unchanged = planedState
+			}
+			if e := operation.RefreshResourceIndex(key, unchanged, types.UnChange); e != nil {
+				return status.NewErrorStatus(e)
+			}
+		default:
+			return status.NewErrorStatus(fmt.Errorf("unknown action:%s", rn.Action.PrettyString()))
 		}
-		rn.state.Attributes = implicitValue.Interface().(map[string]interface{})
+	default:
+		return status.NewErrorStatus(fmt.Errorf("unknown operation: %v", operation.OperationType))
+	}
+
+	return nil
+}
+
+// resourceChange is the change of a resource computed in an operation
+type resourceChange struct {
+	prior, plan, live *models.Resource
+}
+
+// planChange computes the Action of this node, and returns the prior, planed and live resources of the change. The
+// change is computed only once in an operation, since preApply hooks of this node compute it before this node is
+// executed to decide whether they are skipped. Implicit references are resolved by the resourceIndex in applies
+func (rn *ResourceNode) planChange(ctx context.Context, operation *opsmodels.Operation,
+	resourceIndex map[string]*models.Resource,
+) (*resourceChange, status.Status) {
+	rn.planLock.Lock()
+	defer rn.planLock.Unlock()
+	if rn.change != nil {
+		return rn.change, nil
 	}
 
 	// 1. prepare planedState
 	planedState := rn.state
+	switch operation.OperationType {
+	case types.Apply:
+		attributes, s := replaceAllRefs(operation, rn.state.Attributes, resourceIndex, ImplicitReplaceFun)
+		if status.IsErr(s) {
+			return nil, s
+		}
+		rn.state.Attributes = attributes
+	case types.ApplyPreview:
+		// nothing is applied in previews, so references are resolved by the prior state, and values missing there
+		// are shown as known after apply. The Spec is kept unchanged, since it may be applied after the preview
+		attributes, s := replaceAllRefs(operation, rn.state.Attributes, operation.PriorStateResourceIndex, PreviewReplaceFun)
+		if status.IsErr(s) {
+			return nil, s
+		}
+		copied := *rn.state
+		copied.Attributes = attributes
+		planedState = &copied
+	}
+	lifecycle, err := planedState.Lifecycle()
+	if err != nil {
+		return nil, status.NewErrorStatusWithMsg(status.InvalidArgument, err.Error())
+	}
 
 	// 2. get prior state which is stored in kusion_state.json
 	key := rn.state.ResourceKey()
 	priorState := operation.PriorStateResourceIndex[key]
 
 	// 3. get the latest resource from runtime
-	response := operation.Runtime.Read(context.Background(), &runtime.ReadRequest{Resource: planedState})
+	rt, s := operation.GetRuntime(planedState)
+	if status.IsErr(s) {
+		return nil, s
+	}
+	response := rt.Read(ctx, &runtime.ReadRequest{Resource: planedState})
 	liveState := response.Resource
-	s := response.Status
+	s = response.Status
 	if status.IsErr(s) {
-		return s
+		return nil, canceledStatus(ctx, s)
+	}
+	isApply := operation.OperationType == types.Apply || operation.OperationType == types.ApplyPreview
+	if isApply && rn.Action != types.Delete && liveState != nil && len(lifecycle.IgnoreChanges) != 0 {
+		priorState, planedState = ignoreChanges(lifecycle.IgnoreChanges, priorState, planedState, liveState)
 	}
 
 	// 4. compute ActionType of current resource node between planState and liveState
@@ -65,52 +162,144 @@ func (rn *ResourceNode) Execute(operation *opsmodels.Operation) status.Status {
 	case types.Destroy, types.DestroyPreview:
 		rn.Action = types.Delete
 	case types.Apply, types.ApplyPreview:
+		if rn.Action == types.Delete {
+			// resources removed from the Spec are marked as Delete by the DeleteResourceParser
+			break
+		}
 		if liveState == nil {
 			rn.Action = types.Create
 		} else if planedState == nil {
 			rn.Action = types.Delete
-		} else if reflect.DeepEqual(liveState, planedState) { // TODO: need a better comparable func
+		} else if rn.equal(priorState, planedState, liveState) {
 			rn.Action = types.UnChange
 		} else {
 			rn.Action = types.Update
+			if s = rn.checkReplace(ctx, rt, lifecycle, priorState, planedState, liveState); status.IsErr(s) {
+				return nil, canceledStatus(ctx, s)
+			}
 		}
 	default:
-		return status.NewErrorStatus(fmt.Errorf("unknown operation: %v", operation.OperationType))
+		return nil, status.NewErrorStatus(fmt.Errorf("unknown operation: %v", operation.OperationType))
 	}
 
-	// 5. apply or return
-	switch operation.OperationType {
-	case types.ApplyPreview, types.DestroyPreview:
-		fillResponseChangeSteps(operation, rn, priorState, planedState, liveState)
-	case types.Apply, types.Destroy:
-		switch rn.Action {
-		case types.Create, types.Delete, types.Update:
-			s := rn.applyResource(operation, priorState, planedState)
-			if status.IsErr(s) {
-				return s
-			}
-		case types.UnChange:
-			log.Infof("PriorAttributes and PlanAttributes are equal.")
-		default:
-			return status.NewErrorStatus(fmt.Errorf("unknown action:%s", rn.Action.PrettyString()))
+	// 5. enforce lifecycle settings on the action
+	if s = rn.enforceLifecycle(lifecycle); status.IsErr(s) {
+		return nil, s
+	}
+	if operation.OperationType == types.Apply && operation.PlannedOrder != nil {
+		if s = rn.checkPlannedAction(operation.PlannedOrder); status.IsErr(s) {
+			return nil, s
 		}
+	}
+
+	rn.change = &resourceChange{prior: priorState, plan: planedState, live: liveState}
+	return rn.change, nil
+}
+
+// equal compares the live resource with the planed one by the Comparator of its Type.
+// Resources that can't be compared are regarded as changed, since applying them again is harmless
+func (rn *ResourceNode) equal(priorState, planedState, liveState *models.Resource) bool {
+	equal, err := runtime.GetComparator(planedState.RuntimeType()).Equal(priorState, planedState, liveState)
+	if err != nil {
+		log.Warnf("compare resource:%s failed, regard it as changed. %v", rn.ID, err)
+		return false
+	}
+	return equal
+}
+
+// checkReplace turns the Update action into a replace if the Runtime reports the resource can't be updated in place.
+// The replacement is created before deleting the replaced resource if createBeforeDestroy is set, unless the Runtime
+// can't give the replacement a new identity, in which case the replaced resource is deleted first
+func (rn *ResourceNode) checkReplace(ctx context.Context, rt runtime.Runtime, lifecycle *models.Lifecycle,
+	priorState, planedState, liveState *models.Resource,
+) status.Status {
+	replacer, ok := rt.(runtime.Replacer)
+	if !ok {
+		return nil
+	}
+	response := replacer.RequiresReplace(ctx, &runtime.RequiresReplaceRequest{
+		PriorResource: priorState,
+		PlanResource:  planedState,
+		LiveResource:  liveState,
+	})
+	if status.IsErr(response.Status) {
+		return response.Status
+	}
+	if !response.Replace {
+		return nil
+	}
+
+	log.Infof("resource:%s requires replacement. %s", rn.ID, strings.Join(response.Reasons, "; "))
+	switch {
+	case !lifecycle.CreateBeforeDestroy:
+		rn.Action = types.Replace
+	case response.SameIdentity:
+		log.Warnf("resource:%s can't be created before deleting the replaced one since the replacement has the same "+
+			"identity, delete it first", rn.ID)
+		rn.Action = types.Replace
 	default:
-		return status.NewErrorStatus(fmt.Errorf("unknown operation: %v", operation.OperationType))
+		rn.Action = types.ReplaceCreateBeforeDelete
 	}
+	return nil
+}
 
+// checkPlannedAction makes sure the action of this node is the same as that in the saved plan
+func (rn *ResourceNode) checkPlannedAction(order *opsmodels.ChangeOrder) status.Status {
+	step := order.Get(rn.ID)
+	if step == nil {
+		return status.NewErrorStatusWithMsg(status.Conflict,
+			fmt.Sprintf("resource:%s is not in the plan. Please preview again", rn.ID))
+	}
+	if step.Action != rn.Action {
+		return status.NewErrorStatusWithMsg(status.Conflict,
+			fmt.Sprintf("resource:%s is planned to %s, but now requires %s. Please preview again", rn.ID, step.Action, rn.Action))
+	}
 	return nil
 }
 
-func (rn *ResourceNode) applyResource(operation *opsmodels.Operation, priorState, planedState *models.Resource) status.Status {
+// replaceAllRefs replaces references to other stacks and implicit references in attributes. References to other
+// stacks are resolved first, since they are applied before this operation
+func replaceAllRefs(operation *opsmodels.Operation, attributes map[string]interface{}, resourceIndex map[string]*models.Resource,
+	replaceFun func(resourceIndex map[string]*models.Resource, refPath string) (reflect.Value, status.Status),
+) (map[string]interface{}, status.Status) {
+	attributes, s := replaceStackRefs(operation, attributes)
+	if status.IsErr(s) {
+		return nil, s
+	}
+	_, implicitValue, s := ParseImplicitRef(reflect.ValueOf(attributes), resourceIndex, replaceFun)
+	if status.IsErr(s) {
+		return nil, s
+	}
+	return implicitValue.Interface().(map[string]interface{}), nil
+}
+
+// canceledStatus converts the error status of a runtime call to a status.Canceled status if it failed because the ctx is done
+func canceledStatus(ctx context.Context, s status.Status) status.Status {
+	if err := ctx.Err(); err != nil && status.IsErr(s) && s.Code() != status.Canceled {
+		return status.NewErrorStatusWithMsg(status.Canceled, fmt.Sprintf("%v: %s", err, s.Message()))
+	}
+	return s
+}
+
+func (rn *ResourceNode) applyResource(ctx context.Context, operation *opsmodels.Operation, priorState, planedState *models.Resource) status.Status {
 	log.Infof("operation:%v, prior:%v, plan:%v, live:%v", rn.Action, jsonutil.Marshal2String(priorState),
 		jsonutil.Marshal2String(planedState))
 
 	var res *models.Resource
-	var s status.Status
+	rt, s := operation.GetRuntime(rn.state)
+	if status.IsErr(s) {
+		return s
+	}
 
 	switch rn.Action {
 	case types.Create, types.Update:
-		response := operation.Runtime.Apply(context.Background(), &runtime.ApplyRequest{PriorResource: priorState, PlanResource: planedState})
+		response := rt.Apply(ctx, &runtime.ApplyRequest{
+			PriorResource:   priorState,
+			PlanResource:    planedState,
+			DryRun:          operation.DryRun,
+			ServerSideApply: operation.ServerSideApply,
+			ForceConflicts:  operation.ForceConflicts,
+		})
 		res = response.Resource
 		s = response.Status
 		log.Debugf("apply resource:%s, result: %v", planedState.ID, jsonutil.Marshal2String(res))
@@ -118,11 +307,15 @@ func (rn *ResourceNode) applyResource(operation *opsmodels.Operation, priorState
 			log.Debugf("apply status: %v", s.String())
 		}
 	case types.Delete:
-		response := operation.Runtime.Delete(context.Background(), &runtime.DeleteRequest{Resource: priorState})
+		response := rt.Delete(ctx, &runtime.DeleteRequest{Resource: priorState, DryRun: operation.DryRun})
 		s = response.Status
 		if s != nil {
 			log.Debugf("delete state: %v", s.String())
 		}
+	case types.Replace, types.ReplaceCreateBeforeDelete:
+		res, s = rn.replaceResource(ctx, operation, rt, priorState, planedState)
+	case types.Retain:
+		log.Infof("retain resource:%s in the actual infra and remove it from the state", rn.ID)
 	}
 	if status.IsErr(s) {
 		return s
@@ -130,21 +323,93 @@ func (rn *ResourceNode) applyResource(operation *opsmodels.Operation, priorState
 
 	// compatible with delete action
 	if res != nil {
+		// the Type decides the Runtime of this resource in later operations, so it never depends on the Runtime echoing it
+		res.Type = planedState.Type
 		res.DependsOn = planedState.DependsOn
+		// lifecycle settings are kept in the state, so that they are still enforced after the resource is removed from the Spec
+		res.Extensions = planedState.Extensions
 	}
 	key := rn.state.ResourceKey()
 	if e := operation.RefreshResourceIndex(key, res, rn.Action); e != nil {
 		return status.NewErrorStatus(e)
 	}
+	// dry-run results are only visible to the following nodes in this operation, and never be saved or watched
+	if operation.DryRun {
+		log.Infof("dry run resource success: %s", rn.state.ResourceKey())
+		return nil
+	}
 	if e := operation.UpdateState(operation.StateResourceIndex); e != nil {
 		return status.NewErrorStatus(e)
 	}
 
+	// block this node until the applied resource is ready, so that resources depend on it will not start too early
+	if rn.Action == types.Create || rn.Action == types.Update || rn.Action.IsReplace() {
+		if s = rn.waitForReady(ctx, operation, rt, res, nil); status.IsErr(s) {
+			return s
+		}
+	}
+
 	// print apply resource success msg
 	log.Infof("apply resource success: %s", rn.state.ResourceKey())
 	return nil
 }
 
+// replaceResource replaces the live resource with the planed one. By default, the live resource is deleted first and
+// the replacement is created after it is gone. With ReplaceCreateBeforeDelete, the replacement is created first, which
+// is only planned for Runtimes that give the replacement a different identity.
+// The whole replacement is done in this node, so resources depending on it are applied after the replacement is ready
+func (rn *ResourceNode) replaceResource(ctx context.Context, operation *opsmodels.Operation, rt runtime.Runtime,
+	priorState, planedState *models.Resource,
+) (*models.Resource, status.Status) {
+	replaced := priorState
+	if replaced == nil {
+		replaced = planedState
+	}
+
+	create := func() (*models.Resource, status.Status) {
+		// the replacement is a brand-new resource, so there is no prior resource to merge with
+		response := rt.Apply(ctx, &runtime.ApplyRequest{
+			PlanResource:    planedState,
+			DryRun:          operation.DryRun,
+			ServerSideApply: operation.ServerSideApply,
+			ForceConflicts:  operation.ForceConflicts,
+		})
+		log.Debugf("create replacement of resource:%s, result: %v", rn.ID, jsonutil.Marshal2String(response.Resource))
+		return response.Resource, response.Status
+	}
+	remove := func() status.Status {
+		response := rt.Delete(ctx, &runtime.DeleteRequest{Resource: replaced, DryRun: operation.DryRun})
+		if status.IsErr(response.Status) || operation.DryRun {
+			return response.Status
+		}
+		return rn.waitForDeletion(ctx, operation, rt, replaced)
+	}
+
+	if rn.Action == types.ReplaceCreateBeforeDelete {
+		res, s := create()
+		if status.IsErr(s) {
+			return nil, s
+		}
+		return res, remove()
+	}
+
+	if s := remove(); status.IsErr(s) {
+		return nil, s
+	}
+	// the replaced resource still exists in a dry run, which makes creating the replacement fail anyway
+	if operation.DryRun {
+		return planedState, nil
+	}
+	// forget the deleted resource in case creating the replacement fails
+	if e := operation.RefreshResourceIndex(planedState.ResourceKey(), nil, types.Delete); e != nil {
+		return nil, status.NewErrorStatus(e)
+	}
+	if e := operation.UpdateState(operation.StateResourceIndex); e != nil {
+		return nil, status.NewErrorStatus(e)
+	}
+	return create()
+}
+
 func (rn *ResourceNode) State() *models.Resource {
 	return rn.state
 }
@@ -176,37 +441,45 @@ func fillResponseChangeSteps(ops *opsmodels.Operation, rn *ResourceNode, prior,
 	order.ChangeSteps[rn.ID] = opsmodels.NewChangeStep(rn.ID, rn.Action, prior, plan, live)
 }
 
+// ImplicitReplaceFun replaces an implicit reference with the referenced value in the resourceIndex. See RefPath
+// for the format of references
 var ImplicitReplaceFun = func(resourceIndex map[string]*models.Resource, refPath string) (reflect.Value, status.Status) {
-	const Sep = "."
-	split := strings.Split(refPath, Sep)
-	key := split[0]
-	priorState := resourceIndex[key]
-	if priorState == nil {
-		msg := fmt.Sprintf("can't find state by key:%s when replacing %s", key, refPath)
-		return reflect.Value{}, status.NewErrorStatusWithMsg(status.IllegalManifest, msg)
-	}
-	attributes := priorState.Attributes
-	if attributes == nil {
-		msg := fmt.Sprintf("attributes is nil in resource:%s", key)
-		return reflect.Value{}, status.NewErrorStatusWithMsg(status.IllegalManifest, msg)
-	}
-	var valueMap interface{}
-	valueMap = attributes
-	if len(split) > 1 {
-		split := split[1:]
-		for _, k := range split {
-			if valueMap.(map[string]interface{})[k] == nil {
-				msg := fmt.Sprintf("can't find specified value in resource:%s by ref:%s", key, refPath)
-				return reflect.Value{}, status.NewErrorStatusWithMsg(status.IllegalManifest, msg)
-			}
-			valueMap = valueMap.(map[string]interface{})[k]
-		}
+	path, err := ParseRefPath(refPath)
+	if err != nil {
+		return reflect.Value{}, status.NewErrorStatusWithMsg(status.InvalidArgument, err.Error())
+	}
+	v, s := path.Resolve(resourceIndex, func(reason string) (interface{}, status.Status) {
+		return nil, status.NewErrorStatusWithMsg(status.IllegalManifest, reason)
+	})
+	if status.IsErr(s) {
+		return reflect.Value{}, s
 	}
-	return reflect.ValueOf(valueMap), nil
+	return refValue(v), nil
 }
 
 func ParseImplicitRef(v reflect.Value, resourceIndex map[string]*models.Resource,
 	replaceFun func(resourceIndex map[string]*models.Resource, refPath string) (reflect.Value, status.Status),
+) ([]string, reflect.Value, status.Status) {
+	var result []string
+	_, v, s := replaceRefs(v, ImplicitRefPrefix, func(ref string) (reflect.Value, status.Status) {
+		path, err := ParseRefPath(ref)
+		if err != nil {
+			return reflect.Value{}, status.NewErrorStatusWithMsg(status.InvalidArgument, err.Error())
+		}
+		result = append(result, path.ResourceKey)
+		log.Infof("add implicit ref:%s", path.ResourceKey)
+		// replace v with output
+		return replaceFun(resourceIndex, ref)
+	})
+	if status.IsErr(s) {
+		return nil, v, s
+	}
+	return result, v, nil
+}
+
+// replaceRefs walks the value and replaces strings with the prefix by the replaceFun. It returns all refs found in the
+// value without the prefix
+func replaceRefs(v reflect.Value, prefix string, replaceFun func(ref string) (reflect.Value, status.Status),
 ) ([]string, reflect.Value, status.Status) {
 	var result []string
 	if !v.IsValid() {
@@ -218,18 +491,13 @@ func ParseImplicitRef(v reflect.Value, resourceIndex map[string]*models.Resource
 		if v.IsNil() {
 			return nil, v, nil
 		}
-		return ParseImplicitRef(v.Elem(), resourceIndex, replaceFun)
+		return replaceRefs(v.Elem(), prefix, replaceFun)
 	case reflect.String:
 		vStr := v.String()
-		if strings.HasPrefix(vStr, ImplicitRefPrefix) {
-			ref := strings.TrimPrefix(vStr, ImplicitRefPrefix)
-			util.CheckArgument(len(ref) > 0,
-				fmt.Sprintf("illegal implicit ref:%s. Implicit ref format: %sresourceKey.attribute", ref, ImplicitRefPrefix))
-			split := strings.Split(ref, ".")
-			result = append(result, split[0])
-			log.Infof("add implicit ref:%s", split[0])
-			// replace v with output
-			tv, s := replaceFun(resourceIndex, ref)
+		if strings.HasPrefix(vStr, prefix) {
+			ref := strings.TrimPrefix(vStr, prefix)
+			result = append(result, ref)
+			tv, s := replaceFun(ref)
 			if status.IsErr(s) {
 				return nil, v, s
 			}
@@ -243,7 +511,7 @@ func ParseImplicitRef(v reflect.Value, resourceIndex map[string]*models.Resource
 		vs := reflect.MakeSlice(v.Type(), 0, 0)
 
 		for i := 0; i < v.Len(); i++ {
-			ref, tv, s := ParseImplicitRef(v.Index(i), resourceIndex, replaceFun)
+			ref, tv, s := replaceRefs(v.Index(i), prefix, replaceFun)
 			if status.IsErr(s) {
 				return nil, tv, s
 			}
@@ -261,7 +529,7 @@ func ParseImplicitRef(v reflect.Value, resourceIndex map[string]*models.Resource
 
 		iter := v.MapRange()
 		for iter.Next() {
-			ref, tv, s := ParseImplicitRef(iter.Value(), resourceIndex, replaceFun)
+			ref, tv, s := replaceRefs(iter.Value(), prefix, replaceFun)
 			if status.IsErr(s) {
 				return nil, tv, s
 			}
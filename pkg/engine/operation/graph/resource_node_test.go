@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"kusionstack.io/kusion/pkg/engine/states/local"
 
@@ -115,7 +116,7 @@ func TestResourceNode_Execute(t *testing.T) {
 				MsgCh:                   make(chan opsmodels.Message),
 				ResultState:             states.NewState(),
 				Lock:                    &sync.Mutex{},
-				Runtime:                 &runtime.KubernetesRuntime{},
+				RuntimeMap:              map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
 			}},
 			want: nil,
 		},
@@ -135,7 +136,7 @@ func TestResourceNode_Execute(t *testing.T) {
 				MsgCh:                   make(chan opsmodels.Message),
 				ResultState:             states.NewState(),
 				Lock:                    &sync.Mutex{},
-				Runtime:                 &runtime.KubernetesRuntime{},
+				RuntimeMap:              map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
 			}},
 			want: nil,
 		},
@@ -155,7 +156,7 @@ func TestResourceNode_Execute(t *testing.T) {
 				MsgCh:                   make(chan opsmodels.Message),
 				ResultState:             states.NewState(),
 				Lock:                    &sync.Mutex{},
-				Runtime:                 &runtime.KubernetesRuntime{},
+				RuntimeMap:              map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
 			}},
 			want: status.NewErrorStatusWithMsg(status.IllegalManifest, "can't find specified value in resource:jack by ref:jack.notExist"),
 		},
@@ -167,7 +168,7 @@ func TestResourceNode_Execute(t *testing.T) {
 				Action:   tt.fields.Action,
 				state:    tt.fields.state,
 			}
-			monkey.PatchInstanceMethod(reflect.TypeOf(tt.args.operation.Runtime), "Apply",
+			monkey.PatchInstanceMethod(reflect.TypeOf(tt.args.operation.RuntimeMap[models.Kubernetes]), "Apply",
 				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
 					mockState := *newResourceState
 					mockState.Attributes["a"] = "c"
@@ -175,21 +176,409 @@ func TestResourceNode_Execute(t *testing.T) {
 						Resource: &mockState,
 					}
 				})
-			monkey.PatchInstanceMethod(reflect.TypeOf(tt.args.operation.Runtime), "Delete",
+			monkey.PatchInstanceMethod(reflect.TypeOf(tt.args.operation.RuntimeMap[models.Kubernetes]), "Delete",
 				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
 					return nil
 				})
-			monkey.PatchInstanceMethod(reflect.TypeOf(tt.args.operation.Runtime), "Read",
+			monkey.PatchInstanceMethod(reflect.TypeOf(tt.args.operation.RuntimeMap[models.Kubernetes]), "Read",
 				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
 					return &runtime.ReadResponse{Resource: request.Resource}
 				})
+			monkey.PatchInstanceMethod(reflect.TypeOf(tt.args.operation.RuntimeMap[models.Kubernetes]), "Watch",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+					return nil
+				})
 			monkey.PatchInstanceMethod(reflect.TypeOf(tt.args.operation.StateStorage), "Apply",
 				func(f *local.FileSystemState, state *states.State) error {
 					return nil
 				})
 			defer monkey.UnpatchAll()
 
-			assert.Equalf(t, tt.want, rn.Execute(&tt.args.operation), "Execute(%v)", tt.args.operation)
+			assert.Equalf(t, tt.want, rn.Execute(context.Background(), &tt.args.operation), "Execute(%v)", tt.args.operation)
+		})
+	}
+}
+
+func TestResourceNode_ExecuteDryRun(t *testing.T) {
+	resource := &models.Resource{
+		ID:         "jack",
+		Attributes: map[string]interface{}{"a": "b"},
+	}
+	operation := &opsmodels.Operation{
+		OperationType:           types.Apply,
+		StateStorage:            local.NewFileSystemState(),
+		CtxResourceIndex:        map[string]*models.Resource{},
+		PriorStateResourceIndex: map[string]*models.Resource{},
+		StateResourceIndex:      map[string]*models.Resource{},
+		ResultState:             states.NewState(),
+		Lock:                    &sync.Mutex{},
+		RuntimeMap:              map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
+		DryRun:                  true,
+	}
+	rn, s := NewResourceNode(resource.ID, resource, types.Create)
+	assert.Nil(t, s)
+
+	defer monkey.UnpatchAll()
+	monkey.PatchInstanceMethod(reflect.TypeOf(operation.RuntimeMap[models.Kubernetes]), "Read",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+			return &runtime.ReadResponse{}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(operation.RuntimeMap[models.Kubernetes]), "Apply",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+			assert.True(t, request.DryRun)
+			return &runtime.ApplyResponse{Resource: request.PlanResource}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(operation.RuntimeMap[models.Kubernetes]), "Watch",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+			t.Errorf("dry-run resource should not be watched")
+			return nil
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(operation.StateStorage), "Apply",
+		func(f *local.FileSystemState, state *states.State) error {
+			t.Errorf("dry run should not save state")
+			return nil
+		})
+
+	assert.Nil(t, rn.Execute(context.Background(), operation))
+	assert.Equal(t, resource, operation.CtxResourceIndex[resource.ID])
+}
+
+func TestResourceNode_ExecuteCanceled(t *testing.T) {
+	resource := &models.Resource{
+		ID:         "jack",
+		Attributes: map[string]interface{}{"a": "b"},
+	}
+	newOperation := func() *opsmodels.Operation {
+		return &opsmodels.Operation{
+			OperationType:           types.Apply,
+			StateStorage:            local.NewFileSystemState(),
+			CtxResourceIndex:        map[string]*models.Resource{},
+			PriorStateResourceIndex: map[string]*models.Resource{},
+			StateResourceIndex:      map[string]*models.Resource{},
+			ResultState:             states.NewState(),
+			Lock:                    &sync.Mutex{},
+			RuntimeMap:              map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
+			ResourceTimeout:         10 * time.Millisecond,
+		}
+	}
+
+	defer monkey.UnpatchAll()
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Read",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+			return &runtime.ReadResponse{}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Apply",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+			// block until the resource timeout expires
+			<-ctx.Done()
+			return &runtime.ApplyResponse{Status: status.NewErrorStatus(ctx.Err())}
+		})
+
+	t.Run("canceled before execution", func(t *testing.T) {
+		rn, s := NewResourceNode(resource.ID, resource, types.Create)
+		assert.Nil(t, s)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s = rn.Execute(ctx, newOperation())
+		assert.Equal(t, status.Canceled, s.Code())
+	})
+
+	t.Run("resource timeout", func(t *testing.T) {
+		rn, s := NewResourceNode(resource.ID, resource, types.Create)
+		assert.Nil(t, s)
+
+		s = rn.Execute(context.Background(), newOperation())
+		assert.Equal(t, status.Canceled, s.Code())
+	})
+}
+
+func TestResourceNode_ExecuteReplace(t *testing.T) {
+	newOperation := func() *opsmodels.Operation {
+		return &opsmodels.Operation{
+			OperationType:           types.Apply,
+			StateStorage:            local.NewFileSystemState(),
+			CtxResourceIndex:        map[string]*models.Resource{},
+			PriorStateResourceIndex: map[string]*models.Resource{},
+			StateResourceIndex:      map[string]*models.Resource{},
+			ResultState:             states.NewState(),
+			Lock:                    &sync.Mutex{},
+			RuntimeMap:              map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		extensions   map[string]interface{}
+		sameIdentity bool
+		action       types.ActionType
+		calls        []string
+	}{
+		{
+			name:   "delete-then-create",
+			action: types.Replace,
+			calls:  []string{"Delete", "Apply"},
+		},
+		{
+			name:       "create-before-delete",
+			extensions: map[string]interface{}{models.CreateBeforeDestroyExtension: true},
+			action:     types.ReplaceCreateBeforeDelete,
+			calls:      []string{"Apply", "Delete"},
+		},
+		{
+			name:         "delete first for the same identity",
+			extensions:   map[string]interface{}{models.CreateBeforeDestroyExtension: true},
+			sameIdentity: true,
+			action:       types.Replace,
+			calls:        []string{"Delete", "Apply"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := &models.Resource{
+				ID:         "jack",
+				Attributes: map[string]interface{}{"a": "b"},
+				Extensions: tt.extensions,
+			}
+			var calls []string
+			deleted := false
+
+			defer monkey.UnpatchAll()
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Read",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+					if deleted {
+						return &runtime.ReadResponse{}
+					}
+					return &runtime.ReadResponse{Resource: &models.Resource{ID: "jack", Attributes: map[string]interface{}{"a": "c"}}}
+				})
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "RequiresReplace",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.RequiresReplaceRequest) *runtime.RequiresReplaceResponse {
+					return &runtime.RequiresReplaceResponse{
+						Replace:      true,
+						Reasons:      []string{"a: field is immutable"},
+						SameIdentity: tt.sameIdentity,
+					}
+				})
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Apply",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+					assert.Nil(t, request.PriorResource)
+					calls = append(calls, "Apply")
+					return &runtime.ApplyResponse{Resource: request.PlanResource}
+				})
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Delete",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+					calls = append(calls, "Delete")
+					deleted = true
+					return &runtime.DeleteResponse{}
+				})
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Watch",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+					return nil
+				})
+			monkey.PatchInstanceMethod(reflect.TypeOf(&local.FileSystemState{}), "Apply",
+				func(f *local.FileSystemState, state *states.State) error {
+					return nil
+				})
+
+			rn, s := NewResourceNode(resource.ID, resource, types.Update)
+			assert.Nil(t, s)
+			operation := newOperation()
+			assert.Nil(t, rn.Execute(context.Background(), operation))
+			assert.Equal(t, tt.action, rn.Action)
+			assert.Equal(t, tt.calls, calls)
+			assert.Equal(t, resource, operation.StateResourceIndex[resource.ID])
+		})
+	}
+}
+
+func TestResourceNode_ExecuteLifecycle(t *testing.T) {
+	newOperation := func(operationType types.OperationType, prior *models.Resource) *opsmodels.Operation {
+		return &opsmodels.Operation{
+			OperationType:           operationType,
+			StateStorage:            local.NewFileSystemState(),
+			CtxResourceIndex:        map[string]*models.Resource{prior.ID: prior},
+			PriorStateResourceIndex: map[string]*models.Resource{prior.ID: prior},
+			StateResourceIndex:      map[string]*models.Resource{prior.ID: prior},
+			ResultState:             states.NewState(),
+			Lock:                    &sync.Mutex{},
+			RuntimeMap:              map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
+		}
+	}
+
+	defer monkey.UnpatchAll()
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Read",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+			return &runtime.ReadResponse{Resource: request.Resource}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Delete",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+			t.Errorf("resource should not be deleted")
+			return &runtime.DeleteResponse{}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&local.FileSystemState{}), "Apply",
+		func(f *local.FileSystemState, state *states.State) error {
+			return nil
+		})
+
+	t.Run("retain removed resource", func(t *testing.T) {
+		resource := &models.Resource{
+			ID:         "jack",
+			Attributes: map[string]interface{}{"a": "b"},
+			Extensions: map[string]interface{}{models.DeletionPolicyExtension: "retain"},
+		}
+		rn, s := NewResourceNode(resource.ID, resource, types.Delete)
+		assert.Nil(t, s)
+		operation := newOperation(types.Apply, resource)
+
+		assert.Nil(t, rn.Execute(context.Background(), operation))
+		assert.Equal(t, types.Retain, rn.Action)
+		assert.Nil(t, operation.StateResourceIndex[resource.ID])
+	})
+
+	t.Run("prevent destroy in preview", func(t *testing.T) {
+		resource := &models.Resource{
+			ID:         "jack",
+			Attributes: map[string]interface{}{"a": "b"},
+			Extensions: map[string]interface{}{models.PreventDestroyExtension: true},
+		}
+		rn, s := NewResourceNode(resource.ID, resource, types.Delete)
+		assert.Nil(t, s)
+
+		s = rn.Execute(context.Background(), newOperation(types.DestroyPreview, resource))
+		assert.Equal(t, status.PermissionDenied, s.Code())
+	})
+}
+
+func TestResourceNode_ExecuteWaitForReady(t *testing.T) {
+	tests := []struct {
+		name     string
+		events   []runtime.WatchEvent
+		wantCode status.Code
+	}{
+		{
+			name: "ready",
+			events: []runtime.WatchEvent{
+				{Message: "waiting for deployment jack rollout"},
+				{Ready: true, Message: "deployment jack successfully rolled out"},
+			},
+		},
+		{
+			name: "failed",
+			events: []runtime.WatchEvent{
+				{Message: "waiting for job jack to complete"},
+				{Failed: true, Message: "job jack failed"},
+			},
+			wantCode: status.Internal,
+		},
+		{
+			name:     "timeout",
+			events:   []runtime.WatchEvent{{Message: "waiting for deployment jack rollout"}},
+			wantCode: status.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := &models.Resource{
+				ID:         "jack",
+				Attributes: map[string]interface{}{"a": "b"},
+			}
+			operation := &opsmodels.Operation{
+				OperationType:           types.Apply,
+				StateStorage:            local.NewFileSystemState(),
+				CtxResourceIndex:        map[string]*models.Resource{},
+				PriorStateResourceIndex: map[string]*models.Resource{},
+				StateResourceIndex:      map[string]*models.Resource{},
+				ResultState:             states.NewState(),
+				Lock:                    &sync.Mutex{},
+				RuntimeMap:              map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
+				WatchTimeout:            50 * time.Millisecond,
+			}
+			defer monkey.UnpatchAll()
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Read",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+					return &runtime.ReadResponse{}
+				})
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Apply",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+					return &runtime.ApplyResponse{Resource: request.PlanResource}
+				})
+			monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Watch",
+				func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+					ch := make(chan runtime.WatchEvent)
+					go func() {
+						defer close(ch)
+						for _, event := range tt.events {
+							select {
+							case ch <- event:
+							case <-ctx.Done():
+								return
+							}
+						}
+						// keep the watch open without events until the watch timeout expires
+						<-ctx.Done()
+					}()
+					return &runtime.WatchResponse{ResultChan: ch}
+				})
+			monkey.PatchInstanceMethod(reflect.TypeOf(&local.FileSystemState{}), "Apply",
+				func(f *local.FileSystemState, state *states.State) error {
+					return nil
+				})
+
+			rn, s := NewResourceNode(resource.ID, resource, types.Create)
+			assert.Nil(t, s)
+			// the unbuffered watch channel makes the node block until the ready or failed event is received
+			start := time.Now()
+			s = rn.Execute(context.Background(), operation)
+			if tt.wantCode == status.DeadlineExceeded {
+				assert.GreaterOrEqual(t, int64(time.Since(start)), int64(operation.WatchTimeout))
+			}
+			if tt.wantCode == "" {
+				assert.Nil(t, s)
+				return
+			}
+			assert.Equal(t, tt.wantCode, s.Code())
 		})
 	}
 }
+
+func TestResourceNode_ExecuteKeepsType(t *testing.T) {
+	resource := &models.Resource{
+		ID:         "jack",
+		Type:       models.Kubernetes,
+		Attributes: map[string]interface{}{"a": "b"},
+	}
+	operation := &opsmodels.Operation{
+		OperationType:           types.Apply,
+		StateStorage:            local.NewFileSystemState(),
+		CtxResourceIndex:        map[string]*models.Resource{},
+		PriorStateResourceIndex: map[string]*models.Resource{},
+		StateResourceIndex:      map[string]*models.Resource{},
+		ResultState:             states.NewState(),
+		Lock:                    &sync.Mutex{},
+		RuntimeMap:              map[models.Type]runtime.Runtime{models.Kubernetes: &runtime.KubernetesRuntime{}},
+	}
+
+	defer monkey.UnpatchAll()
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Read",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+			return &runtime.ReadResponse{}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Apply",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+			// runtimes may not echo the Type back
+			return &runtime.ApplyResponse{Resource: &models.Resource{ID: request.PlanResource.ID, Attributes: request.PlanResource.Attributes}}
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&runtime.KubernetesRuntime{}), "Watch",
+		func(k *runtime.KubernetesRuntime, ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+			return nil
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(&local.FileSystemState{}), "Apply",
+		func(f *local.FileSystemState, state *states.State) error {
+			return nil
+		})
+
+	rn, s := NewResourceNode(resource.ID, resource, types.Create)
+	assert.Nil(t, s)
+	assert.Nil(t, rn.Execute(context.Background(), operation))
+	assert.Equal(t, models.Kubernetes, operation.StateResourceIndex[resource.ID].Type)
+}
@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// StackRefPrefix is the prefix of references to resources of other stacks, e.g.
+// `$kusion_stack.project/stack.resourceKey.attribute`. Unlike implicit references, they are resolved by the latest
+// states of referenced stacks, and never add dependencies between resources of the current stack
+const StackRefPrefix = "$kusion_stack."
+
+// StackRef is a parsed reference to an attribute of a resource in another stack
+type StackRef struct {
+	Project string
+	Stack   string
+	// Path is the resource key followed by the attribute path, in the same format as implicit references
+	Path string
+}
+
+// ParseStackRef parses a reference without the StackRefPrefix
+func ParseStackRef(ref string) (*StackRef, error) {
+	i := strings.Index(ref, ".")
+	if i < 0 || i == len(ref)-1 {
+		return nil, fmt.Errorf("illegal stack ref:%s. Stack ref format: %sproject/stack.resourceKey.attribute", ref, StackRefPrefix)
+	}
+	split := strings.Split(ref[:i], "/")
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return nil, fmt.Errorf("illegal stack ref:%s. Stack ref format: %sproject/stack.resourceKey.attribute", ref, StackRefPrefix)
+	}
+	return &StackRef{Project: split[0], Stack: split[1], Path: ref[i+1:]}, nil
+}
+
+// replaceStackRefs replaces references to other stacks in attributes with values in the latest states of these stacks.
+// States are read from the storage located by the StackStorage of the operation, or the StateStorage of it if the
+// StackStorage is nil. Every referenced stack is queried once
+func replaceStackRefs(operation *opsmodels.Operation, attributes map[string]interface{}) (map[string]interface{}, status.Status) {
+	var tenant string
+	if operation.ResultState != nil {
+		tenant = operation.ResultState.Tenant
+	}
+
+	stackIndexes := map[string]map[string]*models.Resource{}
+	_, v, s := replaceRefs(reflect.ValueOf(attributes), StackRefPrefix, func(ref string) (reflect.Value, status.Status) {
+		stackRef, err := ParseStackRef(ref)
+		if err != nil {
+			return reflect.Value{}, status.NewErrorStatusWithMsg(status.InvalidArgument, err.Error())
+		}
+
+		stackKey := stackRef.Project + "/" + stackRef.Stack
+		resourceIndex, ok := stackIndexes[stackKey]
+		if !ok {
+			storage := operation.StateStorage
+			if operation.StackStorage != nil {
+				if storage, err = operation.StackStorage(stackRef.Project, stackRef.Stack); err != nil {
+					return reflect.Value{}, status.NewErrorStatusWithMsg(status.IllegalManifest,
+						fmt.Sprintf("can't find stack:%s referenced by %s. %v", stackKey, ref, err))
+				}
+			}
+			state, err := storage.GetLatestState(&states.StateQuery{
+				Tenant:  tenant,
+				Project: stackRef.Project,
+				Stack:   stackRef.Stack,
+			})
+			if err != nil {
+				return reflect.Value{}, status.NewErrorStatusWithMsg(status.Internal,
+					fmt.Sprintf("failed to get the state of stack:%s referenced by %s. %v", stackKey, ref, err))
+			}
+			// some backends like the local file system ignore the query, so make sure it is the referenced stack
+			if state == nil || state.Project != stackRef.Project || state.Stack != stackRef.Stack {
+				return reflect.Value{}, status.NewErrorStatusWithMsg(status.IllegalManifest,
+					fmt.Sprintf("can't find the state of stack:%s referenced by %s. Please apply it first", stackKey, ref))
+			}
+			resourceIndex = state.Resources.Index()
+			stackIndexes[stackKey] = resourceIndex
+		}
+
+		log.Infof("replace stack ref:%s", ref)
+		tv, s := ImplicitReplaceFun(resourceIndex, stackRef.Path)
+		if status.IsErr(s) {
+			return reflect.Value{}, status.NewErrorStatusWithMsg(s.Code(),
+				fmt.Sprintf("failed to replace stack ref:%s. %s", ref, s.Message()))
+		}
+		return tv, nil
+	})
+	if status.IsErr(s) {
+		return nil, s
+	}
+	return v.Interface().(map[string]interface{}), nil
+}
@@ -0,0 +1,122 @@
+package graph
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func TestParseStackRef(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    *StackRef
+		wantErr bool
+	}{
+		{
+			ref:  "network/prod.vpc.id",
+			want: &StackRef{Project: "network", Stack: "prod", Path: "vpc.id"},
+		},
+		{
+			ref:  "network/prod.v1:Service:default:nginx",
+			want: &StackRef{Project: "network", Stack: "prod", Path: "v1:Service:default:nginx"},
+		},
+		{ref: "network/prod", wantErr: true},
+		{ref: "network/prod.", wantErr: true},
+		{ref: "network.vpc.id", wantErr: true},
+		{ref: "/prod.vpc.id", wantErr: true},
+		{ref: "network/prod/dev.vpc.id", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseStackRef(tt.ref)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_replaceStackRefs(t *testing.T) {
+	// states of the current stack and the referenced stack are saved in their own directories by the local backend,
+	// which ignores queries
+	current := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	assert.NoError(t, current.Apply(&states.State{Project: "app", Stack: "prod"}))
+	network := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	assert.NoError(t, network.Apply(&states.State{
+		Project: "network",
+		Stack:   "prod",
+		Resources: models.Resources{
+			{ID: "vpc", Attributes: map[string]interface{}{"id": "vpc-1", "cidr": []interface{}{"10.0.0.0/16"}}},
+		},
+	}))
+	operation := &opsmodels.Operation{
+		StateStorage: current,
+		StackStorage: func(project, stack string) (states.StateStorage, error) {
+			if project == "network" && stack == "prod" {
+				return network, nil
+			}
+			return nil, fmt.Errorf("stack %s/%s not found", project, stack)
+		},
+		ResultState: states.NewState(),
+	}
+
+	tests := []struct {
+		name       string
+		attributes map[string]interface{}
+		want       map[string]interface{}
+		wantCode   status.Code
+	}{
+		{
+			name: "replace",
+			attributes: map[string]interface{}{
+				"vpc":     StackRefPrefix + "network/prod.vpc.id",
+				"cidr":    []interface{}{StackRefPrefix + "network/prod.vpc.cidr"},
+				"subnet":  ImplicitRefPrefix + "subnet.id",
+				"replica": 1,
+			},
+			want: map[string]interface{}{
+				"vpc":     "vpc-1",
+				"cidr":    []interface{}{[]interface{}{"10.0.0.0/16"}},
+				"subnet":  ImplicitRefPrefix + "subnet.id",
+				"replica": 1,
+			},
+		},
+		{
+			name:       "stack not found",
+			attributes: map[string]interface{}{"vpc": StackRefPrefix + "network/dev.vpc.id"},
+			wantCode:   status.IllegalManifest,
+		},
+		{
+			name:       "attribute not found",
+			attributes: map[string]interface{}{"vpc": StackRefPrefix + "network/prod.vpc.name"},
+			wantCode:   status.IllegalManifest,
+		},
+		{
+			name:       "illegal ref",
+			attributes: map[string]interface{}{"vpc": StackRefPrefix + "network.vpc.id"},
+			wantCode:   status.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, s := replaceStackRefs(operation, tt.attributes)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, s.Code())
+				return
+			}
+			assert.Nil(t, s)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+	jsonutil "kusionstack.io/kusion/pkg/util/json"
+)
+
+type ImportOperation struct {
+	opsmodels.Operation
+}
+
+type ImportRequest struct {
+	opsmodels.Request `json:",inline" yaml:",inline"`
+
+	// IDs are keys of resources in the Spec to be imported. Empty means importing all resources in the Spec
+	// that exist in the actual infra
+	IDs []string `json:"ids,omitempty" yaml:"ids,omitempty"`
+}
+
+type ImportResponse struct {
+	// State is the State saved after importing
+	State *states.State
+	// Resources are resources imported in this operation
+	Resources models.Resources
+	// Skipped are keys of resources that are already managed by Kusion or don't exist in the actual infra
+	Skipped []string
+}
+
+// Import reads live resources in the actual infra and records them in the State, so that resources created outside of Kusion
+// can be managed by Kusion without changing them. Resources already in the prior State will not be imported again.
+// Import never invokes methods of the Runtime that make changes in the actual infra. Reading resources is canceled
+// when the ctx is done, and the State is not changed in that case
+func (iop *ImportOperation) Import(ctx context.Context, request *ImportRequest) (rsp *ImportResponse, st status.Status) {
+	log.Infof("engine: Import start!")
+	o := iop.Operation
+
+	defer func() {
+		if e := recover(); e != nil {
+			log.Error("import panic:%v", e)
+
+			switch x := e.(type) {
+			case string:
+				st = status.NewErrorStatus(fmt.Errorf("import panic:%s", e))
+			case error:
+				st = status.NewErrorStatus(x)
+			default:
+				st = status.NewErrorStatusWithCode(status.Unknown, errors.New("unknown panic"))
+			}
+		}
+	}()
+
+	if st = validateRequest(&request.Request); status.IsErr(st) {
+		return nil, st
+	}
+
+	// 1. init & build Indexes
+	priorState, resultState := o.InitStates(&request.Request)
+	priorStateResourceIndex := priorState.Resources.Index()
+	planResourceIndex := request.Spec.Resources.Index()
+
+	resources := request.Spec.Resources
+	if len(request.IDs) != 0 {
+		resources = make(models.Resources, 0, len(request.IDs))
+		for _, id := range request.IDs {
+			r, ok := planResourceIndex[id]
+			if !ok {
+				msg := fmt.Sprintf("can't find resource:%s in the spec", id)
+				return nil, status.NewErrorStatusWithMsg(status.InvalidArgument, msg)
+			}
+			resources = append(resources, *r)
+		}
+	}
+	runtimeMap, err := runtime.InitRuntimes(o.RuntimeMap, resources)
+	if err != nil {
+		return nil, status.NewErrorStatus(err)
+	}
+
+	// 2. read live resources
+	rsp = &ImportResponse{Resources: models.Resources{}}
+	stateResourceIndex := make(map[string]*models.Resource, len(priorStateResourceIndex)+len(resources))
+	for k, v := range priorStateResourceIndex {
+		stateResourceIndex[k] = v
+	}
+	for i := range resources {
+		plan := &resources[i]
+		key := plan.ResourceKey()
+		if _, ok := priorStateResourceIndex[key]; ok {
+			log.Infof("resource:%s is already managed by kusion, skip importing it", key)
+			rsp.Skipped = append(rsp.Skipped, key)
+			continue
+		}
+
+		live, s := importResource(ctx, runtimeMap[plan.RuntimeType()], plan)
+		if status.IsErr(s) {
+			return nil, s
+		}
+		if live == nil {
+			// resources specified explicitly must exist
+			if len(request.IDs) != 0 {
+				msg := fmt.Sprintf("can't find resource:%s in the actual infra", key)
+				return nil, status.NewErrorStatusWithMsg(status.NotFound, msg)
+			}
+			log.Infof("resource:%s doesn't exist, skip importing it", key)
+			rsp.Skipped = append(rsp.Skipped, key)
+			continue
+		}
+
+		// live resources only have attributes, and Kusion settings of them, e.g. lifecycle settings in Extensions,
+		// are taken from the spec
+		live.ID = key
+		live.Type = plan.Type
+		live.DependsOn = plan.DependsOn
+		live.Extensions = plan.Extensions
+		// the imported resource is the last-applied configuration of the next apply, so only fields declared in the
+		// spec are recorded. Otherwise fields populated by the actual infra, e.g. defaulted values and labels added by
+		// controllers, would be deleted by the next apply
+		live.Attributes = jsonutil.RemoveMapFields(plan.Attributes, live.Attributes)
+		stateResourceIndex[key] = live
+		rsp.Resources = append(rsp.Resources, *live)
+	}
+
+	// 3. save the State
+	rsp.State = priorState
+	if len(rsp.Resources) == 0 {
+		return rsp, nil
+	}
+	importOperation := &ImportOperation{
+		Operation: opsmodels.Operation{
+			StateStorage: o.StateStorage,
+			ResultState:  resultState,
+			Lock:         &sync.Mutex{},
+		},
+	}
+	if err = importOperation.UpdateState(stateResourceIndex); err != nil {
+		return nil, status.NewErrorStatus(err)
+	}
+	rsp.State = resultState
+	return rsp, nil
+}
+
+// importResource reads the live resource by runtime.Importer if the Runtime implements it, otherwise by Runtime.Read
+func importResource(ctx context.Context, rt runtime.Runtime, plan *models.Resource) (*models.Resource, status.Status) {
+	if importer, ok := rt.(runtime.Importer); ok {
+		response := importer.Import(ctx, &runtime.ImportRequest{PlanResource: plan})
+		return response.Resource, response.Status
+	}
+	response := rt.Read(ctx, &runtime.ReadRequest{Resource: plan})
+	return response.Resource, response.Status
+}
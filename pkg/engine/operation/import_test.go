@@ -0,0 +1,95 @@
+package operation
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func TestImportOperation_Import(t *testing.T) {
+	// fake-id doesn't exist in fakePreviewRuntime
+	spec := &models.Spec{Resources: models.Resources{FakeResourceState, FakeResourceState2}}
+	request := func(ids ...string) *ImportRequest {
+		return &ImportRequest{
+			Request: opsmodels.Request{Tenant: "fake-tenant", Stack: "fake-stack", Project: "fake-project", Spec: spec},
+			IDs:     ids,
+		}
+	}
+
+	t.Run("import all", func(t *testing.T) {
+		storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+		iop := &ImportOperation{Operation: opsmodels.Operation{
+			StateStorage: storage,
+			RuntimeMap:   map[models.Type]runtime.Runtime{models.Kubernetes: &fakePreviewRuntime{}},
+		}}
+
+		rsp, s := iop.Import(context.Background(), request())
+		assert.Nil(t, s)
+		assert.Equal(t, models.Resources{FakeResourceState2}, rsp.Resources)
+		assert.Equal(t, []string{FakeResourceState.ID}, rsp.Skipped)
+
+		state, err := storage.GetLatestState(nil)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(1), state.Serial)
+		assert.Len(t, state.Resources, 1)
+
+		// resources already in the state are not imported again
+		rsp, s = iop.Import(context.Background(), request(FakeResourceState2.ID))
+		assert.Nil(t, s)
+		assert.Empty(t, rsp.Resources)
+		assert.Equal(t, []string{FakeResourceState2.ID}, rsp.Skipped)
+	})
+
+	t.Run("record fields of the spec", func(t *testing.T) {
+		plan := models.Resource{
+			ID:   "nginx",
+			Type: fakeType,
+			Attributes: map[string]interface{}{
+				"image":  "nginx:1.20",
+				"labels": map[string]interface{}{"app": "nginx"},
+			},
+			Extensions: map[string]interface{}{models.PreventDestroyExtension: true},
+		}
+		storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+		iop := &ImportOperation{Operation: opsmodels.Operation{
+			StateStorage: storage,
+			RuntimeMap: map[models.Type]runtime.Runtime{fakeType: &fakeRefreshRuntime{t: t, live: map[string]*models.Resource{
+				"nginx": {ID: "nginx", Type: fakeType, Attributes: map[string]interface{}{
+					"image":    "nginx:1.20",
+					"labels":   map[string]interface{}{"app": "nginx", "controller": "added"},
+					"replicas": 1,
+				}},
+			}}},
+		}}
+
+		rsp, s := iop.Import(context.Background(), &ImportRequest{Request: opsmodels.Request{
+			Tenant: "fake-tenant", Stack: "fake-stack", Project: "fake-project",
+			Spec: &models.Spec{Resources: models.Resources{plan}},
+		}})
+		assert.Nil(t, s)
+		assert.Equal(t, models.Resources{plan}, rsp.Resources)
+	})
+
+	t.Run("resource not exist", func(t *testing.T) {
+		storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+		iop := &ImportOperation{Operation: opsmodels.Operation{
+			StateStorage: storage,
+			RuntimeMap:   map[models.Type]runtime.Runtime{models.Kubernetes: &fakePreviewRuntime{}},
+		}}
+
+		_, s := iop.Import(context.Background(), request(FakeResourceState.ID))
+		assert.Equal(t, status.NotFound, s.Code())
+
+		_, s = iop.Import(context.Background(), request("not-in-spec"))
+		assert.Equal(t, status.InvalidArgument, s.Code())
+	})
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"kusionstack.io/kusion/pkg/engine/operation/utils"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/pterm/pterm"
+	yamlv3 "gopkg.in/yaml.v3"
 
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/projectstack"
@@ -23,23 +25,24 @@ import (
 )
 
 type ChangeStep struct {
-	ID       string           // the resource id
-	Action   types.ActionType // the operation performed by this step.
-	Original interface{}      // local stored resource
-	Modified interface{}      // planed resource
-	Current  interface{}      // live resource
+	ID       string           `json:"id" yaml:"id"`             // the resource id
+	Action   types.ActionType `json:"action" yaml:"action"`     // the operation performed by this step.
+	Original interface{}      `json:"original" yaml:"original"` // local stored resource
+	Modified interface{}      `json:"modified" yaml:"modified"` // planed resource
+	Current  interface{}      `json:"current" yaml:"current"`   // live resource
 }
 
-// TODO: 3-way diff
+// Diff returns a three-way diff report of this step. Each hunk of the report is labeled by its origin, so that
+// changes made in the configuration can be told apart from drift made out-of-band and fields populated by the runtime
 func (cs *ChangeStep) Diff() (string, error) {
-	// Generate diff report
-	diffReport, err := diffToReport(cs.Current, cs.Modified)
+	// Generate diff hunks
+	hunks, err := cs.DiffHunks()
 	if err != nil {
 		log.Errorf("failed to compute diff with ChangeStep ID: %s", cs.ID)
 		return "", err
 	}
 
-	reportString, err := diff.ToReportString(*diffReport)
+	reportString, err := hunksToString(hunks)
 	if err != nil {
 		log.Warn("diff to string error: %v", err)
 		return "", err
@@ -65,6 +68,185 @@ func (cs *ChangeStep) Diff() (string, error) {
 	return buf.String(), nil
 }
 
+// DiffOrigin tells where a hunk of the three-way diff comes from
+type DiffOrigin string
+
+const (
+	// ConfigChange is a change made in the configuration, i.e. between the prior and the planed resource
+	ConfigChange DiffOrigin = "config"
+	// LiveDrift is a change made out-of-band in the actual infra, i.e. between the prior and the live resource
+	LiveDrift DiffOrigin = "drift"
+	// ServerDefault is a field populated by the runtime, which exists in neither the prior nor the planed resource
+	// but only in the live resource, and will be kept
+	ServerDefault DiffOrigin = "default"
+)
+
+// DiffHunk is a labeled hunk of the three-way diff
+type DiffHunk struct {
+	Origin DiffOrigin
+	Diff   dyff.Diff
+}
+
+// DiffHunks computes a three-way diff among Attributes of Original, Modified and Current. Other fields of resources,
+// e.g. Type and Extensions, are Kusion settings which don't exist in the actual infra, so they are never diffed.
+// Without the prior or live resource, it falls back to a two-way diff between Current and Modified
+// and all hunks are regarded as ConfigChange
+func (cs *ChangeStep) DiffHunks() ([]DiffHunk, error) {
+	if isNil(cs.Original) || isNil(cs.Current) {
+		report, err := diffToReport(cs.Current, cs.Modified)
+		if err != nil {
+			return nil, err
+		}
+		return newDiffHunks(ConfigChange, report.Diffs), nil
+	}
+
+	original, modified, current := attributesOf(cs.Original), attributesOf(cs.Modified), attributesOf(cs.Current)
+	configReport, err := diffToReport(original, modified)
+	if err != nil {
+		return nil, err
+	}
+	liveReport, err := diffToReport(original, current)
+	if err != nil {
+		return nil, err
+	}
+
+	// fields added in the configuration, which are missing in the prior resource and exist in the planed resource
+	planned := map[string]bool{}
+	for _, d := range configReport.Diffs {
+		for _, detail := range d.Details {
+			if detail.Kind == dyff.ADDITION && detail.To != nil && detail.To.Kind == yamlv3.MappingNode {
+				for i := 0; i < len(detail.To.Content); i += 2 {
+					planned[fieldPath(d, detail.To.Content[i])] = true
+				}
+			}
+		}
+	}
+
+	hunks := newDiffHunks(ConfigChange, configReport.Diffs)
+	for _, d := range liveReport.Diffs {
+		// fields missing in both the prior and the planed resource are populated by the runtime. Others, including
+		// entries added to lists and fields also added in the configuration, are modified out-of-band
+		var drift, defaults []dyff.Detail
+		for _, detail := range d.Details {
+			if detail.Kind != dyff.ADDITION || detail.To == nil || detail.To.Kind != yamlv3.MappingNode {
+				drift = append(drift, detail)
+				continue
+			}
+			var driftFields, defaultFields []*yamlv3.Node
+			for i := 0; i < len(detail.To.Content); i += 2 {
+				field := detail.To.Content[i : i+2]
+				if planned[fieldPath(d, field[0])] {
+					driftFields = append(driftFields, field...)
+				} else {
+					defaultFields = append(defaultFields, field...)
+				}
+			}
+			if len(driftFields) > 0 {
+				drift = append(drift, additionOf(detail, driftFields))
+			}
+			if len(defaultFields) > 0 {
+				defaults = append(defaults, additionOf(detail, defaultFields))
+			}
+		}
+		if len(drift) > 0 {
+			hunks = append(hunks, DiffHunk{Origin: LiveDrift, Diff: dyff.Diff{Path: d.Path, Details: drift}})
+		}
+		if len(defaults) > 0 {
+			hunks = append(hunks, DiffHunk{Origin: ServerDefault, Diff: dyff.Diff{Path: d.Path, Details: defaults}})
+		}
+	}
+	return hunks, nil
+}
+
+// attributesOf returns Attributes of the resource, or v itself if it is not a resource
+func attributesOf(v interface{}) interface{} {
+	switch r := v.(type) {
+	case *models.Resource:
+		return r.Attributes
+	case models.Resource:
+		return r.Attributes
+	default:
+		return v
+	}
+}
+
+// fieldPath returns the path of a field added to the mapping of the diff
+func fieldPath(d dyff.Diff, key *yamlv3.Node) string {
+	return d.Path.ToDotStyle() + "." + key.Value
+}
+
+// additionOf returns an addition detail with part of fields added by the detail
+func additionOf(detail dyff.Detail, fields []*yamlv3.Node) dyff.Detail {
+	return dyff.Detail{
+		Kind: dyff.ADDITION,
+		To:   &yamlv3.Node{Kind: detail.To.Kind, Tag: detail.To.Tag, Content: fields},
+	}
+}
+
+func newDiffHunks(origin DiffOrigin, diffs []dyff.Diff) []DiffHunk {
+	hunks := make([]DiffHunk, 0, len(diffs))
+	for _, d := range diffs {
+		hunks = append(hunks, DiffHunk{Origin: origin, Diff: d})
+	}
+	return hunks
+}
+
+// hunksToString renders hunks with their origins. Server defaults are usually noisy, so only their fields are listed
+func hunksToString(hunks []DiffHunk) (string, error) {
+	buf := bytes.NewBufferString("")
+	for _, h := range hunks {
+		switch h.Origin {
+		case ServerDefault:
+			buf.WriteString(pretty.Gray("\n[%s] %s: %s\n", h.Origin, h.Diff.Path.ToDotStyle(), addedFields(h.Diff)))
+		default:
+			reportString, err := diff.ToReportString(dyff.Report{Diffs: []dyff.Diff{h.Diff}})
+			if err != nil {
+				return "", err
+			}
+			label := pretty.BlueBold("[%s] ", h.Origin)
+			if h.Origin == LiveDrift {
+				label = pretty.YellowBold("[%s] ", h.Origin)
+			}
+			buf.WriteString("\n" + label + strings.TrimLeft(reportString, "\n"))
+		}
+	}
+	return buf.String(), nil
+}
+
+// addedFields lists keys or values added in the diff
+func addedFields(d dyff.Diff) string {
+	var fields []string
+	for _, detail := range d.Details {
+		if detail.To == nil {
+			continue
+		}
+		switch detail.To.Kind {
+		case yamlv3.MappingNode:
+			for i := 0; i < len(detail.To.Content); i += 2 {
+				fields = append(fields, detail.To.Content[i].Value)
+			}
+		case yamlv3.SequenceNode:
+			fields = append(fields, fmt.Sprintf("%d list entries", len(detail.To.Content)))
+		default:
+			fields = append(fields, detail.To.Value)
+		}
+	}
+	return strings.Join(fields, ", ")
+}
+
+// isNil reports whether v is nil or a nil pointer, since resources are passed in as interfaces
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
 func NewChangeStep(id string, op types.ActionType, original, modified, current interface{}) *ChangeStep {
 	return &ChangeStep{
 		ID:       id,
@@ -82,6 +264,8 @@ var (
 	UpdateChangeStepFilter   = func(c *ChangeStep) bool { return c.Action == types.Update }
 	DeleteChangeStepFilter   = func(c *ChangeStep) bool { return c.Action == types.Delete }
 	UnChangeChangeStepFilter = func(c *ChangeStep) bool { return c.Action == types.UnChange }
+	ReplaceChangeStepFilter  = func(c *ChangeStep) bool { return c.Action.IsReplace() }
+	RetainChangeStepFilter   = func(c *ChangeStep) bool { return c.Action == types.Retain }
 )
 
 type Changes struct {
@@ -91,8 +275,8 @@ type Changes struct {
 }
 
 type ChangeOrder struct {
-	StepKeys    []string
-	ChangeSteps map[string]*ChangeStep
+	StepKeys    []string               `json:"stepKeys" yaml:"stepKeys"`
+	ChangeSteps map[string]*ChangeStep `json:"changeSteps" yaml:"changeSteps"`
 }
 
 func NewChanges(p *projectstack.Project, s *projectstack.Stack, order *ChangeOrder) *Changes {
@@ -180,6 +364,45 @@ func (p *Changes) Summary() {
 	pterm.Println() // Blank line
 }
 
+// InterruptedSummary prints the result of every resource after the operation is interrupted, e.g. by users or timeouts.
+// results are OpResults received from the operation, and resources without a result have never been started
+func (p *Changes) InterruptedSummary(operation string, results map[string]OpResult) {
+	tableHeader := []string{fmt.Sprintf("Stack: %s", p.stack.Name), "ID", "Action", "Result"}
+	tableData := pterm.TableData{tableHeader}
+
+	var completed, failed, notStarted int
+	for i, step := range p.Values() {
+		itemPrefix := " * ├─"
+		if i == len(p.StepKeys)-1 {
+			itemPrefix = " * └─"
+		}
+
+		var result string
+		switch results[step.ID] {
+		case Success, Skip:
+			result = "Completed"
+			completed++
+		case Failed:
+			result = pretty.Red("Failed")
+			failed++
+		default:
+			result = pretty.Gray("Not started")
+			notStarted++
+		}
+		tableData = append(tableData, []string{itemPrefix, step.ID, step.Action.String(), result})
+	}
+
+	pterm.DefaultTable.WithHasHeader().
+		WithHeaderStyle(&pterm.ThemeDefault.TableHeaderStyle).
+		WithLeftAlignment(true).
+		WithSeparator("  ").
+		WithData(tableData).
+		Render()
+	pterm.Println() // Blank line
+	pterm.Printf("%s interrupted! Resources: %d completed, %d failed, %d not started. State of completed resources is saved.\n",
+		operation, completed, failed, notStarted)
+}
+
 func (o *ChangeOrder) PromptDetails() (string, error) {
 	// Prepare the selects
 	options := []string{"all"}
@@ -17,6 +17,7 @@ var (
 	TestChangeStepOpDelete   = NewChangeStep("id", types.Delete, nil, nil, nil)
 	TestChangeStepOpUpdate   = NewChangeStep("id", types.Update, nil, nil, nil)
 	TestChangeStepOpUnChange = NewChangeStep("id", types.UnChange, nil, nil, nil)
+	TestChangeStepOpReplace  = NewChangeStep("id", types.Replace, nil, nil, nil)
 	TestStepKeys             = []string{"test-key-1", "test-key-2", "test-key-3", "test-key-4"}
 	TestChangeSteps          = map[string]*ChangeStep{
 		"test-key-1": TestChangeStepOpCreate,
@@ -24,6 +25,8 @@ var (
 		"test-key-3": TestChangeStepOpUpdate,
 		"test-key-4": TestChangeStepOpUnChange,
 	}
+
+	TestChangeStepOpReplaceCreateBeforeDelete = NewChangeStep("id", types.ReplaceCreateBeforeDelete, nil, nil, nil)
 )
 
 func TestOpType_Ing(t *testing.T) {
@@ -52,6 +55,21 @@ func TestOpType_Ing(t *testing.T) {
 			op:   types.UnChange,
 			want: "Unchanged",
 		},
+		{
+			name: "t5",
+			op:   types.Replace,
+			want: "Replacing",
+		},
+		{
+			name: "t6",
+			op:   types.ReplaceCreateBeforeDelete,
+			want: "Replacing",
+		},
+		{
+			name: "t7",
+			op:   types.Retain,
+			want: "Retaining",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -88,6 +106,21 @@ func TestOpType_PrettyString(t *testing.T) {
 			op:   types.UnChange,
 			want: pretty.Gray(types.UnChange.Ing()),
 		},
+		{
+			name: "t5",
+			op:   types.Replace,
+			want: pretty.Magenta("Replacing (delete then create)"),
+		},
+		{
+			name: "t6",
+			op:   types.ReplaceCreateBeforeDelete,
+			want: pretty.Magenta("Replacing (create before delete)"),
+		},
+		{
+			name: "t7",
+			op:   types.Retain,
+			want: pretty.Yellow(types.Retain.Ing()),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -147,6 +180,102 @@ func TestChangeStep_Diff(t *testing.T) {
 	}
 }
 
+func TestChangeStep_DiffHunks(t *testing.T) {
+	resource := func(replicas int, image string, status map[string]interface{}) *models.Resource {
+		attributes := map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicas": replicas,
+				"image":    image,
+			},
+		}
+		if status != nil {
+			attributes["status"] = status
+		}
+		return &models.Resource{ID: "id", Attributes: attributes}
+	}
+	prior := resource(1, "nginx:1.20", nil)
+
+	tests := []struct {
+		name     string
+		original *models.Resource
+		modified *models.Resource
+		current  *models.Resource
+		want     map[DiffOrigin]bool
+	}{
+		{
+			name:     "create",
+			original: nil,
+			modified: prior,
+			current:  nil,
+			want:     map[DiffOrigin]bool{ConfigChange: true},
+		},
+		{
+			name:     "config-drift-default",
+			original: prior,
+			modified: resource(2, "nginx:1.20", nil),
+			current:  resource(1, "nginx:1.21", map[string]interface{}{"phase": "Running"}),
+			want:     map[DiffOrigin]bool{ConfigChange: true, LiveDrift: true, ServerDefault: true},
+		},
+		{
+			name:     "only-default",
+			original: prior,
+			modified: prior,
+			current:  resource(1, "nginx:1.20", map[string]interface{}{"phase": "Running"}),
+			want:     map[DiffOrigin]bool{ServerDefault: true},
+		},
+		{
+			name: "kusion-settings-not-in-live",
+			original: &models.Resource{
+				ID:         "id",
+				Type:       models.Kubernetes,
+				Attributes: prior.Attributes,
+				Extensions: map[string]interface{}{models.PreventDestroyExtension: true},
+			},
+			modified: &models.Resource{
+				ID:         "id",
+				Type:       models.Kubernetes,
+				Attributes: prior.Attributes,
+				Extensions: map[string]interface{}{models.PreventDestroyExtension: true},
+			},
+			current: prior,
+			want:    map[DiffOrigin]bool{},
+		},
+		{
+			name:     "field-added-in-config-and-live",
+			original: prior,
+			modified: resource(1, "nginx:1.20", map[string]interface{}{"phase": "Running"}),
+			current:  resource(1, "nginx:1.20", map[string]interface{}{"phase": "Pending"}),
+			want:     map[DiffOrigin]bool{ConfigChange: true, LiveDrift: true},
+		},
+		{
+			name:     "field-added-to-prior-list",
+			original: &models.Resource{ID: "id", Attributes: map[string]interface{}{"args": []interface{}{"a"}}},
+			modified: &models.Resource{ID: "id", Attributes: map[string]interface{}{"args": []interface{}{"a"}}},
+			current:  &models.Resource{ID: "id", Attributes: map[string]interface{}{"args": []interface{}{"a", "b"}}},
+			want:     map[DiffOrigin]bool{LiveDrift: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := NewChangeStep("id", types.Update, tt.original, tt.modified, tt.current)
+			hunks, err := cs.DiffHunks()
+			if err != nil {
+				t.Fatalf("ChangeStep.DiffHunks() error = %v", err)
+			}
+			got := map[DiffOrigin]bool{}
+			for _, h := range hunks {
+				got[h.Origin] = true
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChangeStep.DiffHunks() origins = %v, want %v", got, tt.want)
+			}
+			if _, err = cs.Diff(); err != nil {
+				t.Errorf("ChangeStep.Diff() error = %v", err)
+			}
+		})
+	}
+}
+
 func TestChanges_Get(t *testing.T) {
 	type fields struct {
 		order   *ChangeOrder
@@ -258,6 +387,23 @@ func TestChanges_Values(t *testing.T) {
 			},
 			want: []*ChangeStep{TestChangeStepOpUnChange},
 		},
+		{
+			name: "filter-opreplace",
+			fields: fields{
+				order: &ChangeOrder{
+					StepKeys: []string{"test-key-1", "test-key-2", "test-key-3"},
+					ChangeSteps: map[string]*ChangeStep{
+						"test-key-1": TestChangeStepOpUpdate,
+						"test-key-2": TestChangeStepOpReplace,
+						"test-key-3": TestChangeStepOpReplaceCreateBeforeDelete,
+					},
+				},
+			},
+			args: args{
+				filters: []ChangeStepFilterFunc{ReplaceChangeStepFilter},
+			},
+			want: []*ChangeStep{TestChangeStepOpReplace, TestChangeStepOpReplaceCreateBeforeDelete},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -440,6 +586,25 @@ func TestChanges_Preview(t *testing.T) {
 	}
 }
 
+func TestChanges_InterruptedSummary(t *testing.T) {
+	p := &Changes{
+		ChangeOrder: &ChangeOrder{
+			StepKeys: []string{"completed", "failed", "not-started"},
+			ChangeSteps: map[string]*ChangeStep{
+				"completed":   {ID: "completed", Action: types.Create},
+				"failed":      {ID: "failed", Action: types.Update},
+				"not-started": {ID: "not-started", Action: types.Delete},
+			},
+		},
+		stack: &projectstack.Stack{
+			StackConfiguration: projectstack.StackConfiguration{
+				Name: "test-name",
+			},
+		},
+	}
+	p.InterruptedSummary("Apply", map[string]OpResult{"completed": Success, "failed": Failed})
+}
+
 func Test_buildResourceStateMap(t *testing.T) {
 	type args struct {
 		rs []*models.Resource
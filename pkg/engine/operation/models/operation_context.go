@@ -3,6 +3,7 @@ package models
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"kusionstack.io/kusion/pkg/engine/operation/types"
 
@@ -14,6 +15,7 @@ import (
 
 	"kusionstack.io/kusion/pkg/engine/states"
 	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
 	"kusionstack.io/kusion/pkg/util"
 )
 
@@ -25,6 +27,10 @@ type Operation struct {
 	// StateStorage represents the storage where state will be saved during this operation
 	StateStorage states.StateStorage
 
+	// StackStorage locates the StateStorage of other stacks referenced by stack references. Nil means the
+	// StateStorage is shared by all stacks, and it queries states of other stacks by their projects and stack names
+	StackStorage StackStorageFunc
+
 	// CtxResourceIndex represents resources updated by this operation
 	CtxResourceIndex map[string]*models.Resource
 
@@ -37,8 +43,12 @@ type Operation struct {
 	// ChangeOrder is resources' change order during this operation
 	ChangeOrder *ChangeOrder
 
-	// Runtime is the resource infrastructure runtime of this operation
-	Runtime runtime.Runtime
+	// PlannedOrder is the change order of a saved plan. If it is set, apply fails on resources whose actions are
+	// different from those in the plan, so that only reviewed changes are applied
+	PlannedOrder *ChangeOrder
+
+	// RuntimeMap contains Runtimes of this operation, and every resource will be dispatched to the Runtime of its Type
+	RuntimeMap map[models.Type]runtime.Runtime
 
 	// MsgCh is used to send operation status like Success, Failed or Skip to Kusion CTl,
 	// and this message will be displayed in the terminal
@@ -47,14 +57,46 @@ type Operation struct {
 	// Lock is the operation-wide mutex
 	Lock *sync.Mutex
 
+	// StopCh is closed to stop the operation gracefully, e.g. when it is interrupted by users. Unlike canceling the ctx
+	// of the operation, nodes not started are skipped while in-flight runtime calls are not canceled, so that their
+	// results are saved in the state. Nil means the operation is only stopped by its ctx
+	StopCh <-chan struct{}
+
 	// ResultState is the final State build by this operation, and this State will be saved in the StateStorage
 	ResultState *states.State
+
+	// WatchTimeout is the max duration to wait for an applied resource to be ready. Zero means the default timeout
+	WatchTimeout time.Duration
+
+	// ResourceTimeout is the max duration to execute each resource, including reading, applying and waiting for it
+	// to be ready. Zero means no timeout
+	ResourceTimeout time.Duration
+
+	// Parallelism is the max number of resources executed concurrently. Zero means no limit
+	Parallelism int
+
+	// RuntimeParallelism is the max number of resources executed concurrently per Runtime, keyed by the Type of
+	// resources. It works together with Parallelism, and Runtimes absent or with zero values are not limited
+	RuntimeParallelism map[models.Type]int
+
+	// DryRun means resources are sent to runtimes as dry-run requests, and no state will be saved
+	DryRun bool
+
+	// ServerSideApply means resources should be applied by server-side apply if their runtimes support it
+	ServerSideApply bool
+
+	// ForceConflicts means taking the ownership of conflicting fields when ServerSideApply is enabled
+	ForceConflicts bool
 }
 
+// StackStorageFunc returns the StateStorage of the stack in the project
+type StackStorageFunc func(project, stack string) (states.StateStorage, error)
+
 type Message struct {
 	ResourceID string   // ResourceNode.ID()
 	OpResult   OpResult // Success/Failed/Skip
 	OpErr      error    // Operate error detail
+	Log        string   // A line of logs printed during the execution, e.g. the output of hooks
 }
 
 type Request struct {
@@ -63,6 +105,18 @@ type Request struct {
 	Project  string       `json:"project"`
 	Operator string       `json:"operator"`
 	Spec     *models.Spec `json:"spec"`
+
+	// Targets and Excludes are resource IDs or glob patterns to select resources executed in this operation.
+	// Empty means all resources are executed
+	Targets  []string `json:"targets,omitempty"`
+	Excludes []string `json:"excludes,omitempty"`
+
+	// Hooks are hooks of the stack executed during apply, besides hooks declared in Extensions of resources
+	Hooks []*models.Hook `json:"hooks,omitempty"`
+
+	// DisableKindOrder turns off the default order of Kubernetes resources by their kinds, so that resources are
+	// only ordered by dependsOn and implicit references
+	DisableKindOrder bool `json:"disableKindOrder,omitempty"`
 }
 
 type OpResult string
@@ -74,24 +128,38 @@ const (
 	Skip    OpResult = "Skip"
 )
 
-// RefreshResourceIndex refresh resources in CtxResourceIndex & StateResourceIndex
+// RefreshResourceIndex refresh resources in CtxResourceIndex & StateResourceIndex by the action of the resource
 func (o *Operation) RefreshResourceIndex(resourceKey string, resource *models.Resource, actionType types.ActionType) error {
 	o.Lock.Lock()
 	defer o.Lock.Unlock()
 
 	switch actionType {
-	case types.Delete:
+	case types.Delete, types.Retain:
 		o.CtxResourceIndex[resourceKey] = nil
 		o.StateResourceIndex[resourceKey] = nil
-	case types.Create, types.Update:
+	case types.Create, types.Update, types.Replace, types.ReplaceCreateBeforeDelete:
 		o.CtxResourceIndex[resourceKey] = resource
 		o.StateResourceIndex[resourceKey] = resource
+	case types.UnChange:
+		// unchanged resources are kept as they are in the state, and only recorded for references
+		o.CtxResourceIndex[resourceKey] = resource
 	default:
 		panic("unsupported actionType:" + actionType.Ing())
 	}
 	return nil
 }
 
+// GetRuntime returns the Runtime which manages the resource
+func (o *Operation) GetRuntime(resource *models.Resource) (runtime.Runtime, status.Status) {
+	t := resource.RuntimeType()
+	r := o.RuntimeMap[t]
+	if r == nil {
+		msg := fmt.Sprintf("can't find runtime of type:%s for resource:%s", t, resource.ResourceKey())
+		return nil, status.NewErrorStatusWithMsg(status.Unimplemented, msg)
+	}
+	return r, nil
+}
+
 func (o *Operation) InitStates(request *Request) (*states.State, *states.State) {
 	latestState, err := o.StateStorage.GetLatestState(
 		&states.StateQuery{
@@ -110,6 +178,9 @@ func (o *Operation) InitStates(request *Request) (*states.State, *states.State)
 	err = copier.Copy(resultState, request)
 	util.CheckNotError(err, "Copy request to ResultState, request")
 	resultState.Resources = nil
+	// outputs are only evaluated by apply, so other operations keep the latest ones
+	resultState.Outputs = latestState.Outputs
+	resultState.OutputDefinitions = latestState.OutputDefinitions
 
 	return latestState, resultState
 }
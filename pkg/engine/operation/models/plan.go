@@ -0,0 +1,148 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/version"
+)
+
+// PlanVersion is the version of the format of saved plans. Plans of other versions are refused
+const PlanVersion = 1
+
+// Plan is a saved preview of a stack, which is applied exactly as it is previewed. It records the state it is
+// computed against, and is regarded as stale once the state is changed
+type Plan struct {
+	// Version is the format version of this plan
+	Version int `json:"version" yaml:"version"`
+	// KusionVersion represents the Kusion's version when this plan is created
+	KusionVersion string `json:"kusionVersion" yaml:"kusionVersion"`
+
+	Tenant  string `json:"tenant,omitempty" yaml:"tenant,omitempty"`
+	Project string `json:"project" yaml:"project"`
+	Stack   string `json:"stack" yaml:"stack"`
+
+	// Spec is the compiled Spec this plan is computed by, which is applied without compiling KCL again
+	Spec *models.Spec `json:"spec" yaml:"spec"`
+	// ChangeOrder is the previewed change of every resource, and applying fails if any action is changed
+	ChangeOrder *ChangeOrder `json:"changeOrder" yaml:"changeOrder"`
+
+	// Targets, Excludes, Hooks and DisableKindOrder are those of the previewed request
+	Targets          []string       `json:"targets,omitempty" yaml:"targets,omitempty"`
+	Excludes         []string       `json:"excludes,omitempty" yaml:"excludes,omitempty"`
+	Hooks            []*models.Hook `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+	DisableKindOrder bool           `json:"disableKindOrder,omitempty" yaml:"disableKindOrder,omitempty"`
+
+	// StateSerial and StateDigest identify the state this plan is computed against
+	StateSerial uint64 `json:"stateSerial" yaml:"stateSerial"`
+	StateDigest string `json:"stateDigest" yaml:"stateDigest"`
+
+	// CreateTime is the time this plan is created
+	CreateTime time.Time `json:"createTime" yaml:"createTime"`
+}
+
+// NewPlan creates a plan of the previewed request and changes against the state, which is nil if the stack is never applied
+func NewPlan(request *Request, order *ChangeOrder, state *states.State) (*Plan, error) {
+	digest, err := StateDigest(state)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{
+		Version:          PlanVersion,
+		KusionVersion:    version.ReleaseVersion(),
+		Tenant:           request.Tenant,
+		Project:          request.Project,
+		Stack:            request.Stack,
+		Spec:             request.Spec,
+		ChangeOrder:      order,
+		Targets:          request.Targets,
+		Excludes:         request.Excludes,
+		Hooks:            request.Hooks,
+		DisableKindOrder: request.DisableKindOrder,
+		StateDigest:      digest,
+		CreateTime:       time.Now(),
+	}
+	if state != nil {
+		plan.StateSerial = state.Serial
+	}
+	return plan, nil
+}
+
+// StateDigest returns the SHA-256 digest of the content of the state, and empty if the state is nil
+func StateDigest(state *states.State) (string, error) {
+	if state == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(struct {
+		Serial    uint64                 `json:"serial"`
+		Resources models.Resources       `json:"resources"`
+		Outputs   map[string]interface{} `json:"outputs,omitempty"`
+	}{state.Serial, state.Resources, state.Outputs})
+	if err != nil {
+		return "", fmt.Errorf("failed to compute the digest of the state. %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Check makes sure this plan is for the stack and the latest state is the one this plan is computed against
+func (p *Plan) Check(project, stack string, latest *states.State) error {
+	if p.Version != PlanVersion {
+		return fmt.Errorf("unsupported plan version:%d, expected %d. Please preview again", p.Version, PlanVersion)
+	}
+	if p.Project != project || p.Stack != stack {
+		return fmt.Errorf("the plan is for stack:%s/%s, but current stack is %s/%s", p.Project, p.Stack, project, stack)
+	}
+	if p.Spec == nil || p.ChangeOrder == nil {
+		return fmt.Errorf("the plan has no spec or changes")
+	}
+
+	var serial uint64
+	if latest != nil {
+		serial = latest.Serial
+	}
+	if serial != p.StateSerial {
+		return fmt.Errorf("the state serial has moved from %d to %d since the plan was created. Please preview again",
+			p.StateSerial, serial)
+	}
+	digest, err := StateDigest(latest)
+	if err != nil {
+		return err
+	}
+	if digest != p.StateDigest {
+		return fmt.Errorf("the state has been modified since the plan was created, so the plan is stale. Please preview again")
+	}
+	return nil
+}
+
+// Save writes this plan as JSON into the file
+func (p *Plan) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0o644)
+}
+
+// LoadPlan reads a plan saved in the file
+func LoadPlan(path string) (*Plan, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{}
+	// JSON is a subset of YAML, and go-yaml keeps the types of numbers in attributes, while the Go JSON library
+	// decodes all of them as float64
+	if err = yaml.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file %s. %v", path, err)
+	}
+	return plan, nil
+}
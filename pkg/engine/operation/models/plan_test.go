@@ -0,0 +1,143 @@
+package models
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+	"kusionstack.io/kusion/pkg/engine/states"
+)
+
+var (
+	planResource = models.Resource{
+		ID:         "apps/v1:Deployment:default:nginx",
+		Type:       "Kubernetes",
+		Attributes: map[string]interface{}{"spec": map[string]interface{}{"replicas": 3}},
+	}
+	planState = &states.State{
+		Project:   "demo",
+		Stack:     "dev",
+		Serial:    2,
+		Resources: models.Resources{planResource},
+	}
+)
+
+func newTestPlan(t *testing.T, state *states.State) *Plan {
+	plan, err := NewPlan(&Request{
+		Project:  "demo",
+		Stack:    "dev",
+		Spec:     &models.Spec{Resources: models.Resources{planResource}},
+		Excludes: []string{"v1:Namespace:default"},
+	}, &ChangeOrder{
+		StepKeys: []string{planResource.ID},
+		ChangeSteps: map[string]*ChangeStep{
+			planResource.ID: NewChangeStep(planResource.ID, types.Update, &planResource, &planResource, nil),
+		},
+	}, state)
+	assert.NoError(t, err)
+	return plan
+}
+
+func TestPlan_SaveAndLoad(t *testing.T) {
+	plan := newTestPlan(t, planState)
+	path := filepath.Join(t.TempDir(), "plan.json")
+	assert.NoError(t, plan.Save(path))
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	// actions are saved by their names
+	assert.Contains(t, string(data), `"action": "Update"`)
+
+	got, err := LoadPlan(path)
+	assert.NoError(t, err)
+	assert.Equal(t, PlanVersion, got.Version)
+	assert.Equal(t, uint64(2), got.StateSerial)
+	assert.Equal(t, plan.StateDigest, got.StateDigest)
+	assert.Equal(t, plan.Excludes, got.Excludes)
+	assert.Equal(t, plan.Spec, got.Spec)
+	assert.Equal(t, plan.ChangeOrder.StepKeys, got.ChangeOrder.StepKeys)
+	assert.Equal(t, types.Update, got.ChangeOrder.Get(planResource.ID).Action)
+	assert.NoError(t, got.Check("demo", "dev", planState))
+
+	_, err = LoadPlan(filepath.Join(t.TempDir(), "not-exist.json"))
+	assert.Error(t, err)
+}
+
+func TestPlan_Check(t *testing.T) {
+	modified := *planState
+	modified.Resources = models.Resources{{ID: planResource.ID, Type: planResource.Type}}
+	moved := *planState
+	moved.Serial = 3
+
+	tests := []struct {
+		name    string
+		plan    *Plan
+		stack   string
+		latest  *states.State
+		wantErr string
+	}{
+		{
+			name:   "same state",
+			plan:   newTestPlan(t, planState),
+			stack:  "dev",
+			latest: planState,
+		},
+		{
+			name:  "never applied",
+			plan:  newTestPlan(t, nil),
+			stack: "dev",
+		},
+		{
+			name:    "serial moved",
+			plan:    newTestPlan(t, planState),
+			stack:   "dev",
+			latest:  &moved,
+			wantErr: "the state serial has moved from 2 to 3 since the plan was created. Please preview again",
+		},
+		{
+			name:    "applied after the plan of a new stack",
+			plan:    newTestPlan(t, nil),
+			stack:   "dev",
+			latest:  planState,
+			wantErr: "the state serial has moved from 0 to 2 since the plan was created. Please preview again",
+		},
+		{
+			name:    "state modified",
+			plan:    newTestPlan(t, planState),
+			stack:   "dev",
+			latest:  &modified,
+			wantErr: "the state has been modified since the plan was created, so the plan is stale. Please preview again",
+		},
+		{
+			name:    "other stack",
+			plan:    newTestPlan(t, planState),
+			stack:   "prod",
+			latest:  planState,
+			wantErr: "the plan is for stack:demo/dev, but current stack is demo/prod",
+		},
+		{
+			name: "other version",
+			plan: func() *Plan {
+				plan := newTestPlan(t, planState)
+				plan.Version = PlanVersion + 1
+				return plan
+			}(),
+			stack:   "dev",
+			latest:  planState,
+			wantErr: "unsupported plan version:2, expected 1. Please preview again",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.plan.Check("demo", tt.stack, tt.latest)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// updateOutputs evaluates outputs of the stack against live resources and saves them in the state. Resources referred
+// by outputs are read by their Runtimes, since fields assigned by the server, e.g. the clusterIP of a Service, are
+// missing in the applied attributes. Resources applied in this operation take precedence, and other resources, e.g.
+// unchanged ones or ones pruned by targets, are located by the state
+func (ao *ApplyOperation) updateOutputs(ctx context.Context, outputs map[string]interface{}) status.Status {
+	o := &ao.Operation
+	if o.DryRun || (len(outputs) == 0 && len(o.ResultState.Outputs) == 0 && len(o.ResultState.OutputDefinitions) == 0) {
+		return nil
+	}
+
+	o.Lock.Lock()
+	resourceIndex := make(map[string]*models.Resource, len(o.StateResourceIndex))
+	for key, res := range o.StateResourceIndex {
+		if res != nil {
+			resourceIndex[key] = res
+		}
+	}
+	for key, res := range o.CtxResourceIndex {
+		if res != nil {
+			resourceIndex[key] = res
+		}
+	}
+	o.Lock.Unlock()
+
+	liveIndex, s := ao.readOutputResources(ctx, outputs, resourceIndex)
+	if status.IsErr(s) {
+		return s
+	}
+	evaluated, s := evaluateOutputs(outputs, liveIndex)
+	if status.IsErr(s) {
+		return s
+	}
+
+	o.Lock.Lock()
+	o.ResultState.Outputs = evaluated
+	o.ResultState.OutputDefinitions = outputs
+	o.Lock.Unlock()
+	if err := o.UpdateState(o.StateResourceIndex); err != nil {
+		return status.NewErrorStatus(err)
+	}
+	log.Infof("update %d outputs", len(evaluated))
+	return nil
+}
+
+// readOutputResources reads resources referred by outputs from the actual infra, and returns a copy of the
+// resourceIndex with them replaced by live ones. Resources not found in the actual infra are left as they are
+func (ao *ApplyOperation) readOutputResources(ctx context.Context, outputs map[string]interface{},
+	resourceIndex map[string]*models.Resource,
+) (map[string]*models.Resource, status.Status) {
+	liveIndex := make(map[string]*models.Resource, len(resourceIndex))
+	for key, res := range resourceIndex {
+		liveIndex[key] = res
+	}
+	if len(outputs) == 0 {
+		return liveIndex, nil
+	}
+
+	// only collect keys of referred resources here, and refs are resolved by evaluateOutputs
+	keys, _, s := graph.ParseImplicitRef(reflect.ValueOf(outputs), resourceIndex,
+		func(_ map[string]*models.Resource, refPath string) (reflect.Value, status.Status) {
+			return reflect.ValueOf(refPath), nil
+		})
+	if status.IsErr(s) {
+		// illegal refs are reported with names of outputs by evaluateOutputs
+		return liveIndex, nil
+	}
+	read := map[string]bool{}
+	for _, key := range keys {
+		res := resourceIndex[key]
+		if res == nil || read[key] {
+			continue
+		}
+		read[key] = true
+
+		rt, s := ao.GetRuntime(res)
+		if status.IsErr(s) {
+			return nil, s
+		}
+		response := rt.Read(ctx, &runtime.ReadRequest{Resource: res})
+		if status.IsErr(response.Status) {
+			return nil, status.NewErrorStatusWithMsg(response.Status.Code(),
+				fmt.Sprintf("failed to read resource:%s of outputs. %s", key, response.Status.Message()))
+		}
+		if response.Resource != nil {
+			liveIndex[key] = response.Resource
+		}
+	}
+	return liveIndex, nil
+}
+
+// evaluateOutputs replaces implicit references in outputs with attributes of resources in the resourceIndex
+func evaluateOutputs(outputs map[string]interface{}, resourceIndex map[string]*models.Resource) (map[string]interface{}, status.Status) {
+	if len(outputs) == 0 {
+		return nil, nil
+	}
+	evaluated := make(map[string]interface{}, len(outputs))
+	for name, value := range outputs {
+		if value == nil {
+			evaluated[name] = nil
+			continue
+		}
+		_, v, s := graph.ParseImplicitRef(reflect.ValueOf(value), resourceIndex, graph.ImplicitReplaceFun)
+		if status.IsErr(s) {
+			return nil, status.NewErrorStatusWithMsg(s.Code(), fmt.Sprintf("failed to evaluate output:%s. %s", name, s.Message()))
+		}
+		evaluated[name] = v.Interface()
+	}
+	return evaluated, nil
+}
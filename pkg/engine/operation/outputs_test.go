@@ -0,0 +1,69 @@
+package operation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func Test_evaluateOutputs(t *testing.T) {
+	resourceIndex := map[string]*models.Resource{
+		"v1:Service:default:nginx": {
+			ID: "v1:Service:default:nginx",
+			Attributes: map[string]interface{}{
+				"spec": map[string]interface{}{"clusterIP": "10.0.0.1", "ports": []interface{}{80}},
+			},
+		},
+	}
+	tests := []struct {
+		name     string
+		outputs  map[string]interface{}
+		want     map[string]interface{}
+		wantCode status.Code
+	}{
+		{
+			name: "no outputs",
+		},
+		{
+			name: "outputs",
+			outputs: map[string]interface{}{
+				"cluster_ip": graph.ImplicitRefPrefix + "v1:Service:default:nginx.spec.clusterIP",
+				"endpoint": map[string]interface{}{
+					"ports":   graph.ImplicitRefPrefix + "v1:Service:default:nginx.spec.ports",
+					"project": "nginx",
+				},
+				"empty": nil,
+			},
+			want: map[string]interface{}{
+				"cluster_ip": "10.0.0.1",
+				"endpoint": map[string]interface{}{
+					"ports":   []interface{}{80},
+					"project": "nginx",
+				},
+				"empty": nil,
+			},
+		},
+		{
+			name: "unknown resource",
+			outputs: map[string]interface{}{
+				"cluster_ip": graph.ImplicitRefPrefix + "v1:Service:default:redis.spec.clusterIP",
+			},
+			wantCode: status.IllegalManifest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, s := evaluateOutputs(tt.outputs, resourceIndex)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, s.Code())
+				return
+			}
+			assert.Nil(t, s)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
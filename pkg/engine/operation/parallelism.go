@@ -0,0 +1,84 @@
+package operation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform/dag"
+	"github.com/hashicorp/terraform/tfdiags"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+)
+
+// limiter bounds the number of nodes executed concurrently, both in total and per Runtime.
+// Nil channels mean no limit
+type limiter struct {
+	total    chan struct{}
+	runtimes map[models.Type]chan struct{}
+}
+
+func newLimiter(o *opsmodels.Operation) *limiter {
+	l := &limiter{runtimes: map[models.Type]chan struct{}{}}
+	if o.Parallelism > 0 {
+		l.total = make(chan struct{}, o.Parallelism)
+	}
+	for t, n := range o.RuntimeParallelism {
+		if n > 0 {
+			l.runtimes[t] = make(chan struct{}, n)
+		}
+	}
+	return l
+}
+
+// acquire blocks until a resource of the Runtime type is allowed to execute, or the ctx is done.
+// The Runtime slot is taken before the total one, so that resources waiting for a busy Runtime never
+// occupy slots that resources of other Runtimes could use
+func (l *limiter) acquire(ctx context.Context, t models.Type) (release func(), err error) {
+	var acquired []chan struct{}
+	release = func() {
+		for _, ch := range acquired {
+			<-ch
+		}
+	}
+	for _, ch := range []chan struct{}{l.runtimes[t], l.total} {
+		if ch == nil {
+			continue
+		}
+		select {
+		case ch <- struct{}{}:
+			acquired = append(acquired, ch)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// limitWalkFun wraps the walk callback of an operation, so that resource nodes and hook nodes running Jobs are executed
+// under the parallelism limits of the operation. Jobs are limited as resources of the Kubernetes Runtime, which runs them.
+// Other nodes are never limited
+func limitWalkFun(ctx context.Context, o *opsmodels.Operation, walkFun dag.WalkFunc) dag.WalkFunc {
+	l := newLimiter(o)
+	return func(v dag.Vertex) (diags tfdiags.Diagnostics) {
+		var resource *models.Resource
+		switch node := v.(type) {
+		case *graph.ResourceNode:
+			resource = node.State()
+		case *graph.HookNode:
+			// command hooks run locally, and JobResource is nil for them
+			resource = node.JobResource()
+		}
+		if resource == nil {
+			return walkFun(v)
+		}
+		release, err := l.acquire(ctx, resource.RuntimeType())
+		if err != nil {
+			return diags.Append(fmt.Errorf("skip resource:%s since the operation is canceled. %v", v.(dag.Hashable).Hashcode(), err))
+		}
+		defer release()
+		return walkFun(v)
+	}
+}
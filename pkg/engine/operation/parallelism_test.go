@@ -0,0 +1,137 @@
+package operation
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/dag"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+)
+
+func TestLimiter_acquire(t *testing.T) {
+	tests := []struct {
+		name               string
+		parallelism        int
+		runtimeParallelism map[models.Type]int
+		resourceType       models.Type
+		want               int32
+	}{
+		{
+			name:         "no limit",
+			resourceType: models.Kubernetes,
+			want:         8,
+		},
+		{
+			name:         "total limit",
+			parallelism:  3,
+			resourceType: models.Kubernetes,
+			want:         3,
+		},
+		{
+			name:               "runtime limit",
+			parallelism:        3,
+			runtimeParallelism: map[models.Type]int{models.Kubernetes: 2},
+			resourceType:       models.Kubernetes,
+			want:               2,
+		},
+		{
+			name:               "limit of other runtimes",
+			parallelism:        3,
+			runtimeParallelism: map[models.Type]int{fakeType: 1},
+			resourceType:       models.Kubernetes,
+			want:               3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLimiter(&opsmodels.Operation{Parallelism: tt.parallelism, RuntimeParallelism: tt.runtimeParallelism})
+
+			var running, max int32
+			start := make(chan struct{})
+			wg := sync.WaitGroup{}
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					release, err := l.acquire(context.Background(), tt.resourceType)
+					assert.Nil(t, err)
+					defer release()
+
+					n := atomic.AddInt32(&running, 1)
+					for {
+						m := atomic.LoadInt32(&max)
+						if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+							break
+						}
+					}
+					time.Sleep(20 * time.Millisecond)
+					atomic.AddInt32(&running, -1)
+				}()
+			}
+			close(start)
+			wg.Wait()
+			assert.Equal(t, tt.want, max)
+		})
+	}
+}
+
+func TestLimiter_acquireCanceled(t *testing.T) {
+	l := newLimiter(&opsmodels.Operation{Parallelism: 1})
+	release, err := l.acquire(context.Background(), models.Kubernetes)
+	assert.Nil(t, err)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = l.acquire(ctx, models.Kubernetes)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func Test_limitWalkFun(t *testing.T) {
+	newJobHook := func(name string) dag.Vertex {
+		hn, s := graph.NewHookNode(&models.Hook{
+			Name:  name,
+			Phase: models.PreApply,
+			Job:   map[string]interface{}{"apiVersion": "batch/v1", "kind": "Job"},
+		}, "")
+		assert.Nil(t, s)
+		return hn
+	}
+
+	var running, max int32
+	walkFun := limitWalkFun(context.Background(), &opsmodels.Operation{
+		RuntimeParallelism: map[models.Type]int{models.Kubernetes: 1},
+	}, func(v dag.Vertex) tfdiags.Diagnostics {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+
+	wg := sync.WaitGroup{}
+	for _, name := range []string{"migrate", "seed", "check"} {
+		wg.Add(1)
+		go func(v dag.Vertex) {
+			defer wg.Done()
+			assert.False(t, walkFun(v).HasErrors())
+		}(newJobHook(name))
+	}
+	wg.Wait()
+	// Jobs of hooks are limited as Kubernetes resources
+	assert.Equal(t, int32(1), max)
+}
@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform/dag"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util"
+)
+
+// HookParser adds hooks of the stack and resources into the graph as HookNodes. A preApply hook is executed after
+// resources its resources depend on and before its resources, and a postApply hook is executed after its resources
+// and before resources depending on them. Hooks are added for every resource to be applied, and skipped when they are
+// executed if all their resources are unchanged. It should be the last parser of the graph, so that hooks of resources
+// not selected are dropped together with their resources
+type HookParser struct {
+	// hooks are hooks of the stack
+	hooks []*models.Hook
+}
+
+func NewHookParser(hooks []*models.Hook) *HookParser {
+	return &HookParser{hooks: hooks}
+}
+
+func (h *HookParser) Parse(g *dag.AcyclicGraph) status.Status {
+	util.CheckNotNil(g, "graph is nil")
+	root, err := g.Root()
+	if err != nil {
+		return status.NewErrorStatus(err)
+	}
+
+	// hooks are only executed with resources to be applied, and they are ordered by edges of resources before adding any hooks
+	resourceNodes := map[string]*graph.ResourceNode{}
+	ups := map[string][]dag.Vertex{}
+	downs := map[string][]dag.Vertex{}
+	var ids []string
+	for _, v := range g.Vertices() {
+		rn, ok := v.(*graph.ResourceNode)
+		if !ok || rn.Action == types.Delete {
+			continue
+		}
+		id := rn.Hashcode().(string)
+		resourceNodes[id] = rn
+		ups[id] = g.UpEdges(v).List()
+		downs[id] = g.DownEdges(v).List()
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	addHook := func(hook *models.Hook, resourceID string, targets []string) status.Status {
+		node, s := graph.NewHookNode(hook, resourceID)
+		if status.IsErr(s) {
+			return s
+		}
+		if g.HasVertex(node) {
+			return status.NewErrorStatusWithMsg(status.InvalidArgument, fmt.Sprintf("duplicate hook:%s", node.Hashcode()))
+		}
+		g.Add(node)
+		resources := make([]*graph.ResourceNode, 0, len(targets))
+		for _, id := range targets {
+			resources = append(resources, resourceNodes[id])
+		}
+		node.SetResources(resources)
+		if len(targets) == 0 {
+			g.Connect(dag.BasicEdge(root, node))
+			return nil
+		}
+
+		isTarget := make(map[interface{}]bool, len(targets))
+		for _, id := range targets {
+			isTarget[id] = true
+		}
+		for _, id := range targets {
+			rn := resourceNodes[id]
+			switch hook.Phase {
+			case models.PreApply:
+				g.Connect(dag.BasicEdge(node, rn))
+				for _, up := range ups[id] {
+					if !isTarget[up.(dag.Hashable).Hashcode()] {
+						g.Connect(dag.BasicEdge(up, node))
+					}
+				}
+			case models.PostApply:
+				g.Connect(dag.BasicEdge(rn, node))
+				for _, down := range downs[id] {
+					if !isTarget[down.(dag.Hashable).Hashcode()] {
+						g.Connect(dag.BasicEdge(node, down))
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	// hooks of resources
+	for _, id := range ids {
+		hooks, err := resourceNodes[id].State().Hooks()
+		if err != nil {
+			return status.NewErrorStatusWithMsg(status.InvalidArgument, err.Error())
+		}
+		for _, hook := range hooks {
+			if s := addHook(hook, id, []string{id}); status.IsErr(s) {
+				return s
+			}
+		}
+	}
+
+	// hooks of the stack
+	for _, hook := range h.hooks {
+		targets := ids
+		if len(hook.Resources) != 0 {
+			targets = nil
+			for _, id := range hook.Resources {
+				if _, ok := resourceNodes[id]; ok {
+					targets = append(targets, id)
+				}
+			}
+			if len(targets) == 0 {
+				log.Infof("skip hook:%s since none of its resources is applied", hook.Name)
+				continue
+			}
+		}
+		if s := addHook(hook, "", targets); status.IsErr(s) {
+			return s
+		}
+	}
+
+	if err = g.Validate(); err != nil {
+		return status.NewErrorStatusWithMsg(status.IllegalManifest, "Found circle dependency between hooks and resources."+err.Error())
+	}
+	g.TransitiveReduction()
+	return nil
+}
@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/dag"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func TestHookParser_Parse(t *testing.T) {
+	newSpec := func(ponyHooks []interface{}) *models.Spec {
+		pony := models.Resource{ID: "pony", Attributes: map[string]interface{}{"c": "d"}, DependsOn: []string{"jack"}}
+		if ponyHooks != nil {
+			pony.Extensions = map[string]interface{}{models.HooksExtension: ponyHooks}
+		}
+		return &models.Spec{Resources: []models.Resource{
+			{ID: "jack", Attributes: map[string]interface{}{"a": "b"}},
+			pony,
+			{ID: "eric", Attributes: map[string]interface{}{"a": graph.ImplicitRefPrefix + "pony.c"}},
+			{ID: "tom", Attributes: map[string]interface{}{"e": "f"}},
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		ponyHooks []interface{}
+		hooks     []*models.Hook
+		want      string
+		wantCode  status.Code
+	}{
+		{
+			name: "pre-apply hook of resource",
+			ponyHooks: []interface{}{
+				map[string]interface{}{"name": "migrate", "phase": "preApply", "command": "make migrate"},
+			},
+			want: `
+eric
+hook:preApply:migrate@pony
+  pony
+jack
+  hook:preApply:migrate@pony
+pony
+  eric
+root
+  jack
+  tom
+tom
+`,
+		},
+		{
+			name: "post-apply hook of resource",
+			ponyHooks: []interface{}{
+				map[string]interface{}{"name": "smoke", "phase": "postApply", "command": "make test"},
+			},
+			want: `
+eric
+hook:postApply:smoke@pony
+  eric
+jack
+  pony
+pony
+  hook:postApply:smoke@pony
+root
+  jack
+  tom
+tom
+`,
+		},
+		{
+			name:  "post-apply hook of stack",
+			hooks: []*models.Hook{{Name: "smoke", Phase: models.PostApply, Command: "make test"}},
+			want: `
+eric
+  hook:postApply:smoke
+hook:postApply:smoke
+jack
+  pony
+pony
+  eric
+root
+  jack
+  tom
+tom
+  hook:postApply:smoke
+`,
+		},
+		{
+			name:  "pre-apply hook of stack resources",
+			hooks: []*models.Hook{{Name: "migrate", Phase: models.PreApply, Command: "make migrate", Resources: []string{"pony", "tom"}}},
+			want: `
+eric
+hook:preApply:migrate
+  pony
+  tom
+jack
+  hook:preApply:migrate
+pony
+  eric
+root
+  jack
+tom
+`,
+		},
+		{
+			name:  "hook of resources not applied",
+			hooks: []*models.Hook{{Name: "migrate", Phase: models.PreApply, Command: "make migrate", Resources: []string{"bob"}}},
+			want: `
+eric
+jack
+  pony
+pony
+  eric
+root
+  jack
+  tom
+tom
+`,
+		},
+		{
+			name: "duplicate hooks",
+			hooks: []*models.Hook{
+				{Name: "smoke", Phase: models.PostApply, Command: "make test"},
+				{Name: "smoke", Phase: models.PostApply, Command: "make e2e"},
+			},
+			wantCode: status.InvalidArgument,
+		},
+		{
+			name: "illegal hook of resource",
+			ponyHooks: []interface{}{
+				map[string]interface{}{"name": "migrate", "phase": "preApply"},
+			},
+			wantCode: status.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ag := &dag.AcyclicGraph{}
+			ag.Add(&graph.RootNode{})
+			assert.Nil(t, NewSpecParser(newSpec(tt.ponyHooks)).Parse(ag))
+
+			s := NewHookParser(tt.hooks).Parse(ag)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, s.Code())
+				return
+			}
+			assert.Nil(t, s)
+			assert.Equal(t, strings.TrimSpace(tt.want), strings.TrimSpace(ag.String()))
+		})
+	}
+}
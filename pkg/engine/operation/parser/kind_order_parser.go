@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"sort"
+
+	"github.com/hashicorp/terraform/dag"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util"
+)
+
+// DefaultKindOrder is the default order to apply Kubernetes resources by their kinds, similar to the install order of
+// Helm. Kinds in the same group are applied concurrently, and kinds not listed here, e.g. custom resources, are
+// applied after all of them
+var DefaultKindOrder = [][]string{
+	{"Namespace"},
+	{
+		"CustomResourceDefinition", "ServiceAccount", "ClusterRole", "ClusterRoleBinding", "Role", "RoleBinding",
+		"ResourceQuota", "LimitRange", "NetworkPolicy", "PodSecurityPolicy", "PodDisruptionBudget", "PriorityClass",
+		"StorageClass",
+	},
+	{"ConfigMap", "Secret", "PersistentVolume", "PersistentVolumeClaim"},
+	{"Service"},
+	{
+		"Pod", "ReplicationController", "ReplicaSet", "Deployment", "StatefulSet", "DaemonSet", "Job", "CronJob",
+		"HorizontalPodAutoscaler",
+	},
+	{"Ingress", "APIService"},
+}
+
+// KindOrderParser adds implicit edges between Kubernetes resources by their kinds in DefaultKindOrder, so that
+// resources without dependsOn or implicit references are still applied in order, e.g. a Namespace and a CRD are
+// applied before custom resources in them. Resources to be deleted are deleted in the reverse order.
+//
+// Edges conflicting with existing dependencies are not added, so explicit dependencies always win. It should be used
+// after the TargetParser, otherwise all resources ordered before targets would be targeted as well
+type KindOrderParser struct{}
+
+func NewKindOrderParser() *KindOrderParser {
+	return &KindOrderParser{}
+}
+
+var _ Parser = (*KindOrderParser)(nil)
+
+func (k *KindOrderParser) Parse(g *dag.AcyclicGraph) status.Status {
+	util.CheckNotNil(g, "dag is nil")
+
+	ranks := make(map[string]int)
+	for i, kinds := range DefaultKindOrder {
+		for _, kind := range kinds {
+			ranks[kind] = i
+		}
+	}
+
+	// group resources to apply and delete by ranks of their kinds, and the last group is for kinds not listed
+	applied := make([][]*graph.ResourceNode, len(DefaultKindOrder)+1)
+	deleted := make([][]*graph.ResourceNode, len(DefaultKindOrder)+1)
+	for _, v := range g.Vertices() {
+		rn, ok := v.(*graph.ResourceNode)
+		if !ok || rn.State() == nil || rn.State().RuntimeType() != models.Kubernetes {
+			continue
+		}
+		kind, _ := rn.State().Attributes["kind"].(string)
+		if kind == "" {
+			continue
+		}
+		rank, ok := ranks[kind]
+		if !ok {
+			rank = len(DefaultKindOrder)
+		}
+		if rn.Action == types.Delete {
+			// deleted in the reverse order
+			deleted[len(DefaultKindOrder)-rank] = append(deleted[len(DefaultKindOrder)-rank], rn)
+		} else {
+			applied[rank] = append(applied[rank], rn)
+		}
+	}
+
+	if err := connectGroups(g, applied); err != nil {
+		return status.NewErrorStatus(err)
+	}
+	if err := connectGroups(g, deleted); err != nil {
+		return status.NewErrorStatus(err)
+	}
+
+	if err := g.Validate(); err != nil {
+		return status.NewErrorStatusWithMsg(status.IllegalManifest, "Found circle dependency in models:"+err.Error())
+	}
+	g.TransitiveReduction()
+	return nil
+}
+
+// connectGroups connects every resource node in a group to those in the next non-empty group, unless the edge
+// conflicts with existing dependencies
+func connectGroups(g *dag.AcyclicGraph, groups [][]*graph.ResourceNode) error {
+	var prev []*graph.ResourceNode
+	for _, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		// sorted so that edges are added in a stable order
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].Hashcode().(string) < group[j].Hashcode().(string)
+		})
+		for _, from := range prev {
+			// Descendents walks up edges, which are nodes executed before the from node, and connecting any of them
+			// after it makes a cycle. Edges added below all start from the from node, so they never change the set
+			before, err := g.Descendents(from)
+			if err != nil {
+				return err
+			}
+			for _, to := range group {
+				if !before.Include(to) {
+					g.Connect(dag.BasicEdge(from, to))
+				}
+			}
+		}
+		prev = group
+	}
+	return nil
+}
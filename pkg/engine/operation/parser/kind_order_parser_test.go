@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/dag"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+)
+
+func newKindResource(id, kind string, dependsOn ...string) models.Resource {
+	return models.Resource{
+		ID:         id,
+		Type:       models.Kubernetes,
+		Attributes: map[string]interface{}{"kind": kind},
+		DependsOn:  dependsOn,
+	}
+}
+
+func TestKindOrderParser_Parse(t *testing.T) {
+	ns := newKindResource("v1:Namespace:default", "Namespace")
+	cm := newKindResource("v1:ConfigMap:default:cm", "ConfigMap")
+	deploy := newKindResource("apps/v1:Deployment:default:nginx", "Deployment")
+	foo := newKindResource("example.com/v1:Foo:default:foo", "Foo")
+	tf := models.Resource{
+		ID:         "hashicorp:random:random_password:example",
+		Type:       "Terraform",
+		Attributes: map[string]interface{}{"kind": "Namespace"},
+	}
+
+	tests := []struct {
+		name    string
+		applied models.Resources
+		deleted models.Resources
+		want    string
+	}{
+		{
+			name:    "apply by kinds",
+			applied: models.Resources{foo, deploy, cm, ns, tf},
+			want: `
+apps/v1:Deployment:default:nginx
+  example.com/v1:Foo:default:foo
+example.com/v1:Foo:default:foo
+hashicorp:random:random_password:example
+root
+  hashicorp:random:random_password:example
+  v1:Namespace:default
+v1:ConfigMap:default:cm
+  apps/v1:Deployment:default:nginx
+v1:Namespace:default
+  v1:ConfigMap:default:cm
+`,
+		},
+		{
+			name:    "delete in the reverse order",
+			applied: models.Resources{},
+			deleted: models.Resources{ns, cm, deploy, foo},
+			want: `
+apps/v1:Deployment:default:nginx
+  v1:ConfigMap:default:cm
+example.com/v1:Foo:default:foo
+  apps/v1:Deployment:default:nginx
+root
+  example.com/v1:Foo:default:foo
+v1:ConfigMap:default:cm
+  v1:Namespace:default
+v1:Namespace:default
+`,
+		},
+		{
+			name:    "explicit dependencies win",
+			applied: models.Resources{ns, newKindResource(cm.ID, "ConfigMap", deploy.ID), deploy, foo},
+			want: `
+apps/v1:Deployment:default:nginx
+  example.com/v1:Foo:default:foo
+  v1:ConfigMap:default:cm
+example.com/v1:Foo:default:foo
+root
+  apps/v1:Deployment:default:nginx
+  v1:Namespace:default
+v1:ConfigMap:default:cm
+v1:Namespace:default
+  v1:ConfigMap:default:cm
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ag := &dag.AcyclicGraph{}
+			ag.Add(&graph.RootNode{})
+			assert.Nil(t, NewSpecParser(&models.Spec{Resources: tt.applied}).Parse(ag))
+			if len(tt.deleted) != 0 {
+				assert.Nil(t, NewDeleteResourceParser(tt.deleted).Parse(ag))
+			}
+
+			assert.Nil(t, NewKindOrderParser().Parse(ag))
+			assert.Equal(t, strings.TrimSpace(tt.want), strings.TrimSpace(ag.String()))
+		})
+	}
+}
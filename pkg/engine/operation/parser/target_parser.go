@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform/dag"
+
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util"
+)
+
+// TargetParser prunes resource nodes in the DAG built by other parsers, so that only targeted resources are executed.
+// Targets and excludes are resource IDs or glob patterns, in which `*` matches any characters and `?` matches one character.
+//
+// Resources that must be executed before targets, e.g. resources targets depend on in the apply DAG, are targeted
+// as well. Excluded resources are never executed, even if targets depend on them.
+type TargetParser struct {
+	targets  []string
+	excludes []string
+}
+
+func NewTargetParser(targets, excludes []string) *TargetParser {
+	return &TargetParser{targets: targets, excludes: excludes}
+}
+
+var _ Parser = (*TargetParser)(nil)
+
+// Empty means all resources are executed
+func (t *TargetParser) Empty() bool {
+	return len(t.targets) == 0 && len(t.excludes) == 0
+}
+
+func (t *TargetParser) Parse(g *dag.AcyclicGraph) status.Status {
+	util.CheckNotNil(g, "dag is nil")
+	if t.Empty() {
+		return nil
+	}
+
+	nodes := make(map[string]*graph.ResourceNode)
+	for _, v := range g.Vertices() {
+		if rn, ok := v.(*graph.ResourceNode); ok {
+			nodes[rn.Hashcode().(string)] = rn
+		}
+	}
+
+	// 1. select targets and resources executed before them
+	selected := make(map[string]bool, len(nodes))
+	if len(t.targets) == 0 {
+		for id := range nodes {
+			selected[id] = true
+		}
+	}
+	for _, target := range t.targets {
+		matched := false
+		for id, rn := range nodes {
+			if !matchPattern(target, id) {
+				continue
+			}
+			matched = true
+			selectWithUpstreams(g, rn, selected)
+		}
+		if !matched {
+			return status.NewErrorStatusWithMsg(status.InvalidArgument, fmt.Sprintf("no resources match target:%s", target))
+		}
+	}
+
+	// 2. exclude resources
+	for _, exclude := range t.excludes {
+		for id := range nodes {
+			if matchPattern(exclude, id) {
+				delete(selected, id)
+			}
+		}
+	}
+
+	// 3. remove resources not selected, and connect their upstreams with downstreams to keep the order of others
+	for id, rn := range nodes {
+		if selected[id] {
+			continue
+		}
+		for _, up := range g.UpEdges(rn).List() {
+			for _, down := range g.DownEdges(rn).List() {
+				g.Connect(dag.BasicEdge(up, down))
+			}
+		}
+		g.Remove(rn)
+	}
+	log.Warnf("%d of %d resources are targeted, the stack will be partially executed", len(selected), len(nodes))
+
+	if err := g.Validate(); err != nil {
+		return status.NewErrorStatusWithMsg(status.IllegalManifest, "Found circle dependency in models:"+err.Error())
+	}
+	g.TransitiveReduction()
+	return nil
+}
+
+// selectWithUpstreams selects the resource node and all resource nodes executed before it
+func selectWithUpstreams(g *dag.AcyclicGraph, rn *graph.ResourceNode, selected map[string]bool) {
+	id := rn.Hashcode().(string)
+	if selected[id] {
+		return
+	}
+	selected[id] = true
+	for _, up := range g.UpEdges(rn).List() {
+		if upNode, ok := up.(*graph.ResourceNode); ok {
+			selectWithUpstreams(g, upNode, selected)
+		}
+	}
+}
+
+// matchPattern reports whether the resource ID matches the ID or glob pattern
+func matchPattern(pattern, id string) bool {
+	if !strings.ContainsAny(pattern, "*?") {
+		return pattern == id
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	matched, _ := regexp.MatchString("^"+expr+"$", id)
+	return matched
+}
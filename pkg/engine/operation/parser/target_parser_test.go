@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/dag"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func TestTargetParser_Parse(t *testing.T) {
+	mf := &models.Spec{Resources: []models.Resource{
+		{ID: "jack", Attributes: map[string]interface{}{"a": "b"}},
+		{ID: "pony", Attributes: map[string]interface{}{"c": "d"}, DependsOn: []string{"jack"}},
+		{ID: "eric", Attributes: map[string]interface{}{"a": graph.ImplicitRefPrefix + "pony.c"}},
+		{ID: "tom", Attributes: map[string]interface{}{"e": "f"}},
+	}}
+
+	tests := []struct {
+		name     string
+		targets  []string
+		excludes []string
+		want     string
+		wantCode status.Code
+	}{
+		{
+			name: "no targets",
+			want: `
+eric
+jack
+  pony
+pony
+  eric
+root
+  jack
+  tom
+tom
+`,
+		},
+		{
+			name:    "target with dependencies",
+			targets: []string{"pony"},
+			want: `
+jack
+  pony
+pony
+root
+  jack
+`,
+		},
+		{
+			name:    "target by glob pattern",
+			targets: []string{"t?m", "j*"},
+			want: `
+jack
+root
+  jack
+  tom
+tom
+`,
+		},
+		{
+			name:     "exclude keeps the order of others",
+			excludes: []string{"pony"},
+			want: `
+eric
+jack
+  eric
+root
+  jack
+  tom
+tom
+`,
+		},
+		{
+			name:     "exclude dependencies of targets",
+			targets:  []string{"eric"},
+			excludes: []string{"jack"},
+			want: `
+eric
+pony
+  eric
+root
+  pony
+`,
+		},
+		{
+			name:     "no resources match target",
+			targets:  []string{"bob"},
+			wantCode: status.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ag := &dag.AcyclicGraph{}
+			ag.Add(&graph.RootNode{})
+			assert.Nil(t, NewSpecParser(mf).Parse(ag))
+
+			s := NewTargetParser(tt.targets, tt.excludes).Parse(ag)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, s.Code())
+				return
+			}
+			assert.Nil(t, s)
+			assert.Equal(t, strings.TrimSpace(tt.want), strings.TrimSpace(ag.String()))
+		})
+	}
+}
+
+func Test_matchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		id      string
+		want    bool
+	}{
+		{pattern: "v1:Namespace:default", id: "v1:Namespace:default", want: true},
+		{pattern: "v1:Namespace:default", id: "v1:Namespace:kube-system", want: false},
+		{pattern: "apps/v1:Deployment:default:*", id: "apps/v1:Deployment:default:nginx", want: true},
+		{pattern: "*:Service:*", id: "v1:Service:default:nginx", want: true},
+		{pattern: "*:Service:*", id: "apps/v1:Deployment:default:nginx", want: false},
+		{pattern: "v1:Namespace:default-?", id: "v1:Namespace:default-1", want: true},
+		{pattern: "v1:Namespace:default.*", id: "v1:Namespace:default-1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchPattern(tt.pattern, tt.id))
+		})
+	}
+}
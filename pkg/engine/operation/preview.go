@@ -1,6 +1,7 @@
 package operation
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -8,9 +9,11 @@ import (
 	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
 
 	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	"kusionstack.io/kusion/pkg/engine/operation/parser"
 	"kusionstack.io/kusion/pkg/engine/operation/types"
 
 	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
 
 	"github.com/hashicorp/terraform/dag"
 	"github.com/hashicorp/terraform/tfdiags"
@@ -34,7 +37,7 @@ type PreviewResponse struct {
 
 // Preview compute all changes between resources in request and the actual infrastructure.
 // The whole process is similar to the operation Apply, but the execution of each node is mocked and will not actually invoke the Runtime
-func (po *PreviewOperation) Preview(request *PreviewRequest) (rsp *PreviewResponse, s status.Status) {
+func (po *PreviewOperation) Preview(ctx context.Context, request *PreviewRequest) (rsp *PreviewResponse, s status.Status) {
 	o := po.Operation
 
 	defer func() {
@@ -52,14 +55,20 @@ func (po *PreviewOperation) Preview(request *PreviewRequest) (rsp *PreviewRespon
 		}
 	}()
 
-	if s := validateRequest(&request.Request); status.IsErr(s) {
+	validate := validateRequest
+	if o.OperationType == types.DestroyPreview {
+		validate = validateDestroyRequest
+	}
+	if s := validate(&request.Request); status.IsErr(s) {
 		return nil, s
 	}
 
 	var (
 		priorState, resultState *states.State
 		priorStateResourceIndex map[string]*models.Resource
+		runtimeMap              map[models.Type]runtime.Runtime
 		ag                      *dag.AcyclicGraph
+		err                     error
 	)
 
 	// 1. init & build Indexes
@@ -68,15 +77,25 @@ func (po *PreviewOperation) Preview(request *PreviewRequest) (rsp *PreviewRespon
 	switch o.OperationType {
 	case types.ApplyPreview:
 		priorStateResourceIndex = priorState.Resources.Index()
+		runtimeMap, err = runtime.InitRuntimes(o.RuntimeMap, request.Spec.Resources, priorState.Resources)
 		ag, s = NewApplyGraph(request.Spec, priorState)
 	case types.DestroyPreview:
-		resources := request.Request.Spec.Resources
-		priorStateResourceIndex = resources.Index()
-		ag, s = NewDestroyGraph(resources)
+		priorStateResourceIndex = priorState.Resources.Index()
+		runtimeMap, err = runtime.InitRuntimes(o.RuntimeMap, priorState.Resources)
+		ag, s = newStateDestroyGraph(priorState, request.Spec)
+	}
+	if err != nil {
+		return nil, status.NewErrorStatus(err)
 	}
 	if status.IsErr(s) {
 		return nil, s
 	}
+	if s = parser.NewTargetParser(request.Targets, request.Excludes).Parse(ag); status.IsErr(s) {
+		return nil, s
+	}
+	if s = parseKindOrder(&request.Request, ag); status.IsErr(s) {
+		return nil, s
+	}
 
 	// 2. walk DAG and preview resources
 	log.Info("walking DAG and preview resources ...")
@@ -85,27 +104,33 @@ func (po *PreviewOperation) Preview(request *PreviewRequest) (rsp *PreviewRespon
 		Operation: opsmodels.Operation{
 			OperationType:           o.OperationType,
 			StateStorage:            o.StateStorage,
+			StackStorage:            o.StackStorage,
 			CtxResourceIndex:        map[string]*models.Resource{},
 			PriorStateResourceIndex: priorStateResourceIndex,
 			StateResourceIndex:      priorStateResourceIndex,
 			ChangeOrder:             o.ChangeOrder,
-			Runtime:                 o.Runtime, // preview need get the latest spec from runtime
+			RuntimeMap:              runtimeMap, // preview need get the latest spec from runtime
 			ResultState:             resultState,
 			Lock:                    &sync.Mutex{},
+			ResourceTimeout:         o.ResourceTimeout,
+			Parallelism:             o.Parallelism,
+			RuntimeParallelism:      o.RuntimeParallelism,
 		},
 	}
 
-	w := &dag.Walker{Callback: previewOperation.previewWalkFun}
+	w := &dag.Walker{Callback: limitWalkFun(ctx, &previewOperation.Operation, func(v dag.Vertex) tfdiags.Diagnostics {
+		return previewOperation.previewWalkFun(ctx, v)
+	})}
 	w.Update(ag)
 	// Wait
 	if diags := w.Wait(); diags.HasErrors() {
-		return nil, status.NewErrorStatus(diags.Err())
+		return nil, walkErrStatus(ctx, diags)
 	}
 
 	return &PreviewResponse{Order: previewOperation.ChangeOrder}, nil
 }
 
-func (po *PreviewOperation) previewWalkFun(v dag.Vertex) (diags tfdiags.Diagnostics) {
+func (po *PreviewOperation) previewWalkFun(ctx context.Context, v dag.Vertex) (diags tfdiags.Diagnostics) {
 	var s status.Status
 	if v == nil {
 		return nil
@@ -128,7 +153,7 @@ func (po *PreviewOperation) previewWalkFun(v dag.Vertex) (diags tfdiags.Diagnost
 	}()
 
 	if node, ok := v.(graph.ExecutableNode); ok {
-		s = node.Execute(&po.Operation)
+		s = node.Execute(ctx, &po.Operation)
 		if status.IsErr(s) {
 			diags = diags.Append(fmt.Errorf("node execute failed.\n%v", s))
 			return diags
@@ -2,6 +2,7 @@ package operation
 
 import (
 	"context"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
@@ -81,7 +82,7 @@ func TestOperation_Preview(t *testing.T) {
 		PriorStateResourceIndex map[string]*models.Resource
 		StateResourceIndex      map[string]*models.Resource
 		Order                   *opsmodels.ChangeOrder
-		Runtime                 runtime.Runtime
+		RuntimeMap              map[models.Type]runtime.Runtime
 		MsgCh                   chan opsmodels.Message
 		resultState             *states.State
 		lock                    *sync.Mutex
@@ -89,6 +90,11 @@ func TestOperation_Preview(t *testing.T) {
 	type args struct {
 		request *PreviewRequest
 	}
+	// resources to destroy are in the latest state
+	destroyStateStorage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	if err := destroyStateStorage.Apply(&states.State{Resources: models.Resources{FakeResourceState2}}); err != nil {
+		t.Fatal(err)
+	}
 	tests := []struct {
 		name    string
 		fields  fields
@@ -100,7 +106,7 @@ func TestOperation_Preview(t *testing.T) {
 			name: "success-when-apply",
 			fields: fields{
 				OperationType: types.ApplyPreview,
-				Runtime:       &fakePreviewRuntime{},
+				RuntimeMap:    map[models.Type]runtime.Runtime{models.Kubernetes: &fakePreviewRuntime{}},
 				StateStorage:  &local.FileSystemState{Path: local.KusionState},
 				Order:         &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
 			},
@@ -139,8 +145,8 @@ func TestOperation_Preview(t *testing.T) {
 			name: "success-when-destroy",
 			fields: fields{
 				OperationType: types.DestroyPreview,
-				Runtime:       &fakePreviewRuntime{},
-				StateStorage:  &local.FileSystemState{Path: local.KusionState},
+				RuntimeMap:    map[models.Type]runtime.Runtime{models.Kubernetes: &fakePreviewRuntime{}},
+				StateStorage:  destroyStateStorage,
 				Order:         &opsmodels.ChangeOrder{},
 			},
 			args: args{
@@ -150,11 +156,6 @@ func TestOperation_Preview(t *testing.T) {
 						Stack:    "fake-stack",
 						Project:  "fake-project",
 						Operator: "fake-operator",
-						Spec: &models.Spec{
-							Resources: []models.Resource{
-								FakeResourceState2,
-							},
-						},
 					},
 				},
 			},
@@ -178,7 +179,7 @@ func TestOperation_Preview(t *testing.T) {
 			name: "fail-because-empty-models",
 			fields: fields{
 				OperationType: types.ApplyPreview,
-				Runtime:       &fakePreviewRuntime{},
+				RuntimeMap:    map[models.Type]runtime.Runtime{models.Kubernetes: &fakePreviewRuntime{}},
 				StateStorage:  &local.FileSystemState{Path: local.KusionState},
 				Order:         &opsmodels.ChangeOrder{},
 			},
@@ -196,7 +197,7 @@ func TestOperation_Preview(t *testing.T) {
 			name: "fail-because-nonexistent-id",
 			fields: fields{
 				OperationType: types.ApplyPreview,
-				Runtime:       &fakePreviewRuntime{},
+				RuntimeMap:    map[models.Type]runtime.Runtime{models.Kubernetes: &fakePreviewRuntime{}},
 				StateStorage:  &local.FileSystemState{Path: local.KusionState},
 				Order:         &opsmodels.ChangeOrder{},
 			},
@@ -234,13 +235,13 @@ func TestOperation_Preview(t *testing.T) {
 					PriorStateResourceIndex: tt.fields.PriorStateResourceIndex,
 					StateResourceIndex:      tt.fields.StateResourceIndex,
 					ChangeOrder:             tt.fields.Order,
-					Runtime:                 tt.fields.Runtime,
+					RuntimeMap:              tt.fields.RuntimeMap,
 					MsgCh:                   tt.fields.MsgCh,
 					ResultState:             tt.fields.resultState,
 					Lock:                    tt.fields.lock,
 				},
 			}
-			gotRsp, gotS := o.Preview(tt.args.request)
+			gotRsp, gotS := o.Preview(context.Background(), tt.args.request)
 			if !reflect.DeepEqual(gotRsp, tt.wantRsp) {
 				t.Errorf("Operation.Preview() gotRsp = %v, want %v", kdump.FormatN(gotRsp), kdump.FormatN(tt.wantRsp))
 			}
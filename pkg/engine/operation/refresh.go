@@ -0,0 +1,139 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+	jsonutil "kusionstack.io/kusion/pkg/util/json"
+)
+
+type RefreshOperation struct {
+	opsmodels.Operation
+}
+
+// RefreshRequest only needs Tenant, Project and Stack to locate the State. Spec is not used
+type RefreshRequest struct {
+	opsmodels.Request `json:",inline" yaml:",inline"`
+}
+
+type RefreshResponse struct {
+	// State is the latest State after refreshing
+	State *states.State
+	// Order describes how each resource in the prior State is refreshed. Update means the resource is modified out-of-band
+	// and Delete means the resource doesn't exist anymore and is dropped from the State
+	Order *opsmodels.ChangeOrder
+}
+
+// Refresh reconciles the State with the actual infra. Every resource in the prior State is read by its Runtime,
+// resources modified out-of-band are replaced with their live attributes and resources that no longer exist are dropped.
+// A new State serial is saved only if any resource is changed, and the actual infra is never changed.
+// Reading resources is canceled when the ctx is done, and the State is not changed in that case
+func (ro *RefreshOperation) Refresh(ctx context.Context, request *RefreshRequest) (rsp *RefreshResponse, st status.Status) {
+	log.Infof("engine: Refresh start!")
+	o := ro.Operation
+
+	defer func() {
+		if e := recover(); e != nil {
+			log.Error("refresh panic:%v", e)
+
+			switch x := e.(type) {
+			case string:
+				st = status.NewErrorStatus(fmt.Errorf("refresh panic:%s", e))
+			case error:
+				st = status.NewErrorStatus(x)
+			default:
+				st = status.NewErrorStatusWithCode(status.Unknown, errors.New("unknown panic"))
+			}
+		}
+	}()
+
+	if request == nil {
+		return nil, status.NewErrorStatusWithMsg(status.InvalidArgument, "request is nil")
+	}
+
+	// 1. init & build Indexes
+	priorState, resultState := o.InitStates(&request.Request)
+	runtimeMap, err := runtime.InitRuntimes(o.RuntimeMap, priorState.Resources)
+	if err != nil {
+		return nil, status.NewErrorStatus(err)
+	}
+
+	// 2. read live resources
+	order := &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}}
+	stateResourceIndex := make(map[string]*models.Resource, len(priorState.Resources))
+	changed := false
+	for i := range priorState.Resources {
+		prior := &priorState.Resources[i]
+		key := prior.ResourceKey()
+
+		live, s := importResource(ctx, runtimeMap[prior.RuntimeType()], prior)
+		if status.IsErr(s) {
+			return nil, s
+		}
+
+		action := types.UnChange
+		refreshed := prior
+		if live == nil {
+			log.Infof("resource:%s doesn't exist anymore, drop it from the state", key)
+			action = types.Delete
+			refreshed = nil
+		} else {
+			// live resources only have attributes, and Kusion settings of them, e.g. lifecycle settings in Extensions,
+			// are kept from the prior resource
+			live.ID = key
+			live.Type = prior.Type
+			live.DependsOn = prior.DependsOn
+			live.Extensions = prior.Extensions
+			// keep the prior resource if nothing is changed, since it is the last-applied configuration
+			equal, err := runtime.GetComparator(prior.RuntimeType()).Equal(prior, prior, live)
+			if err != nil {
+				log.Warnf("compare resource:%s failed, regard it as changed. %v", key, err)
+			}
+			if err != nil || !equal {
+				action = types.Update
+				// the refreshed resource is the last-applied configuration of the next apply, so only fields in the
+				// prior resource are kept. Otherwise fields populated by the actual infra, e.g. defaulted values and
+				// labels added by controllers, would be deleted by the next apply
+				pruned := *live
+				pruned.Attributes = jsonutil.RemoveMapFields(prior.Attributes, live.Attributes)
+				refreshed = &pruned
+			}
+		}
+
+		if action != types.UnChange {
+			changed = true
+		}
+		if refreshed != nil {
+			stateResourceIndex[key] = refreshed
+		}
+		order.StepKeys = append(order.StepKeys, key)
+		order.ChangeSteps[key] = opsmodels.NewChangeStep(key, action, prior, prior, live)
+	}
+
+	// 3. save the State
+	if !changed {
+		return &RefreshResponse{State: priorState, Order: order}, nil
+	}
+	refreshOperation := &RefreshOperation{
+		Operation: opsmodels.Operation{
+			OperationType: types.Refresh,
+			StateStorage:  o.StateStorage,
+			ResultState:   resultState,
+			Lock:          &sync.Mutex{},
+		},
+	}
+	if err = refreshOperation.UpdateState(stateResourceIndex); err != nil {
+		return nil, status.NewErrorStatus(err)
+	}
+	return &RefreshResponse{State: resultState, Order: order}, nil
+}
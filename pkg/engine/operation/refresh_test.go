@@ -0,0 +1,174 @@
+package operation
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util/json"
+)
+
+const fakeType models.Type = "Fake"
+
+var _ runtime.Runtime = (*fakeRefreshRuntime)(nil)
+
+// fakeRefreshRuntime reads resources from the live map, and fails on any changes
+type fakeRefreshRuntime struct {
+	t    *testing.T
+	live map[string]*models.Resource
+}
+
+func (f *fakeRefreshRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	f.t.Errorf("refresh should not apply resource:%s", request.PlanResource.ID)
+	return nil
+}
+
+func (f *fakeRefreshRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	if err := ctx.Err(); err != nil {
+		return &runtime.ReadResponse{Status: status.NewErrorStatus(err)}
+	}
+	return &runtime.ReadResponse{Resource: f.live[request.Resource.ResourceKey()]}
+}
+
+func (f *fakeRefreshRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	f.t.Errorf("refresh should not delete resource:%s", request.Resource.ID)
+	return nil
+}
+
+func (f *fakeRefreshRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	return nil
+}
+
+func TestRefreshOperation_Refresh(t *testing.T) {
+	newResource := func(id, image string) *models.Resource {
+		return &models.Resource{ID: id, Type: fakeType, Attributes: map[string]interface{}{"image": image}}
+	}
+	request := &RefreshRequest{Request: opsmodels.Request{Tenant: "fake-tenant", Stack: "fake-stack", Project: "fake-project"}}
+
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	prior := states.NewState()
+	prior.Serial = 1
+	prior.Resources = models.Resources{*newResource("unchanged", "nginx:1.20"), *newResource("edited", "nginx:1.20"), *newResource("deleted", "nginx:1.20")}
+	assert.Nil(t, storage.Apply(prior))
+
+	ro := &RefreshOperation{Operation: opsmodels.Operation{
+		StateStorage: storage,
+		RuntimeMap: map[models.Type]runtime.Runtime{fakeType: &fakeRefreshRuntime{t: t, live: map[string]*models.Resource{
+			"unchanged": newResource("unchanged", "nginx:1.20"),
+			"edited":    newResource("edited", "nginx:1.21"),
+		}}},
+	}}
+	rsp, s := ro.Refresh(context.Background(), request)
+	assert.Nil(t, s)
+	assert.Equal(t, []string{"unchanged", "edited", "deleted"}, rsp.Order.StepKeys)
+	assert.Equal(t, types.UnChange, rsp.Order.Get("unchanged").Action)
+	assert.Equal(t, types.Update, rsp.Order.Get("edited").Action)
+	assert.Equal(t, types.Delete, rsp.Order.Get("deleted").Action)
+
+	latest, err := storage.GetLatestState(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), latest.Serial)
+	index := latest.Resources.Index()
+	assert.Len(t, index, 2)
+	assert.Equal(t, "nginx:1.21", index["edited"].Attributes["image"])
+
+	// nothing changed, so no new serial is saved
+	_, s = ro.Refresh(context.Background(), request)
+	assert.Nil(t, s)
+	latest, err = storage.GetLatestState(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), latest.Serial)
+
+	// the refresh is canceled, and the State is not changed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, s = ro.Refresh(ctx, request)
+	assert.True(t, status.IsErr(s))
+	latest, err = storage.GetLatestState(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), latest.Serial)
+}
+
+func TestRefreshOperation_RefreshThenApply(t *testing.T) {
+	request := &RefreshRequest{Request: opsmodels.Request{Tenant: "fake-tenant", Stack: "fake-stack", Project: "fake-project"}}
+	plan := map[string]interface{}{
+		"image":  "nginx:1.20",
+		"labels": map[string]interface{}{"app": "nginx"},
+	}
+	// the image is edited out-of-band, and the others are populated by the actual infra
+	live := &models.Resource{ID: "nginx", Type: fakeType, Attributes: map[string]interface{}{
+		"image":    "nginx:1.21",
+		"labels":   map[string]interface{}{"app": "nginx", "controller": "added"},
+		"replicas": 1,
+	}}
+
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	prior := states.NewState()
+	prior.Resources = models.Resources{{ID: "nginx", Type: fakeType, Attributes: plan}}
+	assert.Nil(t, storage.Apply(prior))
+
+	ro := &RefreshOperation{Operation: opsmodels.Operation{
+		StateStorage: storage,
+		RuntimeMap:   map[models.Type]runtime.Runtime{fakeType: &fakeRefreshRuntime{t: t, live: map[string]*models.Resource{"nginx": live}}},
+	}}
+	_, s := ro.Refresh(context.Background(), request)
+	assert.Nil(t, s)
+	latest, err := storage.GetLatestState(nil)
+	assert.Nil(t, err)
+	refreshed := latest.Resources.Index()["nginx"]
+
+	// the next apply only reverts the edited image, and keeps fields populated by the actual infra
+	patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch([]byte(json.MustMarshal2String(refreshed.Attributes)),
+		[]byte(json.MustMarshal2String(plan)), []byte(json.MustMarshal2String(live.Attributes)))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"image":"nginx:1.20"}`, string(patch))
+}
+
+func TestRefreshOperation_RefreshKeepsLifecycle(t *testing.T) {
+	request := &RefreshRequest{Request: opsmodels.Request{Tenant: "fake-tenant", Stack: "fake-stack", Project: "fake-project"}}
+	extensions := map[string]interface{}{
+		models.PreventDestroyExtension: true,
+		models.DeletionPolicyExtension: string(models.DeletionPolicyRetain),
+	}
+
+	storage := &local.FileSystemState{Path: filepath.Join(t.TempDir(), local.KusionState)}
+	prior := states.NewState()
+	prior.Resources = models.Resources{{
+		ID:         "nginx",
+		Type:       fakeType,
+		Attributes: map[string]interface{}{"image": "nginx:1.20"},
+		Extensions: extensions,
+	}}
+	assert.Nil(t, storage.Apply(prior))
+
+	// the live resource read by the runtime has no extensions
+	ro := &RefreshOperation{Operation: opsmodels.Operation{
+		StateStorage: storage,
+		RuntimeMap: map[models.Type]runtime.Runtime{fakeType: &fakeRefreshRuntime{t: t, live: map[string]*models.Resource{
+			"nginx": {ID: "nginx", Type: fakeType, Attributes: map[string]interface{}{"image": "nginx:1.21"}},
+		}}},
+	}}
+	rsp, s := ro.Refresh(context.Background(), request)
+	assert.Nil(t, s)
+	assert.Equal(t, types.Update, rsp.Order.Get("nginx").Action)
+
+	latest, err := storage.GetLatestState(nil)
+	assert.Nil(t, err)
+	refreshed := latest.Resources.Index()["nginx"]
+	assert.Equal(t, "nginx:1.21", refreshed.Attributes["image"])
+	lifecycle, err := refreshed.Lifecycle()
+	assert.Nil(t, err)
+	assert.True(t, lifecycle.PreventDestroy)
+	assert.Equal(t, models.DeletionPolicyRetain, lifecycle.DeletionPolicy)
+}
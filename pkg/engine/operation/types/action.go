@@ -1,6 +1,10 @@
 package types
 
-import "kusionstack.io/kusion/pkg/util/pretty"
+import (
+	"fmt"
+
+	"kusionstack.io/kusion/pkg/util/pretty"
+)
 
 // ActionType represents the kind of operation performed by a plan.  It evaluates to its string label.
 type ActionType int64
@@ -12,16 +16,50 @@ const (
 	Create                      // creating a new resource.
 	Update                      // updating an existing resource.
 	Delete                      // deleting an existing resource.
+	Replace                     // deleting an existing resource and then creating it again.
+	// ReplaceCreateBeforeDelete creates the replacement of an existing resource before deleting it.
+	ReplaceCreateBeforeDelete
+	Retain // removing an existing resource from the state without deleting it.
 )
 
+var actionNames = []string{
+	"Undefined",
+	"UnChange",
+	"Create",
+	"Update",
+	"Delete",
+	"Replace",
+	"Replace(CreateBeforeDelete)",
+	"Retain",
+}
+
 func (t ActionType) String() string {
-	return []string{
-		"Undefined",
-		"UnChange",
-		"Create",
-		"Update",
-		"Delete",
-	}[t]
+	return actionNames[t]
+}
+
+// MarshalText encodes the action by its name in JSON and YAML, e.g. in saved plans, which stays the same if values of
+// actions are changed
+func (t ActionType) MarshalText() ([]byte, error) {
+	if t < 0 || int(t) >= len(actionNames) {
+		return nil, fmt.Errorf("unknown action:%d", t)
+	}
+	return []byte(actionNames[t]), nil
+}
+
+// UnmarshalText decodes the action by its name
+func (t *ActionType) UnmarshalText(text []byte) error {
+	for i, name := range actionNames {
+		if name == string(text) {
+			*t = ActionType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown action:%s", text)
+}
+
+// IsReplace reports whether the resource is deleted and created again, regardless of the order
+func (t ActionType) IsReplace() bool {
+	return t == Replace || t == ReplaceCreateBeforeDelete
 }
 
 func (t ActionType) Ing() string {
@@ -32,6 +70,10 @@ func (t ActionType) Ing() string {
 		return "Updating"
 	case Delete:
 		return "Deleting"
+	case Replace, ReplaceCreateBeforeDelete:
+		return "Replacing"
+	case Retain:
+		return "Retaining"
 	default:
 		return "Unchanged"
 	}
@@ -47,6 +89,12 @@ func (t ActionType) PrettyString() string {
 		return pretty.Blue(t.Ing())
 	case Delete:
 		return pretty.Red(t.Ing())
+	case Replace:
+		return pretty.Magenta("%s (delete then create)", t.Ing())
+	case ReplaceCreateBeforeDelete:
+		return pretty.Magenta("%s (create before delete)", t.Ing())
+	case Retain:
+		return pretty.Yellow(t.Ing())
 	default:
 		return pretty.Normal(t.Ing())
 	}
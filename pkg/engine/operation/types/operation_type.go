@@ -9,4 +9,5 @@ const (
 	ApplyPreview
 	Destroy
 	DestroyPreview
+	Refresh
 )
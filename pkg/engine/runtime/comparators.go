@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"reflect"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+)
+
+// Comparator decides whether a resource in the actual infra has drifted from the planned one.
+// Runtimes usually fill in fields like status or defaulted values, so a semantic comparison
+// is needed to tell real changes from these server-populated fields
+type Comparator interface {
+	// Equal reports whether the live resource is equivalent to the plan resource.
+	// prior is the last applied resource saved in state storage and can be nil
+	Equal(prior, plan, live *models.Resource) (bool, error)
+}
+
+// ComparatorFunc is an adapter to allow the use of ordinary functions as Comparators
+type ComparatorFunc func(prior, plan, live *models.Resource) (bool, error)
+
+func (f ComparatorFunc) Equal(prior, plan, live *models.Resource) (bool, error) {
+	return f(prior, plan, live)
+}
+
+// Comparators contains Comparators keyed by the Type of resources they compare.
+// Resources without a registered Comparator are compared by DefaultComparator
+var Comparators = make(map[models.Type]Comparator)
+
+func AddToComparators(t models.Type, comparator Comparator) {
+	Comparators[t] = comparator
+}
+
+// DefaultComparator compares attributes of resources literally
+var DefaultComparator Comparator = ComparatorFunc(func(prior, plan, live *models.Resource) (bool, error) {
+	if plan == nil || live == nil {
+		return plan == live, nil
+	}
+	return reflect.DeepEqual(plan.Attributes, live.Attributes), nil
+})
+
+// GetComparator returns the Comparator of resources with specified Type
+func GetComparator(t models.Type) Comparator {
+	if c, ok := Comparators[t]; ok {
+		return c
+	}
+	return DefaultComparator
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/third_party/diff"
+)
+
+func init() {
+	AddToComparators(models.Kubernetes, &KubernetesComparator{})
+}
+
+// KubernetesComparator compares kubernetes objects by a three-way diff. The prior resource saved in state storage
+// is regarded as the last-applied configuration, so fields populated by the API server and controllers,
+// such as status, metadata.resourceVersion, metadata.managedFields and defaulted values, are not reported as changes
+type KubernetesComparator struct{}
+
+var _ Comparator = (*KubernetesComparator)(nil)
+
+func (c *KubernetesComparator) Equal(prior, plan, live *models.Resource) (bool, error) {
+	if plan == nil || live == nil {
+		return plan == live, nil
+	}
+
+	config, err := toUnstructured(plan.Attributes)
+	if err != nil {
+		return false, err
+	}
+	liveObj, err := toUnstructured(live.Attributes)
+	if err != nil {
+		return false, err
+	}
+	// fall back to a two-way diff if there is no last-applied configuration
+	orig := config.DeepCopy()
+	if prior != nil {
+		if orig, err = toUnstructured(prior.Attributes); err != nil {
+			return false, err
+		}
+		// status is meaningless in the last-applied configuration
+		unstructured.RemoveNestedField(orig.Object, "status")
+	}
+
+	diff.Normalize(config)
+	diff.Normalize(liveObj)
+	diff.Normalize(orig)
+	result, err := diff.ThreeWayDiff(orig, config, liveObj)
+	if err != nil {
+		return false, err
+	}
+	return !result.Modified, nil
+}
+
+// toUnstructured converts attributes to an Unstructured by a JSON round trip, which strips type information like
+// int vs. int64 and makes sure it only contains JSON compatible values
+func toUnstructured(attributes map[string]interface{}) (*unstructured.Unstructured, error) {
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+	obj := map[string]interface{}{}
+	if err = json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: obj}, nil
+}
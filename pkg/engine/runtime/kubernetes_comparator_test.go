@@ -0,0 +1,144 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+)
+
+func deploymentAttributes(replicas int, labels map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      "foo",
+			"namespace": "default",
+			"labels":    labels,
+		},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"app": "foo"},
+			},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"app": "foo"},
+				},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "foo", "image": "nginx:1.21"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// liveDeployment mocks fields populated by the API server and controllers
+func liveDeployment(attributes map[string]interface{}) map[string]interface{} {
+	obj, _ := toUnstructured(attributes)
+	live := obj.Object
+	metadata := live["metadata"].(map[string]interface{})
+	metadata["uid"] = "9f6b1c62-1f2a-4a5e-8a3c-2b1f4c7d9e10"
+	metadata["resourceVersion"] = "123456"
+	metadata["generation"] = 2
+	metadata["creationTimestamp"] = "2022-01-01T00:00:00Z"
+	metadata["managedFields"] = []interface{}{
+		map[string]interface{}{"manager": "kusion", "operation": "Update", "apiVersion": "apps/v1"},
+	}
+	spec := live["spec"].(map[string]interface{})
+	spec["revisionHistoryLimit"] = 10
+	spec["progressDeadlineSeconds"] = 600
+	spec["strategy"] = map[string]interface{}{"type": "RollingUpdate"}
+	container := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0]
+	container.(map[string]interface{})["imagePullPolicy"] = "IfNotPresent"
+	live["status"] = map[string]interface{}{"observedGeneration": 2, "replicas": 2, "availableReplicas": 2}
+	return live
+}
+
+func TestKubernetesComparator_Equal(t *testing.T) {
+	labels := map[string]interface{}{"app": "foo"}
+	applied := deploymentAttributes(2, labels)
+	crd := map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Foo",
+		"metadata":   map[string]interface{}{"name": "foo"},
+		"spec":       map[string]interface{}{"size": 1},
+	}
+	liveCRD := map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Foo",
+		"metadata":   map[string]interface{}{"name": "foo", "resourceVersion": "1", "uid": "abc"},
+		"spec":       map[string]interface{}{"size": 1, "mode": "default"},
+		"status":     map[string]interface{}{"phase": "Running"},
+	}
+
+	tests := []struct {
+		name  string
+		prior map[string]interface{}
+		plan  map[string]interface{}
+		live  map[string]interface{}
+		want  bool
+	}{
+		{
+			name:  "server-populated-fields",
+			prior: applied,
+			plan:  applied,
+			live:  liveDeployment(applied),
+			want:  true,
+		},
+		{
+			name:  "no-prior",
+			prior: nil,
+			plan:  applied,
+			live:  liveDeployment(applied),
+			want:  true,
+		},
+		{
+			name:  "replicas-changed",
+			prior: applied,
+			plan:  deploymentAttributes(3, labels),
+			live:  liveDeployment(applied),
+			want:  false,
+		},
+		{
+			name:  "label-removed",
+			prior: deploymentAttributes(2, map[string]interface{}{"app": "foo", "env": "test"}),
+			plan:  applied,
+			live:  liveDeployment(deploymentAttributes(2, map[string]interface{}{"app": "foo", "env": "test"})),
+			want:  false,
+		},
+		{
+			name:  "drift",
+			prior: applied,
+			plan:  applied,
+			live:  liveDeployment(deploymentAttributes(1, labels)),
+			want:  false,
+		},
+		{
+			name:  "custom-resource",
+			prior: crd,
+			plan:  crd,
+			live:  liveCRD,
+			want:  true,
+		},
+	}
+
+	c := &KubernetesComparator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prior *models.Resource
+			if tt.prior != nil {
+				prior = &models.Resource{ID: "foo", Attributes: tt.prior}
+			}
+			plan := &models.Resource{ID: "foo", Attributes: tt.plan}
+			live := &models.Resource{ID: "foo", Attributes: tt.live}
+
+			got, err := c.Equal(prior, plan, live)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
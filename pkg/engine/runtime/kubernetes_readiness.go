@@ -0,0 +1,191 @@
+package runtime
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// isReady reports whether the kubernetes object has reached its desired state, and returns a message describing
+// its current progress. Kinds without a well-known status are regarded as ready as soon as they exist
+func isReady(obj *unstructured.Unstructured) (bool, string) {
+	gvk := obj.GroupVersionKind()
+	switch gvk.GroupKind().String() {
+	case "Deployment.apps":
+		return isDeploymentReady(obj)
+	case "StatefulSet.apps":
+		return isStatefulSetReady(obj)
+	case "DaemonSet.apps":
+		return isDaemonSetReady(obj)
+	case "ReplicaSet.apps":
+		return isReplicaSetReady(obj)
+	case "Pod":
+		return isPodReady(obj)
+	case "Job.batch":
+		return isJobReady(obj)
+	case "PersistentVolumeClaim":
+		return isPersistentVolumeClaimReady(obj)
+	case "Service":
+		return isServiceReady(obj)
+	case "CustomResourceDefinition.apiextensions.k8s.io":
+		return isCustomResourceDefinitionReady(obj)
+	default:
+		return true, fmt.Sprintf("%s %s is ready", gvk.Kind, obj.GetName())
+	}
+}
+
+// isFailed reports whether the kubernetes object will never be ready unless it is changed, e.g. a failed Job
+func isFailed(obj *unstructured.Unstructured) bool {
+	switch obj.GroupVersionKind().GroupKind().String() {
+	case "Job.batch":
+		return hasTrueCondition(obj, "Failed")
+	default:
+		return false
+	}
+}
+
+func isDeploymentReady(obj *unstructured.Unstructured) (bool, string) {
+	if observed := observedGeneration(obj); observed < obj.GetGeneration() {
+		return false, fmt.Sprintf("waiting for deployment %s spec update to be observed", obj.GetName())
+	}
+
+	replicas := replicas(obj)
+	updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas")
+	if updated < replicas {
+		return false, fmt.Sprintf("waiting for deployment %s rollout: %d of %d new replicas have been updated",
+			obj.GetName(), updated, replicas)
+	}
+	available, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+	if available < replicas {
+		return false, fmt.Sprintf("waiting for deployment %s rollout: %d of %d updated replicas are available",
+			obj.GetName(), available, replicas)
+	}
+	return true, fmt.Sprintf("deployment %s successfully rolled out", obj.GetName())
+}
+
+func isStatefulSetReady(obj *unstructured.Unstructured) (bool, string) {
+	if observed := observedGeneration(obj); observed < obj.GetGeneration() {
+		return false, fmt.Sprintf("waiting for statefulset %s spec update to be observed", obj.GetName())
+	}
+
+	replicas := replicas(obj)
+	ready, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+	if ready < replicas {
+		return false, fmt.Sprintf("waiting for statefulset %s rollout: %d of %d pods are ready",
+			obj.GetName(), ready, replicas)
+	}
+	return true, fmt.Sprintf("statefulset %s successfully rolled out", obj.GetName())
+}
+
+func isDaemonSetReady(obj *unstructured.Unstructured) (bool, string) {
+	if observed := observedGeneration(obj); observed < obj.GetGeneration() {
+		return false, fmt.Sprintf("waiting for daemonset %s spec update to be observed", obj.GetName())
+	}
+
+	desired, _, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+	updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedNumberScheduled")
+	if updated < desired {
+		return false, fmt.Sprintf("waiting for daemonset %s rollout: %d of %d updated pods are scheduled",
+			obj.GetName(), updated, desired)
+	}
+	ready, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberReady")
+	if ready < desired {
+		return false, fmt.Sprintf("waiting for daemonset %s rollout: %d of %d pods are ready",
+			obj.GetName(), ready, desired)
+	}
+	return true, fmt.Sprintf("daemonset %s successfully rolled out", obj.GetName())
+}
+
+func isReplicaSetReady(obj *unstructured.Unstructured) (bool, string) {
+	replicas := replicas(obj)
+	ready, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+	if ready < replicas {
+		return false, fmt.Sprintf("waiting for replicaset %s: %d of %d pods are ready", obj.GetName(), ready, replicas)
+	}
+	return true, fmt.Sprintf("replicaset %s is ready", obj.GetName())
+}
+
+func isPodReady(obj *unstructured.Unstructured) (bool, string) {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if phase == "Succeeded" || hasTrueCondition(obj, "Ready") {
+		return true, fmt.Sprintf("pod %s is ready", obj.GetName())
+	}
+	return false, fmt.Sprintf("waiting for pod %s to be ready, current phase: %s", obj.GetName(), phase)
+}
+
+func isJobReady(obj *unstructured.Unstructured) (bool, string) {
+	if hasTrueCondition(obj, "Complete") {
+		return true, fmt.Sprintf("job %s is complete", obj.GetName())
+	}
+	if hasTrueCondition(obj, "Failed") {
+		return false, fmt.Sprintf("job %s is failed", obj.GetName())
+	}
+	return false, fmt.Sprintf("waiting for job %s to complete", obj.GetName())
+}
+
+func isPersistentVolumeClaimReady(obj *unstructured.Unstructured) (bool, string) {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if phase == "Bound" {
+		return true, fmt.Sprintf("persistentvolumeclaim %s is bound", obj.GetName())
+	}
+	return false, fmt.Sprintf("waiting for persistentvolumeclaim %s to be bound, current phase: %s", obj.GetName(), phase)
+}
+
+// storageClassName returns the name of the StorageClass requested by the PersistentVolumeClaim, which is specified
+// by spec.storageClassName or the deprecated beta annotation
+func storageClassName(obj *unstructured.Unstructured) string {
+	if name, found, _ := unstructured.NestedString(obj.Object, "spec", "storageClassName"); found {
+		return name
+	}
+	return obj.GetAnnotations()["volume.beta.kubernetes.io/storage-class"]
+}
+
+// isServiceReady regards a LoadBalancer service as ready even if no external address is assigned yet, since
+// provisioning the load balancer may depend on workloads applied after the service, or take long on some clouds
+func isServiceReady(obj *unstructured.Unstructured) (bool, string) {
+	serviceType, _, _ := unstructured.NestedString(obj.Object, "spec", "type")
+	if serviceType != "LoadBalancer" {
+		return true, fmt.Sprintf("service %s is ready", obj.GetName())
+	}
+	ingress, _, _ := unstructured.NestedSlice(obj.Object, "status", "loadBalancer", "ingress")
+	if len(ingress) == 0 {
+		return true, fmt.Sprintf("service %s is ready, its external address is not assigned yet", obj.GetName())
+	}
+	return true, fmt.Sprintf("service %s is ready", obj.GetName())
+}
+
+func isCustomResourceDefinitionReady(obj *unstructured.Unstructured) (bool, string) {
+	if hasTrueCondition(obj, "Established") {
+		return true, fmt.Sprintf("customresourcedefinition %s is established", obj.GetName())
+	}
+	return false, fmt.Sprintf("waiting for customresourcedefinition %s to be established", obj.GetName())
+}
+
+// replicas returns spec.replicas of the object, which is 1 by default
+func replicas(obj *unstructured.Unstructured) int64 {
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		return 1
+	}
+	return replicas
+}
+
+func observedGeneration(obj *unstructured.Unstructured) int64 {
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	return observed
+}
+
+// hasTrueCondition reports whether status.conditions of the object contains a condition with specified type and status True
+func hasTrueCondition(obj *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == conditionType && condition["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
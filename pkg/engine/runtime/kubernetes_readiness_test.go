@@ -0,0 +1,177 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_isReady(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  map[string]interface{}
+		want bool
+	}{
+		{
+			name: "configmap",
+			obj: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "foo"},
+			},
+			want: true,
+		},
+		{
+			name: "deployment-not-observed",
+			obj: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "foo", "generation": int64(2)},
+				"spec":       map[string]interface{}{"replicas": int64(2)},
+				"status": map[string]interface{}{
+					"observedGeneration": int64(1),
+					"updatedReplicas":    int64(2),
+					"availableReplicas":  int64(2),
+				},
+			},
+			want: false,
+		},
+		{
+			name: "deployment-unavailable",
+			obj: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "foo", "generation": int64(2)},
+				"spec":       map[string]interface{}{"replicas": int64(2)},
+				"status": map[string]interface{}{
+					"observedGeneration": int64(2),
+					"updatedReplicas":    int64(2),
+					"availableReplicas":  int64(1),
+				},
+			},
+			want: false,
+		},
+		{
+			name: "deployment-ready",
+			obj: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "foo", "generation": int64(2)},
+				"spec":       map[string]interface{}{"replicas": int64(2)},
+				"status": map[string]interface{}{
+					"observedGeneration": int64(2),
+					"updatedReplicas":    int64(2),
+					"availableReplicas":  int64(2),
+				},
+			},
+			want: true,
+		},
+		{
+			name: "pod-pending",
+			obj: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"metadata":   map[string]interface{}{"name": "foo"},
+				"status":     map[string]interface{}{"phase": "Pending"},
+			},
+			want: false,
+		},
+		{
+			name: "pod-ready",
+			obj: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"metadata":   map[string]interface{}{"name": "foo"},
+				"status": map[string]interface{}{
+					"phase": "Running",
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Ready", "status": "True"},
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "pvc-pending",
+			obj: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "PersistentVolumeClaim",
+				"metadata":   map[string]interface{}{"name": "foo"},
+				"status":     map[string]interface{}{"phase": "Pending"},
+			},
+			want: false,
+		},
+		{
+			name: "pvc-bound",
+			obj: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "PersistentVolumeClaim",
+				"metadata":   map[string]interface{}{"name": "foo"},
+				"status":     map[string]interface{}{"phase": "Bound"},
+			},
+			want: true,
+		},
+		{
+			name: "loadbalancer-service-pending",
+			obj: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata":   map[string]interface{}{"name": "foo"},
+				"spec":       map[string]interface{}{"type": "LoadBalancer"},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := isReady(&unstructured.Unstructured{Object: tt.obj})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_isFailed(t *testing.T) {
+	job := func(conditionType string) map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "Job",
+			"metadata":   map[string]interface{}{"name": "foo"},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": conditionType, "status": "True"},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		obj  map[string]interface{}
+		want bool
+	}{
+		{
+			name: "failed-job",
+			obj:  job("Failed"),
+			want: true,
+		},
+		{
+			name: "complete-job",
+			obj:  job("Complete"),
+			want: false,
+		},
+		{
+			name: "configmap",
+			obj: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "foo"},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isFailed(&unstructured.Unstructured{Object: tt.obj}))
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+// immutableFields are well-known fields that can't be changed after the object is created, keyed by the GroupKind.
+// Immutable fields of other kinds, including custom resources, are detected by a server-side dry run
+var immutableFields = map[schema.GroupKind][][]string{
+	{Group: "", Kind: "Service"}:               {{"spec", "clusterIP"}},
+	{Group: "", Kind: "PersistentVolumeClaim"}: {{"spec", "storageClassName"}, {"spec", "accessModes"}, {"spec", "volumeName"}},
+	{Group: "apps", Kind: "Deployment"}:        {{"spec", "selector"}},
+	{Group: "apps", Kind: "DaemonSet"}:         {{"spec", "selector"}},
+	{Group: "apps", Kind: "ReplicaSet"}:        {{"spec", "selector"}},
+	{Group: "apps", Kind: "StatefulSet"}: {
+		{"spec", "selector"},
+		{"spec", "serviceName"},
+		{"spec", "podManagementPolicy"},
+		{"spec", "volumeClaimTemplates"},
+	},
+	{Group: "batch", Kind: "Job"}: {{"spec", "selector"}, {"spec", "template"}, {"spec", "completionMode"}},
+}
+
+// RequiresReplace detects changes of immutable fields by the list of well-known immutable fields first, and then by a
+// server-side dry run of the patch, which covers immutable fields of any kind. The replacement always has the same
+// name as the replaced object, so it can't be created before the replaced object is deleted
+func (k *KubernetesRuntime) RequiresReplace(ctx context.Context, request *RequiresReplaceRequest) *RequiresReplaceResponse {
+	planState := request.PlanResource
+	liveState := request.LiveResource
+	// only existing resources can be replaced
+	if planState == nil || liveState == nil {
+		return &RequiresReplaceResponse{}
+	}
+
+	planObj, err := toUnstructured(planState.Attributes)
+	if err != nil {
+		return &RequiresReplaceResponse{Status: status.NewErrorStatus(err)}
+	}
+	liveObj, err := toUnstructured(liveState.Attributes)
+	if err != nil {
+		return &RequiresReplaceResponse{Status: status.NewErrorStatus(err)}
+	}
+	if reasons := immutableFieldChanges(planObj, liveObj); len(reasons) != 0 {
+		return &RequiresReplaceResponse{Replace: true, Reasons: reasons, SameIdentity: true}
+	}
+
+	obj, resource, err := k.buildKubernetesResourceByState(ctx, planState, false)
+	if err != nil {
+		return &RequiresReplaceResponse{Status: status.NewErrorStatus(err)}
+	}
+	err = mergePatch(ctx, resource, obj.GetName(), request.PriorResource, planState, liveState, true)
+	if reasons := immutableCauses(err); len(reasons) != 0 {
+		return &RequiresReplaceResponse{Replace: true, Reasons: reasons, SameIdentity: true}
+	}
+	if err != nil {
+		// other errors are not caused by immutable fields, and they will be reported when the resource is applied
+		log.Infof("dry run patching %s failed, regard it as updatable. %v", planState.ResourceKey(), err)
+	}
+	return &RequiresReplaceResponse{}
+}
+
+// immutableFieldChanges returns paths of well-known immutable fields specified in the plan object with values
+// different from the live object
+func immutableFieldChanges(plan, live *unstructured.Unstructured) []string {
+	var changes []string
+	for _, path := range immutableFields[plan.GroupVersionKind().GroupKind()] {
+		planValue, found, err := unstructured.NestedFieldNoCopy(plan.Object, path...)
+		if err != nil || !found {
+			continue
+		}
+		liveValue, _, _ := unstructured.NestedFieldNoCopy(live.Object, path...)
+		if !contains(liveValue, planValue) {
+			changes = append(changes, fmt.Sprintf("%s: field is immutable", strings.Join(path, ".")))
+		}
+	}
+	return changes
+}
+
+// contains reports whether all fields in the plan value are equal to the ones in the live value. Fields only in the
+// live value are populated by the API server or controllers, e.g. defaulted fields and labels, and they are ignored
+func contains(live, plan interface{}) bool {
+	switch p := plan.(type) {
+	case nil:
+		return live == nil
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return live == nil && len(p) == 0
+		}
+		for k, v := range p {
+			if !contains(l[k], v) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok {
+			return live == nil && len(p) == 0
+		}
+		if len(l) != len(p) {
+			return false
+		}
+		for i := range p {
+			if !contains(l[i], p[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(live, plan)
+	}
+}
+
+// immutableCauses returns fields rejected by the API server since they can't be updated
+func immutableCauses(err error) []string {
+	if err == nil || !k8serrors.IsInvalid(err) {
+		return nil
+	}
+	var apiStatus k8serrors.APIStatus
+	if !errors.As(err, &apiStatus) || apiStatus.Status().Details == nil {
+		return nil
+	}
+
+	var causes []string
+	for _, cause := range apiStatus.Status().Details.Causes {
+		// e.g. "field is immutable" of most fields, and "updates to statefulset spec for fields other than ... are forbidden"
+		if strings.Contains(cause.Message, "immutable") ||
+			(cause.Type == metav1.CauseTypeFieldValueForbidden && strings.Contains(cause.Message, "updates to")) {
+			causes = append(causes, fmt.Sprintf("%s: %s", cause.Field, cause.Message))
+		}
+	}
+	return causes
+}
@@ -0,0 +1,166 @@
+package runtime
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func Test_immutableFieldChanges(t *testing.T) {
+	service := func(spec map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"name": "foo"},
+			"spec":       spec,
+		}}
+	}
+	job := func(labels map[string]interface{}, image string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "Job",
+			"metadata":   map[string]interface{}{"name": "foo"},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"metadata": map[string]interface{}{"labels": labels},
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "foo", "image": image},
+						},
+					},
+				},
+			},
+		}}
+	}
+
+	tests := []struct {
+		name string
+		plan *unstructured.Unstructured
+		live *unstructured.Unstructured
+		want []string
+	}{
+		{
+			name: "cluster-ip-changed",
+			plan: service(map[string]interface{}{"clusterIP": "None"}),
+			live: service(map[string]interface{}{"clusterIP": "10.0.0.1", "type": "ClusterIP"}),
+			want: []string{"spec.clusterIP: field is immutable"},
+		},
+		{
+			name: "cluster-ip-not-specified",
+			plan: service(map[string]interface{}{"type": "ClusterIP"}),
+			live: service(map[string]interface{}{"clusterIP": "10.0.0.1", "type": "ClusterIP"}),
+		},
+		{
+			name: "job-template-with-populated-fields",
+			plan: job(map[string]interface{}{"app": "foo"}, "foo:v1"),
+			live: job(map[string]interface{}{"app": "foo", "job-name": "foo"}, "foo:v1"),
+		},
+		{
+			name: "job-template-changed",
+			plan: job(map[string]interface{}{"app": "foo"}, "foo:v2"),
+			live: job(map[string]interface{}{"app": "foo", "job-name": "foo"}, "foo:v1"),
+			want: []string{"spec.template: field is immutable"},
+		},
+		{
+			name: "kind-without-immutable-fields",
+			plan: &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "data": "a"}},
+			live: &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "data": "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, immutableFieldChanges(tt.plan, tt.live))
+		})
+	}
+}
+
+func Test_contains(t *testing.T) {
+	tests := []struct {
+		name string
+		live interface{}
+		plan interface{}
+		want bool
+	}{
+		{name: "equal-scalar", live: "a", plan: "a", want: true},
+		{name: "different-scalar", live: "a", plan: "b", want: false},
+		{name: "nil-plan-missing-in-live", live: nil, plan: nil, want: true},
+		{name: "empty-map-missing-in-live", live: nil, plan: map[string]interface{}{}, want: true},
+		{
+			name: "live-with-extra-fields",
+			live: map[string]interface{}{"a": "b", "c": "d"},
+			plan: map[string]interface{}{"a": "b"},
+			want: true,
+		},
+		{
+			name: "plan-with-extra-fields",
+			live: map[string]interface{}{"a": "b"},
+			plan: map[string]interface{}{"a": "b", "c": "d"},
+			want: false,
+		},
+		{
+			name: "list-with-different-length",
+			live: []interface{}{"a"},
+			plan: []interface{}{"a", "b"},
+			want: false,
+		},
+		{
+			name: "list-of-maps",
+			live: []interface{}{map[string]interface{}{"a": "b", "status": "c"}},
+			plan: []interface{}{map[string]interface{}{"a": "b"}},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, contains(tt.live, tt.plan))
+		})
+	}
+}
+
+func Test_immutableCauses(t *testing.T) {
+	gk := schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+	tests := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{
+			name: "nil",
+			err:  nil,
+		},
+		{
+			name: "not-invalid",
+			err:  errors.New("connection refused"),
+		},
+		{
+			name: "immutable",
+			err: k8serrors.NewInvalid(gk, "foo", field.ErrorList{
+				field.Invalid(field.NewPath("spec", "selector"), "foo", "field is immutable"),
+			}),
+			want: []string{`spec.selector: Invalid value: "foo": field is immutable`},
+		},
+		{
+			name: "forbidden-updates",
+			err: k8serrors.NewInvalid(gk, "foo", field.ErrorList{
+				field.Forbidden(field.NewPath("spec"), "updates to statefulset spec for fields other than 'replicas' are forbidden"),
+			}),
+			want: []string{"spec: Forbidden: updates to statefulset spec for fields other than 'replicas' are forbidden"},
+		},
+		{
+			name: "other-invalid-values",
+			err: k8serrors.NewInvalid(gk, "foo", field.ErrorList{
+				field.Required(field.NewPath("spec", "template"), ""),
+			}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, immutableCauses(tt.err))
+		})
+	}
+}
@@ -3,15 +3,22 @@ package runtime
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8syaml "k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
 	memory "k8s.io/client-go/discovery/cached"
 	"k8s.io/client-go/dynamic"
@@ -26,7 +33,40 @@ import (
 	"kusionstack.io/kusion/pkg/util/yaml"
 )
 
-var _ Runtime = (*KubernetesRuntime)(nil)
+var (
+	_ Runtime  = (*KubernetesRuntime)(nil)
+	_ Importer = (*KubernetesRuntime)(nil)
+	_ Replacer = (*KubernetesRuntime)(nil)
+)
+
+// fieldManager is the manager name kusion used to apply kubernetes resources
+const fieldManager = "kusion"
+
+// crdEstablishedTimeout is the max duration to wait for the CRD of a kind unknown by the REST mapper to be established
+const crdEstablishedTimeout = 30 * time.Second
+
+// crdEstablishedInterval is the interval to check whether the CRD is established
+var crdEstablishedInterval = time.Second
+
+// crdGVR is the resource of CRDs used to find the CRD of a kind. Only apiextensions.k8s.io/v1 is supported, which is
+// served since Kubernetes 1.16. On older clusters, listing CRDs fails, so kinds of CRDs created after the REST mapper
+// is initialized are not found until Kusion runs again
+var crdGVR = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// storageClassGVR is the resource of StorageClasses used to find the volume binding mode of PersistentVolumeClaims
+var storageClassGVR = schema.GroupVersionResource{
+	Group:    "storage.k8s.io",
+	Version:  "v1",
+	Resource: "storageclasses",
+}
+
+func init() {
+	AddToRuntimes(models.Kubernetes, NewKubernetesRuntime)
+}
 
 type KubernetesRuntime struct {
 	dyn    dynamic.Interface
@@ -57,10 +97,23 @@ func (k *KubernetesRuntime) Apply(ctx context.Context, request *ApplyRequest) *A
 	}
 
 	// Get kubernetes Resource interface from plan state
-	planObj, resource, err := k.buildKubernetesResourceByState(planState)
+	planObj, resource, err := k.buildKubernetesResourceByState(ctx, planState, true)
 	if err != nil {
 		return &ApplyResponse{nil, status.NewErrorStatus(err)}
 	}
+
+	// Server-side apply computes the patch in API server, which covers both create and update
+	if request.ServerSideApply {
+		if s := k.serverSideApply(ctx, resource, planObj, request.DryRun, request.ForceConflicts); status.IsErr(s) {
+			return &ApplyResponse{nil, s}
+		}
+		return &ApplyResponse{&models.Resource{
+			ID:         planState.ResourceKey(),
+			Attributes: planObj.Object,
+			DependsOn:  planState.DependsOn,
+		}, nil}
+	}
+
 	// Get live state
 	response := k.Read(ctx, &ReadRequest{planState})
 	liveState := response.Resource
@@ -71,28 +124,11 @@ func (k *KubernetesRuntime) Apply(ctx context.Context, request *ApplyRequest) *A
 
 	// LiveState is nil, fall back to create planObj directly
 	if liveState == nil {
-		if _, err = resource.Create(ctx, planObj, metav1.CreateOptions{}); err != nil {
-			return &ApplyResponse{nil, status.NewErrorStatus(err)}
-		}
-	} else {
-		// Original equals to last-applied from annotation, kusion store it in kusion_state.json
-		original := ""
-		if priorState != nil {
-			original = json.MustMarshal2String(priorState.Attributes)
-		}
-		// Modified equals input content
-		modified := json.MustMarshal2String(planState.Attributes)
-		// Current equals live manifest
-		current := json.MustMarshal2String(liveState.Attributes)
-		// 3-way json merge patch
-		patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch([]byte(original), []byte(modified), []byte(current))
-		if err != nil {
-			return &ApplyResponse{nil, status.NewErrorStatus(err)}
-		}
-		// Apply patch
-		if _, err = resource.Patch(ctx, planObj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{FieldManager: "kusion"}); err != nil {
+		if _, err = resource.Create(ctx, planObj, metav1.CreateOptions{DryRun: dryRunOption(request.DryRun)}); err != nil {
 			return &ApplyResponse{nil, status.NewErrorStatus(err)}
 		}
+	} else if err = mergePatch(ctx, resource, planObj.GetName(), priorState, planState, liveState, request.DryRun); err != nil {
+		return &ApplyResponse{nil, status.NewErrorStatus(err)}
 	}
 
 	return &ApplyResponse{&models.Resource{
@@ -102,6 +138,82 @@ func (k *KubernetesRuntime) Apply(ctx context.Context, request *ApplyRequest) *A
 	}, nil}
 }
 
+// mergePatch patches the live object by a three-way json merge patch among the prior, plan and live resources
+func mergePatch(ctx context.Context, resource dynamic.ResourceInterface, name string,
+	priorState, planState, liveState *models.Resource, dryRun bool,
+) error {
+	// Original equals to last-applied from annotation, kusion store it in kusion_state.json
+	original := ""
+	if priorState != nil {
+		original = json.MustMarshal2String(priorState.Attributes)
+	}
+	// Modified equals input content
+	modified := json.MustMarshal2String(planState.Attributes)
+	// Current equals live manifest
+	current := json.MustMarshal2String(liveState.Attributes)
+	// 3-way json merge patch
+	patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch([]byte(original), []byte(modified), []byte(current))
+	if err != nil {
+		return err
+	}
+	// Apply patch
+	_, err = resource.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{
+		FieldManager: fieldManager,
+		DryRun:       dryRunOption(dryRun),
+	})
+	return err
+}
+
+// serverSideApply applies the object with server-side apply. Field ownership conflicts are converted to a status with Conflict code
+func (k *KubernetesRuntime) serverSideApply(ctx context.Context, resource dynamic.ResourceInterface,
+	obj *unstructured.Unstructured, dryRun, force bool,
+) status.Status {
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return status.NewErrorStatus(err)
+	}
+	_, err = resource.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        &force,
+		DryRun:       dryRunOption(dryRun),
+	})
+	if err != nil {
+		if k8serrors.IsConflict(err) {
+			return conflictStatus(obj, err)
+		}
+		return status.NewErrorStatus(err)
+	}
+	return nil
+}
+
+// dryRunOption returns the DryRun option of kubernetes requests. Server-side dry run requests go through admission
+// and validation as real ones, but will not be persisted
+func dryRunOption(dryRun bool) []string {
+	if dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+// conflictStatus lists fields and their managers in conflict with kusion, so that users can decide whether to force it
+func conflictStatus(obj *unstructured.Unstructured, err error) status.Status {
+	var conflicts []string
+	var apiStatus k8serrors.APIStatus
+	if errors.As(err, &apiStatus) {
+		if details := apiStatus.Status().Details; details != nil {
+			for _, cause := range details.Causes {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %s", cause.Field, cause.Message))
+			}
+		}
+	}
+	if len(conflicts) == 0 {
+		conflicts = append(conflicts, err.Error())
+	}
+	msg := fmt.Sprintf("apply %s %s conflicts with other field managers, use --force-conflicts to take the ownership:\n%s",
+		obj.GetKind(), obj.GetName(), strings.Join(conflicts, "\n"))
+	return status.NewErrorStatusWithMsg(status.Conflict, msg)
+}
+
 // Read kubernetes Resource by client-go
 func (k *KubernetesRuntime) Read(ctx context.Context, request *ReadRequest) *ReadResponse {
 	requestResource := request.Resource
@@ -110,9 +222,13 @@ func (k *KubernetesRuntime) Read(ctx context.Context, request *ReadRequest) *Rea
 		return &ReadResponse{nil, status.NewErrorStatus(errors.New("requestResource is nil"))}
 	}
 
-	// Get resource by attribute
-	obj, resource, err := k.buildKubernetesResourceByState(requestResource)
+	// Get resource by attribute. The CRD of the kind is not waited for, since no object of a kind without CRDs exists
+	obj, resource, err := k.buildKubernetesResourceByState(ctx, requestResource, false)
 	if err != nil {
+		if meta.IsNoMatchError(err) {
+			log.Infof("kind of %s is not found, regard it as not existing. %v", requestResource.ResourceKey(), err)
+			return &ReadResponse{nil, nil}
+		}
 		return &ReadResponse{nil, status.NewErrorStatus(err)}
 	}
 
@@ -133,6 +249,32 @@ func (k *KubernetesRuntime) Read(ctx context.Context, request *ReadRequest) *Rea
 	}, nil}
 }
 
+// Import reads the live kubernetes object and drops fields populated by the API server, so that the result can be used
+// as the last-applied configuration in the next three-way merge
+func (k *KubernetesRuntime) Import(ctx context.Context, request *ImportRequest) *ImportResponse {
+	response := k.Read(ctx, &ReadRequest{Resource: request.PlanResource})
+	if status.IsErr(response.Status) || response.Resource == nil {
+		return &ImportResponse{nil, response.Status}
+	}
+
+	obj := &unstructured.Unstructured{Object: response.Resource.Attributes}
+	unstructured.RemoveNestedField(obj.Object, "status")
+	for _, field := range []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "selfLink"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		delete(annotations, corev1.LastAppliedConfigAnnotation)
+		obj.SetAnnotations(annotations)
+	}
+
+	return &ImportResponse{&models.Resource{
+		ID:         response.Resource.ID,
+		Type:       request.PlanResource.Type,
+		Attributes: obj.Object,
+		DependsOn:  response.Resource.DependsOn,
+	}, nil}
+}
+
 // Delete kubernetes Resource by client-go
 func (k *KubernetesRuntime) Delete(ctx context.Context, request *DeleteRequest) *DeleteResponse {
 	requestResource := request.Resource
@@ -142,13 +284,17 @@ func (k *KubernetesRuntime) Delete(ctx context.Context, request *DeleteRequest)
 	}
 
 	// Get Resource by attribute
-	obj, resource, err := k.buildKubernetesResourceByState(requestResource)
+	obj, resource, err := k.buildKubernetesResourceByState(ctx, requestResource, true)
 	if err != nil {
 		return &DeleteResponse{status.NewErrorStatus(err)}
 	}
 
-	// Delete Resource
-	err = resource.Delete(ctx, obj.GetName(), metav1.DeleteOptions{})
+	// Delete Resource, and dependents like pods of Jobs are deleted by the garbage collector in the background as kubectl does
+	propagation := metav1.DeletePropagationBackground
+	err = resource.Delete(ctx, obj.GetName(), metav1.DeleteOptions{
+		DryRun:            dryRunOption(request.DryRun),
+		PropagationPolicy: &propagation,
+	})
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			log.Infof("%s not found, ignore", requestResource.ResourceKey())
@@ -160,9 +306,124 @@ func (k *KubernetesRuntime) Delete(ctx context.Context, request *DeleteRequest)
 	return &DeleteResponse{nil}
 }
 
-// Watch kubernetes resource by client-go
+// Watch kubernetes resource by client-go. Every state change of this resource will be sent to the ResultChan
+// until the resource is ready or the ctx is done
 func (k *KubernetesRuntime) Watch(ctx context.Context, request *WatchRequest) *WatchResponse {
-	panic("need implement")
+	requestResource := request.Resource
+	// Validate
+	if requestResource == nil {
+		return &WatchResponse{nil, status.NewErrorStatus(errors.New("requestResource is nil"))}
+	}
+
+	// Get Resource by attribute
+	obj, resource, err := k.buildKubernetesResourceByState(ctx, requestResource, true)
+	if err != nil {
+		return &WatchResponse{nil, status.NewErrorStatus(err)}
+	}
+
+	resultCh := make(chan WatchEvent)
+	checkReady := func(live *unstructured.Unstructured) (bool, string) {
+		return k.checkReady(ctx, live)
+	}
+	go watchUntilReady(ctx, resource, obj.GetName(), requestResource, resultCh, checkReady)
+
+	return &WatchResponse{resultCh, nil}
+}
+
+// checkReady reports whether the object is ready as isReady does, except that a pending PersistentVolumeClaim is
+// regarded as ready if it is bound only once a pod using it is scheduled. Such pods are applied after the claim,
+// so waiting for the claim to be bound blocks them forever
+func (k *KubernetesRuntime) checkReady(ctx context.Context, obj *unstructured.Unstructured) (bool, string) {
+	ready, msg := isReady(obj)
+	if ready || obj.GroupVersionKind().GroupKind().String() != "PersistentVolumeClaim" {
+		return ready, msg
+	}
+	if phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); phase != "Pending" {
+		return ready, msg
+	}
+	name := storageClassName(obj)
+	if name == "" {
+		return ready, msg
+	}
+	sc, err := k.dyn.Resource(storageClassGVR).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		log.Warnf("failed to get storageclass %s of persistentvolumeclaim %s: %v", name, obj.GetName(), err)
+		return ready, msg
+	}
+	if mode, _, _ := unstructured.NestedString(sc.Object, "volumeBindingMode"); mode == "WaitForFirstConsumer" {
+		return true, fmt.Sprintf("persistentvolumeclaim %s is ready, it will be bound once a pod using it is scheduled",
+			obj.GetName())
+	}
+	return ready, msg
+}
+
+// watchUntilReady watches the object with specified name and converts its events into WatchEvent, whose readiness
+// is checked by checkReady. The watch will be re-established if it is closed by the server before the object is ready
+func watchUntilReady(ctx context.Context, resource dynamic.ResourceInterface, name string,
+	requestResource *models.Resource, resultCh chan<- WatchEvent,
+	checkReady func(*unstructured.Unstructured) (bool, string),
+) {
+	defer close(resultCh)
+
+	send := func(event WatchEvent) bool {
+		select {
+		case resultCh <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		w, err := resource.Watch(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+		})
+		if err != nil {
+			send(WatchEvent{Message: fmt.Sprintf("failed to watch %s: %v", requestResource.ResourceKey(), err)})
+			return
+		}
+
+		for event := range w.ResultChan() {
+			var e WatchEvent
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				obj, ok := event.Object.(*unstructured.Unstructured)
+				if !ok {
+					continue
+				}
+				ready, msg := checkReady(obj)
+				e = WatchEvent{
+					Resource: &models.Resource{
+						ID:         requestResource.ResourceKey(),
+						Type:       requestResource.Type,
+						Attributes: obj.Object,
+						DependsOn:  requestResource.DependsOn,
+					},
+					Ready:   ready,
+					Failed:  isFailed(obj),
+					Message: msg,
+				}
+			case watch.Deleted:
+				e = WatchEvent{Message: fmt.Sprintf("%s has been deleted", requestResource.ResourceKey())}
+			case watch.Error:
+				e = WatchEvent{Message: k8serrors.FromObject(event.Object).Error()}
+			default:
+				continue
+			}
+
+			if !send(e) || e.Ready || e.Failed {
+				w.Stop()
+				return
+			}
+		}
+
+		// The result channel is closed by the server or the ctx is done
+		w.Stop()
+		if ctx.Err() != nil {
+			return
+		}
+		log.Infof("watch of %s is closed by server, restart it", requestResource.ResourceKey())
+	}
 }
 
 // getKubernetesClient get kubernetes client
@@ -190,8 +451,11 @@ func getKubernetesClient() (dynamic.Interface, *restmapper.DeferredDiscoveryREST
 	return dyn, mapper, nil
 }
 
-// buildKubernetesResourceByState get resource by attribute
-func (k *KubernetesRuntime) buildKubernetesResourceByState(resourceState *models.Resource) (*unstructured.Unstructured, dynamic.ResourceInterface, error) {
+// buildKubernetesResourceByState get resource by attribute. waitCRD decides whether to wait for the CRD of a kind
+// unknown by the REST mapper to be established, see restMapping
+func (k *KubernetesRuntime) buildKubernetesResourceByState(ctx context.Context, resourceState *models.Resource,
+	waitCRD bool,
+) (*unstructured.Unstructured, dynamic.ResourceInterface, error) {
 	// Convert interface{} to unstructured
 	attribute := resourceState.Attributes
 	rYaml := yaml.MergeToOneYAML(attribute)
@@ -201,27 +465,85 @@ func (k *KubernetesRuntime) buildKubernetesResourceByState(resourceState *models
 		return nil, nil, err
 	}
 
-	// Get resource by unstructured
-	var resource dynamic.ResourceInterface
-
-	resource, err = buildKubernetesResourceByUnstructured(k.dyn, k.mapper, obj, gvk)
+	// Find GVR
+	mapping, err := k.restMapping(ctx, gvk, waitCRD)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return obj, resource, nil
+	// Get resource by unstructured
+	return obj, buildKubernetesResourceByUnstructured(k.dyn, mapping, obj), nil
 }
 
-// buildKubernetesResourceByUnstructured get resource by unstructured object
-func buildKubernetesResourceByUnstructured(dyn dynamic.Interface, mapper *restmapper.DeferredDiscoveryRESTMapper,
-	obj *unstructured.Unstructured, gvk *schema.GroupVersionKind,
-) (dynamic.ResourceInterface, error) {
-	// Find GVR
-	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+// restMapping finds the REST mapping of the kind. The mapper caches kinds discovered when the runtime is created, so
+// kinds of CRDs created after that, e.g. by the same apply, are not matched. In that case, it resets the mapper and
+// retries once the CRD of the kind is established. With waitCRD, it waits for the CRD to be established, otherwise
+// the CRD is checked only once
+func (k *KubernetesRuntime) restMapping(ctx context.Context, gvk *schema.GroupVersionKind, waitCRD bool,
+) (*meta.RESTMapping, error) {
+	mapping, err := k.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	var noKindMatch *meta.NoKindMatchError
+	if err == nil || !errors.As(err, &noKindMatch) {
+		return mapping, err
+	}
+
+	log.Infof("kind %s is not found by the REST mapper, check its CRD", gvk.String())
+	waitCtx, cancel := context.WithTimeout(ctx, crdEstablishedTimeout)
+	defer cancel()
+	condition := func() (bool, error) {
+		found, established, listErr := k.crdEstablished(waitCtx, gvk.GroupKind())
+		if listErr != nil {
+			log.Infof("failed to list CRDs of kind %s. %v", gvk.String(), listErr)
+			return true, nil
+		}
+		if !found {
+			// the kind is not defined by any CRD, so the mapper won't find it after waiting
+			return true, nil
+		}
+		if !established {
+			return false, nil
+		}
+		// the API server may serve the discovery of the new kind a little later than the CRD is established
+		k.mapper.Reset()
+		mapping, err = k.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		return err == nil, nil
+	}
+	if !waitCRD {
+		_, _ = condition()
+		return mapping, err
+	}
+
+	pollErr := wait.PollImmediateUntil(crdEstablishedInterval, condition, waitCtx.Done())
+	if err != nil && errors.Is(pollErr, wait.ErrWaitTimeout) {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("stop waiting for the CRD of kind %s: %w", gvk.String(), ctx.Err())
+		}
+		return nil, fmt.Errorf("%w, and its CRD is not established and served in %s", err, crdEstablishedTimeout)
+	}
+	return mapping, err
+}
+
+// crdEstablished reports whether a CRD defines the kind, and whether the CRD is established
+func (k *KubernetesRuntime) crdEstablished(ctx context.Context, gk schema.GroupKind) (found, established bool, err error) {
+	crds, err := k.dyn.Resource(crdGVR).List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return nil, err
+		return false, false, err
+	}
+	for i := range crds.Items {
+		crd := &crds.Items[i]
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+		if group == gk.Group && kind == gk.Kind {
+			return true, hasTrueCondition(crd, "Established"), nil
+		}
 	}
+	return false, false, nil
+}
 
+// buildKubernetesResourceByUnstructured get resource by unstructured object
+func buildKubernetesResourceByUnstructured(dyn dynamic.Interface, mapping *meta.RESTMapping,
+	obj *unstructured.Unstructured,
+) dynamic.ResourceInterface {
 	// Obtain REST interface for the GVR
 	var dr dynamic.ResourceInterface
 	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
@@ -232,7 +554,7 @@ func buildKubernetesResourceByUnstructured(dyn dynamic.Interface, mapper *restma
 		dr = dyn.Resource(mapping.Resource)
 	}
 
-	return dr, nil
+	return dr
 }
 
 // convertString2Unstructured convert string to unstructured object
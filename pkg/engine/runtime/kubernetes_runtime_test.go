@@ -0,0 +1,357 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	memory "k8s.io/client-go/discovery/cached"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/restmapper"
+	k8stesting "k8s.io/client-go/testing"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+func Test_conflictStatus(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "foo"},
+	}}
+
+	tests := []struct {
+		name     string
+		err      error
+		contains []string
+	}{
+		{
+			name: "apply-conflict",
+			err: k8serrors.NewApplyConflict([]metav1.StatusCause{
+				{
+					Type:    metav1.CauseTypeFieldManagerConflict,
+					Message: `conflict with "kubectl"`,
+					Field:   ".spec.replicas",
+				},
+			}, "Apply failed with 1 conflict"),
+			contains: []string{"Deployment foo", `.spec.replicas: conflict with "kubectl"`},
+		},
+		{
+			name:     "no-details",
+			err:      errors.New("conflict"),
+			contains: []string{"Deployment foo", "conflict"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := conflictStatus(obj, tt.err)
+			assert.Equal(t, status.Conflict, s.Code())
+			for _, c := range tt.contains {
+				assert.Contains(t, s.Message(), c)
+			}
+		})
+	}
+}
+
+func TestKubernetesRuntime_restMapping(t *testing.T) {
+	defer func(interval time.Duration) { crdEstablishedInterval = interval }(crdEstablishedInterval)
+	crdEstablishedInterval = 10 * time.Millisecond
+	fooGVK := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Foo"}
+	cmGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	coreResources := &metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+	}
+	fooResources := &metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{{Name: "foos", Kind: "Foo", Namespaced: true}},
+	}
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "foos.example.com"},
+		"spec": map[string]interface{}{
+			"group": "example.com",
+			"names": map[string]interface{}{"kind": "Foo", "plural": "foos"},
+		},
+		"status": map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Established", "status": "True"}},
+		},
+	}}
+
+	notEstablished := crd.DeepCopy()
+	unstructured.RemoveNestedField(notEstablished.Object, "status")
+
+	tests := []struct {
+		name         string
+		gvk          schema.GroupVersionKind
+		crds         []k8sruntime.Object
+		noWait       bool
+		canceled     bool
+		wantResource string
+		wantErr      func(err error) bool
+	}{
+		{
+			name:         "cached kind",
+			gvk:          cmGVK,
+			wantResource: "configmaps",
+		},
+		{
+			name:         "kind of established crd",
+			gvk:          fooGVK,
+			crds:         []k8sruntime.Object{crd},
+			wantResource: "foos",
+		},
+		{
+			name:         "kind of established crd without waiting",
+			gvk:          fooGVK,
+			crds:         []k8sruntime.Object{crd},
+			noWait:       true,
+			wantResource: "foos",
+		},
+		{
+			name:    "kind without crd",
+			gvk:     fooGVK,
+			wantErr: meta.IsNoMatchError,
+		},
+		{
+			name:    "kind of crd not established without waiting",
+			gvk:     fooGVK,
+			crds:    []k8sruntime.Object{notEstablished},
+			noWait:  true,
+			wantErr: meta.IsNoMatchError,
+		},
+		{
+			name:     "canceled while waiting for crd",
+			gvk:      fooGVK,
+			crds:     []k8sruntime.Object{notEstablished},
+			canceled: true,
+			wantErr: func(err error) bool {
+				return errors.Is(err, context.Canceled)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dc := &discoveryfake.FakeDiscovery{Fake: &k8stesting.Fake{
+				Resources: []*metav1.APIResourceList{coreResources},
+			}}
+			k := &KubernetesRuntime{
+				dyn: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(k8sruntime.NewScheme(),
+					map[schema.GroupVersionResource]string{crdGVR: "CustomResourceDefinitionList"}, tt.crds...),
+				mapper: restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc)),
+			}
+			// cache kinds discovered before the crd is created
+			_, err := k.mapper.RESTMapping(cmGVK.GroupKind(), cmGVK.Version)
+			assert.NoError(t, err)
+			if len(tt.crds) != 0 {
+				dc.Resources = append(dc.Resources, fooResources)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.canceled {
+				cancel()
+			}
+
+			mapping, err := k.restMapping(ctx, &tt.gvk, !tt.noWait)
+			if tt.wantErr != nil {
+				assert.True(t, tt.wantErr(err), "unexpected error: %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantResource, mapping.Resource.Resource)
+		})
+	}
+}
+
+func TestKubernetesRuntime_PreviewAndApplyCRDAndCR(t *testing.T) {
+	fooGVR := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "foos"}
+	crdResources := &metav1.APIResourceList{
+		GroupVersion: "apiextensions.k8s.io/v1",
+		APIResources: []metav1.APIResource{{Name: "customresourcedefinitions", Kind: "CustomResourceDefinition"}},
+	}
+	fooResources := &metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{{Name: "foos", Kind: "Foo", Namespaced: true}},
+	}
+	crd := &models.Resource{
+		ID:   "apiextensions.k8s.io/v1:CustomResourceDefinition:foos.example.com",
+		Type: models.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": "apiextensions.k8s.io/v1",
+			"kind":       "CustomResourceDefinition",
+			"metadata":   map[string]interface{}{"name": "foos.example.com"},
+			"spec": map[string]interface{}{
+				"group": "example.com",
+				"names": map[string]interface{}{"kind": "Foo", "plural": "foos"},
+			},
+			// set by the API server once the CRD is created
+			"status": map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"type": "Established", "status": "True"}},
+			},
+		},
+	}
+	cr := &models.Resource{
+		ID:   "example.com/v1:Foo:default:foo",
+		Type: models.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Foo",
+			"metadata":   map[string]interface{}{"name": "foo", "namespace": "default"},
+		},
+	}
+
+	dc := &discoveryfake.FakeDiscovery{Fake: &k8stesting.Fake{
+		Resources: []*metav1.APIResourceList{crdResources},
+	}}
+	k := &KubernetesRuntime{
+		dyn: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(k8sruntime.NewScheme(),
+			map[schema.GroupVersionResource]string{crdGVR: "CustomResourceDefinitionList", fooGVR: "FooList"}),
+		mapper: restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc)),
+	}
+	ctx := context.Background()
+
+	// preview: neither the CRD nor the CR exists, so both of them are to be created
+	for _, res := range []*models.Resource{crd, cr} {
+		response := k.Read(ctx, &ReadRequest{Resource: res})
+		assert.Nil(t, response.Status)
+		assert.Nil(t, response.Resource)
+	}
+
+	// apply: the kind of the CR is served once the CRD is created
+	applyResponse := k.Apply(ctx, &ApplyRequest{PlanResource: crd})
+	assert.Nil(t, applyResponse.Status)
+	dc.Resources = append(dc.Resources, fooResources)
+	applyResponse = k.Apply(ctx, &ApplyRequest{PlanResource: cr})
+	assert.Nil(t, applyResponse.Status)
+
+	readResponse := k.Read(ctx, &ReadRequest{Resource: cr})
+	assert.Nil(t, readResponse.Status)
+	if assert.NotNil(t, readResponse.Resource) {
+		assert.Equal(t, "foo", readResponse.Resource.Attributes["metadata"].(map[string]interface{})["name"])
+	}
+}
+
+func TestKubernetesRuntime_WatchPersistentVolumeClaimAndDeployment(t *testing.T) {
+	pvcGVR := schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumeclaims"}
+	dc := &discoveryfake.FakeDiscovery{Fake: &k8stesting.Fake{
+		Resources: []*metav1.APIResourceList{
+			{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{{Name: "persistentvolumeclaims", Kind: "PersistentVolumeClaim", Namespaced: true}},
+			},
+			{
+				GroupVersion: "apps/v1",
+				APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment", Namespaced: true}},
+			},
+		},
+	}}
+	storageClass := func(name, mode string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion":        "storage.k8s.io/v1",
+			"kind":              "StorageClass",
+			"metadata":          map[string]interface{}{"name": name},
+			"volumeBindingMode": mode,
+		}}
+	}
+	pvc := func(storageClassName string) *models.Resource {
+		return &models.Resource{
+			ID:   "v1:PersistentVolumeClaim:default:data",
+			Type: models.Kubernetes,
+			Attributes: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "PersistentVolumeClaim",
+				"metadata":   map[string]interface{}{"name": "data", "namespace": "default"},
+				"spec":       map[string]interface{}{"storageClassName": storageClassName},
+				// set by the API server, the claim is not bound before a pod using it is scheduled
+				"status": map[string]interface{}{"phase": "Pending"},
+			},
+		}
+	}
+	deployment := &models.Resource{
+		ID:   "apps/v1:Deployment:default:app",
+		Type: models.Kubernetes,
+		Attributes: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "app", "namespace": "default"},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"volumes": []interface{}{
+							map[string]interface{}{
+								"name":                  "data",
+								"persistentVolumeClaim": map[string]interface{}{"claimName": "data"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		pvc       *models.Resource
+		wantReady bool
+	}{
+		{
+			name:      "binding deferred to the first consumer",
+			pvc:       pvc("local"),
+			wantReady: true,
+		},
+		{
+			name:      "immediate binding",
+			pvc:       pvc("standard"),
+			wantReady: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dyn := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme(),
+				storageClass("local", "WaitForFirstConsumer"), storageClass("standard", "Immediate"))
+			k := &KubernetesRuntime{
+				dyn:    dyn,
+				mapper: restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc)),
+			}
+			// the watch sends the current claim once it is established
+			w := watch.NewFakeWithChanSize(1, false)
+			defer w.Stop()
+			dyn.PrependWatchReactor("persistentvolumeclaims", func(action k8stesting.Action) (bool, watch.Interface, error) {
+				obj, err := dyn.Tracker().Get(pvcGVR, "default", "data")
+				if err != nil {
+					return true, nil, err
+				}
+				w.Modify(obj)
+				return true, w, nil
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// the claim is applied and watched before the deployment, as DefaultKindOrder orders them
+			applyResponse := k.Apply(ctx, &ApplyRequest{PlanResource: tt.pvc})
+			assert.Nil(t, applyResponse.Status)
+			watchResponse := k.Watch(ctx, &WatchRequest{Resource: tt.pvc})
+			assert.Nil(t, watchResponse.Status)
+			event := <-watchResponse.ResultChan
+			assert.Equal(t, tt.wantReady, event.Ready, event.Message)
+			if !event.Ready {
+				return
+			}
+
+			applyResponse = k.Apply(ctx, &ApplyRequest{PlanResource: deployment})
+			assert.Nil(t, applyResponse.Status)
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+var _ runtime.Runtime = (*Client)(nil)
+
+// Client is a runtime.Runtime which delegates all requests to a plugin
+type Client struct {
+	t       models.Type
+	version int
+
+	writeLock sync.Mutex
+	encoder   *json.Encoder
+	closer    io.Closer
+
+	lock    sync.Mutex
+	nextID  uint64
+	pending map[uint64]*pendingRequest
+	// err is set when the connection to the plugin is broken
+	err error
+}
+
+// pendingRequest receives responses of a request until the request is done or canceled
+type pendingRequest struct {
+	responses chan *Response
+	// done is closed when Kusion stops waiting for responses of this request
+	done chan struct{}
+}
+
+// NewClient starts the plugin executable as a child process and finishes the handshake with it.
+// The plugin exits when the Client is closed or Kusion exits
+func NewClient(path string, t models.Type) (*Client, error) {
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", MagicCookieKey, MagicCookieValue))
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start runtime plugin %s: %w", path, err)
+	}
+
+	// redirect logs of the plugin to the log of Kusion
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Infof("[plugin %s] %s", t, scanner.Text())
+		}
+	}()
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			log.Warnf("runtime plugin %s exited: %v", path, err)
+		}
+	}()
+
+	c := newClient(t, stdout, stdin)
+	if err = c.handshake(); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("failed to handshake with runtime plugin %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func newClient(t models.Type, in io.Reader, out io.WriteCloser) *Client {
+	c := &Client{
+		t:       t,
+		encoder: json.NewEncoder(out),
+		closer:  out,
+		pending: map[uint64]*pendingRequest{},
+	}
+	go c.readLoop(in)
+	return c
+}
+
+// Close closes the stdin of the plugin and the plugin should exit after all running requests are finished
+func (c *Client) Close() error {
+	return c.closer.Close()
+}
+
+// Apply the resource by the plugin
+func (c *Client) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	result := &ResourceResponse{}
+	err := c.call(ctx, MethodApply, &ApplyRequest{
+		PriorResource:   request.PriorResource,
+		PlanResource:    request.PlanResource,
+		DryRun:          request.DryRun,
+		ServerSideApply: request.ServerSideApply,
+		ForceConflicts:  request.ForceConflicts,
+	}, result)
+	if err != nil {
+		return &runtime.ApplyResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+	return &runtime.ApplyResponse{Resource: result.Resource, Status: result.Status.toStatus()}
+}
+
+// Read the resource by the plugin
+func (c *Client) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	result := &ResourceResponse{}
+	if err := c.call(ctx, MethodRead, &ResourceRequest{Resource: request.Resource}, result); err != nil {
+		return &runtime.ReadResponse{Resource: nil, Status: status.NewErrorStatus(err)}
+	}
+	return &runtime.ReadResponse{Resource: result.Resource, Status: result.Status.toStatus()}
+}
+
+// Delete the resource by the plugin
+func (c *Client) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	result := &StatusResponse{}
+	if err := c.call(ctx, MethodDelete, &DeleteRequest{Resource: request.Resource, DryRun: request.DryRun}, result); err != nil {
+		return &runtime.DeleteResponse{Status: status.NewErrorStatus(err)}
+	}
+	return &runtime.DeleteResponse{Status: result.Status.toStatus()}
+}
+
+// Watch the resource by the plugin. Events are streamed until the plugin finishes the watch or the ctx is done
+func (c *Client) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	id, p, err := c.send(MethodWatch, &ResourceRequest{Resource: request.Resource})
+	if err != nil {
+		return &runtime.WatchResponse{Status: status.NewErrorStatus(err)}
+	}
+
+	// the first response tells whether the watch is started
+	first := &WatchResponse{}
+	response, err := c.receive(ctx, id, p)
+	if err == nil {
+		err = decodeResult(response, first)
+	}
+	if err != nil {
+		c.cancel(id)
+		return &runtime.WatchResponse{Status: status.NewErrorStatus(err)}
+	}
+	if first.Unsupported {
+		return nil
+	}
+	if response.Done {
+		return &runtime.WatchResponse{Status: first.Status.toStatus()}
+	}
+
+	resultCh := make(chan runtime.WatchEvent)
+	go func() {
+		defer close(resultCh)
+		for {
+			response, err := c.receive(ctx, id, p)
+			if err != nil {
+				c.cancel(id)
+				return
+			}
+			if response.Done && len(response.Result) == 0 {
+				return
+			}
+			event := &WatchEvent{}
+			if err = decodeResult(response, event); err != nil {
+				log.Errorf("failed to decode watch event from runtime plugin %s: %v", c.t, err)
+				c.cancel(id)
+				return
+			}
+			select {
+			case resultCh <- runtime.WatchEvent{
+				Resource: event.Resource,
+				Ready:    event.Ready,
+				Failed:   event.Failed,
+				Message:  event.Message,
+			}:
+			case <-ctx.Done():
+				c.cancel(id)
+				return
+			}
+			if response.Done {
+				return
+			}
+		}
+	}()
+	return &runtime.WatchResponse{ResultChan: resultCh, Status: first.Status.toStatus()}
+}
+
+func (c *Client) handshake() error {
+	result := &HandshakeResponse{}
+	if err := c.call(context.Background(), MethodHandshake, &HandshakeRequest{
+		ProtocolVersions: SupportedProtocolVersions,
+		Type:             c.t,
+	}, result); err != nil {
+		return err
+	}
+	if negotiateVersion([]int{result.ProtocolVersion}) == 0 {
+		return fmt.Errorf("unsupported protocol version %d chosen by plugin", result.ProtocolVersion)
+	}
+	if result.Type != c.t {
+		return fmt.Errorf("plugin manages resources of type %s instead of %s", result.Type, c.t)
+	}
+	c.version = result.ProtocolVersion
+	return nil
+}
+
+// call sends a request and decodes its only response into result
+func (c *Client) call(ctx context.Context, method string, params, result interface{}) error {
+	id, p, err := c.send(method, params)
+	if err != nil {
+		return err
+	}
+	response, err := c.receive(ctx, id, p)
+	if err != nil {
+		c.cancel(id)
+		return err
+	}
+	return decodeResult(response, result)
+}
+
+func (c *Client) send(method string, params interface{}) (uint64, *pendingRequest, error) {
+	c.lock.Lock()
+	if c.err != nil {
+		c.lock.Unlock()
+		return 0, nil, c.err
+	}
+	c.nextID++
+	id := c.nextID
+	p := &pendingRequest{responses: make(chan *Response, 16), done: make(chan struct{})}
+	c.pending[id] = p
+	c.lock.Unlock()
+
+	if err := c.write(id, method, params); err != nil {
+		c.remove(id)
+		return 0, nil, err
+	}
+	return id, p, nil
+}
+
+// notify sends a request without waiting for its response
+func (c *Client) notify(method string, params interface{}) error {
+	c.lock.Lock()
+	if c.err != nil {
+		c.lock.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	c.lock.Unlock()
+
+	return c.write(id, method, params)
+}
+
+func (c *Client) write(id uint64, method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if err = c.encoder.Encode(&Request{ID: id, Method: method, Params: data}); err != nil {
+		return fmt.Errorf("failed to send request to runtime plugin %s: %w", c.t, err)
+	}
+	return nil
+}
+
+func (c *Client) receive(ctx context.Context, id uint64, p *pendingRequest) (*Response, error) {
+	select {
+	case response, ok := <-p.responses:
+		if !ok {
+			c.lock.Lock()
+			defer c.lock.Unlock()
+			return nil, c.err
+		}
+		if response.Done {
+			c.remove(id)
+		}
+		if response.Error != "" {
+			return nil, errors.New(response.Error)
+		}
+		return response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// cancel tells the plugin to stop a running request and stops waiting for its responses
+func (c *Client) cancel(id uint64) {
+	c.remove(id)
+	if err := c.notify(MethodCancel, &CancelRequest{ID: id}); err != nil {
+		log.Warnf("failed to cancel request %d of runtime plugin %s: %v", id, c.t, err)
+	}
+}
+
+func (c *Client) remove(id uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if p, ok := c.pending[id]; ok {
+		close(p.done)
+		delete(c.pending, id)
+	}
+}
+
+// readLoop dispatches responses to their pending requests until the plugin closes its stdout
+func (c *Client) readLoop(in io.Reader) {
+	decoder := json.NewDecoder(in)
+	for {
+		response := &Response{}
+		err := decoder.Decode(response)
+		if err != nil {
+			c.lock.Lock()
+			c.err = fmt.Errorf("connection to runtime plugin %s is broken: %v", c.t, err)
+			for id, p := range c.pending {
+				close(p.responses)
+				close(p.done)
+				delete(c.pending, id)
+			}
+			c.lock.Unlock()
+			return
+		}
+
+		c.lock.Lock()
+		p, ok := c.pending[response.ID]
+		c.lock.Unlock()
+		if !ok {
+			// the request has been canceled
+			continue
+		}
+		select {
+		case p.responses <- response:
+		case <-p.done:
+		}
+	}
+}
+
+func decodeResult(response *Response, result interface{}) error {
+	if len(response.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/util/kfile"
+)
+
+const (
+	// Folder is the folder under the kusion data folder where runtime plugins are installed
+	Folder = "plugins"
+
+	// Prefix is the name prefix of runtime plugin executables, and the rest of the name is the Type of resources it manages
+	Prefix = "kusion-runtime-"
+)
+
+// Discover returns all runtime plugins installed in the plugins folder under the kusion data folder, keyed by resource Type
+func Discover() (map[models.Type]string, error) {
+	dataFolder, err := kfile.KusionDataFolder()
+	if err != nil {
+		return nil, err
+	}
+	return DiscoverIn(filepath.Join(dataFolder, Folder))
+}
+
+// DiscoverIn returns all runtime plugins in the dir, keyed by resource Type
+func DiscoverIn(dir string) (map[models.Type]string, error) {
+	plugins := map[models.Type]string{}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return plugins, nil
+		}
+		return nil, err
+	}
+
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, Prefix) {
+			continue
+		}
+		if goruntime.GOOS == "windows" {
+			name = strings.TrimSuffix(name, ".exe")
+		} else if f.Mode()&0o111 == 0 {
+			log.Warnf("skip runtime plugin %s as it is not executable", f.Name())
+			continue
+		}
+
+		t := models.Type(strings.TrimPrefix(name, Prefix))
+		if t == "" {
+			continue
+		}
+		plugins[t] = filepath.Join(dir, f.Name())
+	}
+	return plugins, nil
+}
+
+// RegisterRuntimes registers all discovered runtime plugins into runtime.Runtimes. Plugins are started lazily when
+// a stack contains resources of their Type, and built-in runtimes can't be overridden by plugins
+func RegisterRuntimes() error {
+	plugins, err := Discover()
+	if err != nil {
+		return err
+	}
+
+	for t, path := range plugins {
+		if _, ok := runtime.Runtimes[t]; ok {
+			log.Warnf("skip runtime plugin %s as type %s is already registered", path, t)
+			continue
+		}
+		t, path := t, path
+		runtime.AddToRuntimes(t, func() (runtime.Runtime, error) {
+			return NewClient(path, t)
+		})
+		log.Infof("register runtime plugin %s for type %s", path, t)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+// Package plugin contains the protocol between Kusion and out-of-process runtime plugins.
+//
+// A runtime plugin is an executable named kusion-runtime-<Type> and placed in the plugins folder under the kusion data
+// folder. Kusion starts the plugin as a child process when a stack contains resources of this Type, and talks to it
+// with newline-delimited JSON messages over the stdin and stdout of the plugin. The first request is always a Handshake
+// to negotiate the protocol version, and the following requests are mapped to methods in runtime.Runtime.
+//
+// Plugin authors can serve their runtime.Runtime implementation with Serve, and test it with NewInProcessClient.
+package plugin
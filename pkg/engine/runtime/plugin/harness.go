@@ -0,0 +1,28 @@
+package plugin
+
+import (
+	"io"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+)
+
+// NewInProcessClient serves the runtime in current process and returns a Client connected to it over the plugin protocol.
+// It is designed for plugin authors to test their runtime without building and installing the plugin executable
+func NewInProcessClient(t models.Type, rt runtime.Runtime) (*Client, error) {
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+
+	go func() {
+		err := NewServer(t, rt).Serve(serverIn, serverOut)
+		// a nil error makes the Client read io.EOF
+		serverOut.CloseWithError(err)
+	}()
+
+	c := newClient(t, clientIn, clientOut)
+	if err := c.handshake(); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,187 @@
+package plugin
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+const fakeType models.Type = "Fake"
+
+var fakeResource = &models.Resource{
+	ID:         "fake-id",
+	Type:       fakeType,
+	Attributes: map[string]interface{}{"a": "b"},
+}
+
+// failedResource is a resource whose watch fails
+var failedResource = &models.Resource{
+	ID:         "failed-id",
+	Type:       fakeType,
+	Attributes: map[string]interface{}{"a": "c"},
+}
+
+var _ runtime.Runtime = (*fakeRuntime)(nil)
+
+type fakeRuntime struct{}
+
+func (f *fakeRuntime) Apply(ctx context.Context, request *runtime.ApplyRequest) *runtime.ApplyResponse {
+	return &runtime.ApplyResponse{Resource: request.PlanResource}
+}
+
+func (f *fakeRuntime) Read(ctx context.Context, request *runtime.ReadRequest) *runtime.ReadResponse {
+	if request.Resource.ResourceKey() != fakeResource.ID {
+		return &runtime.ReadResponse{}
+	}
+	return &runtime.ReadResponse{Resource: request.Resource}
+}
+
+func (f *fakeRuntime) Delete(ctx context.Context, request *runtime.DeleteRequest) *runtime.DeleteResponse {
+	return &runtime.DeleteResponse{Status: status.NewErrorStatusWithMsg(status.PermissionDenied, "can't delete")}
+}
+
+func (f *fakeRuntime) Watch(ctx context.Context, request *runtime.WatchRequest) *runtime.WatchResponse {
+	events := []runtime.WatchEvent{
+		{Resource: request.Resource, Ready: false, Message: "watching"},
+		{Resource: request.Resource, Ready: true, Message: "watching"},
+	}
+	if request.Resource.ResourceKey() == failedResource.ID {
+		events = []runtime.WatchEvent{{Resource: request.Resource, Failed: true, Message: "failed"}}
+	}
+
+	resultCh := make(chan runtime.WatchEvent)
+	go func() {
+		defer close(resultCh)
+		for _, event := range events {
+			select {
+			case resultCh <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return &runtime.WatchResponse{ResultChan: resultCh}
+}
+
+// TestMain makes this test binary a runtime plugin when it is started by NewClient
+func TestMain(m *testing.M) {
+	if os.Getenv(MagicCookieKey) == MagicCookieValue {
+		if err := Serve(fakeType, &fakeRuntime{}); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func testRuntime(t *testing.T, rt runtime.Runtime) {
+	ctx := context.Background()
+
+	applyResponse := rt.Apply(ctx, &runtime.ApplyRequest{PlanResource: fakeResource})
+	assert.Nil(t, applyResponse.Status)
+	assert.Equal(t, fakeResource, applyResponse.Resource)
+
+	readResponse := rt.Read(ctx, &runtime.ReadRequest{Resource: &models.Resource{ID: "not-exist"}})
+	assert.Nil(t, readResponse.Status)
+	assert.Nil(t, readResponse.Resource)
+
+	deleteResponse := rt.Delete(ctx, &runtime.DeleteRequest{Resource: fakeResource})
+	assert.Equal(t, status.NewErrorStatusWithMsg(status.PermissionDenied, "can't delete"), deleteResponse.Status)
+
+	watchResponse := rt.Watch(ctx, &runtime.WatchRequest{Resource: fakeResource})
+	assert.Nil(t, watchResponse.Status)
+	var events []runtime.WatchEvent
+	for event := range watchResponse.ResultChan {
+		events = append(events, event)
+	}
+	assert.Equal(t, []runtime.WatchEvent{
+		{Resource: fakeResource, Ready: false, Message: "watching"},
+		{Resource: fakeResource, Ready: true, Message: "watching"},
+	}, events)
+
+	watchResponse = rt.Watch(ctx, &runtime.WatchRequest{Resource: failedResource})
+	assert.Nil(t, watchResponse.Status)
+	events = nil
+	for event := range watchResponse.ResultChan {
+		events = append(events, event)
+	}
+	assert.Equal(t, []runtime.WatchEvent{{Resource: failedResource, Failed: true, Message: "failed"}}, events)
+}
+
+func TestInProcessClient(t *testing.T) {
+	c, err := NewInProcessClient(fakeType, &fakeRuntime{})
+	assert.Nil(t, err)
+	defer c.Close()
+	assert.Equal(t, ProtocolVersion, c.version)
+
+	testRuntime(t, c)
+}
+
+func TestNewClient(t *testing.T) {
+	c, err := NewClient(os.Args[0], fakeType)
+	assert.Nil(t, err)
+	defer c.Close()
+
+	testRuntime(t, c)
+
+	// requests fail after the plugin exits
+	assert.Nil(t, c.Close())
+	time.Sleep(100 * time.Millisecond)
+	readResponse := c.Read(context.Background(), &runtime.ReadRequest{Resource: fakeResource})
+	assert.True(t, status.IsErr(readResponse.Status))
+}
+
+func TestHandshake(t *testing.T) {
+	t.Run("type mismatch", func(t *testing.T) {
+		_, err := NewClient(os.Args[0], "Other")
+		assert.Error(t, err)
+	})
+
+	t.Run("no compatible version", func(t *testing.T) {
+		assert.Equal(t, 0, negotiateVersion([]int{ProtocolVersion + 1}))
+		assert.Equal(t, ProtocolVersion, negotiateVersion([]int{ProtocolVersion + 1, ProtocolVersion}))
+	})
+}
+
+func TestWatchCanceled(t *testing.T) {
+	c, err := NewInProcessClient(fakeType, &fakeRuntime{})
+	assert.Nil(t, err)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watchResponse := c.Watch(ctx, &runtime.WatchRequest{Resource: fakeResource})
+	cancel()
+	for range watchResponse.ResultChan {
+	}
+
+	// the client still works after a watch is canceled
+	applyResponse := c.Apply(context.Background(), &runtime.ApplyRequest{PlanResource: fakeResource})
+	assert.Nil(t, applyResponse.Status)
+}
+
+func TestDiscoverIn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, Prefix+"Fake"), []byte{}, 0o755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, Prefix+"NotExecutable"), []byte{}, 0o644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "other"), []byte{}, 0o755))
+
+	plugins, err := DiscoverIn(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, map[models.Type]string{fakeType: filepath.Join(dir, Prefix+"Fake")}, plugins)
+
+	plugins, err = DiscoverIn(filepath.Join(dir, "not-exist"))
+	assert.Nil(t, err)
+	assert.Empty(t, plugins)
+}
@@ -0,0 +1,142 @@
+package plugin
+
+import (
+	"encoding/json"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/status"
+)
+
+const (
+	// ProtocolVersion is the latest protocol version supported by this package
+	ProtocolVersion = 1
+
+	// MagicCookieKey and MagicCookieValue are set in the environment of plugin processes. They are not a security
+	// measure, just a way to tell users a plugin is not designed to be executed directly
+	MagicCookieKey   = "KUSION_RUNTIME_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "5bd1e4c3a0f64b0c9c7d2e8a6f3b1d47"
+)
+
+// SupportedProtocolVersions contains all protocol versions supported by this package
+var SupportedProtocolVersions = []int{ProtocolVersion}
+
+// Methods of the protocol
+const (
+	MethodHandshake = "Handshake"
+	MethodApply     = "Apply"
+	MethodRead      = "Read"
+	MethodDelete    = "Delete"
+	MethodWatch     = "Watch"
+	MethodCancel    = "Cancel"
+)
+
+// Request is a message sent from Kusion to the plugin
+type Request struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a message sent from the plugin to Kusion. A request is answered with exactly one Response except Watch,
+// whose events are streamed in multiple Responses with the same ID and the last one is marked as Done
+type Response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	// Error represents a protocol error like unknown method or malformed params. Errors of the runtime itself are
+	// returned in the Status of each result
+	Error string `json:"error,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+}
+
+type HandshakeRequest struct {
+	// ProtocolVersions contains all protocol versions supported by Kusion
+	ProtocolVersions []int `json:"protocolVersions"`
+	// Type is the Type of resources Kusion wants this plugin to manage
+	Type models.Type `json:"type"`
+}
+
+type HandshakeResponse struct {
+	// ProtocolVersion is the protocol version chosen by the plugin
+	ProtocolVersion int `json:"protocolVersion"`
+	// Type is the Type of resources managed by this plugin
+	Type models.Type `json:"type"`
+}
+
+type ApplyRequest struct {
+	PriorResource   *models.Resource `json:"priorResource,omitempty"`
+	PlanResource    *models.Resource `json:"planResource,omitempty"`
+	DryRun          bool             `json:"dryRun,omitempty"`
+	ServerSideApply bool             `json:"serverSideApply,omitempty"`
+	ForceConflicts  bool             `json:"forceConflicts,omitempty"`
+}
+
+type ResourceResponse struct {
+	Resource *models.Resource `json:"resource,omitempty"`
+	Status   *Status          `json:"status,omitempty"`
+}
+
+type ResourceRequest struct {
+	Resource *models.Resource `json:"resource,omitempty"`
+}
+
+type DeleteRequest struct {
+	Resource *models.Resource `json:"resource,omitempty"`
+	DryRun   bool             `json:"dryRun,omitempty"`
+}
+
+type StatusResponse struct {
+	Status *Status `json:"status,omitempty"`
+}
+
+// WatchResponse is the first Response of a Watch request. Unsupported means the runtime doesn't support Watch,
+// and no WatchEvent will be sent after it
+type WatchResponse struct {
+	Unsupported bool    `json:"unsupported,omitempty"`
+	Status      *Status `json:"status,omitempty"`
+}
+
+type WatchEvent struct {
+	Resource *models.Resource `json:"resource,omitempty"`
+	Ready    bool             `json:"ready,omitempty"`
+	Failed   bool             `json:"failed,omitempty"`
+	Message  string           `json:"message,omitempty"`
+}
+
+type CancelRequest struct {
+	// ID is the ID of the request to cancel
+	ID uint64 `json:"id"`
+}
+
+// Status is the serializable form of status.Status
+type Status struct {
+	Kind    status.Kind `json:"kind"`
+	Code    status.Code `json:"code"`
+	Message string      `json:"message"`
+}
+
+func fromStatus(s status.Status) *Status {
+	if s == nil {
+		return nil
+	}
+	return &Status{Kind: s.Kind(), Code: s.Code(), Message: s.Message()}
+}
+
+func (s *Status) toStatus() status.Status {
+	if s == nil {
+		return nil
+	}
+	return status.NewBaseStatus(s.Kind, s.Code, s.Message)
+}
+
+// negotiateVersion returns the highest protocol version supported by both sides, or 0 if there is no such version
+func negotiateVersion(versions []int) int {
+	chosen := 0
+	for _, v := range versions {
+		for _, supported := range SupportedProtocolVersions {
+			if v == supported && v > chosen {
+				chosen = v
+			}
+		}
+	}
+	return chosen
+}
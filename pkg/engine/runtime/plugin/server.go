@@ -0,0 +1,231 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+)
+
+// Serve serves the runtime as a plugin managing resources of type t over stdin and stdout. It should be invoked in
+// the main function of the plugin, and it returns when Kusion closes the stdin of the plugin.
+// Plugins must not write anything else to stdout, use stderr for logs instead
+func Serve(t models.Type, rt runtime.Runtime) error {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		return errors.New("this binary is a Kusion runtime plugin and is not designed to be executed directly. " +
+			"Please install it into the plugins folder under the kusion data folder")
+	}
+	return NewServer(t, rt).Serve(os.Stdin, os.Stdout)
+}
+
+// Server dispatches requests from Kusion to a runtime.Runtime
+type Server struct {
+	t  models.Type
+	rt runtime.Runtime
+
+	// version is the negotiated protocol version, 0 means the handshake is not finished
+	version int
+
+	writeLock sync.Mutex
+	encoder   *json.Encoder
+
+	lock    sync.Mutex
+	cancels map[uint64]context.CancelFunc
+}
+
+func NewServer(t models.Type, rt runtime.Runtime) *Server {
+	return &Server{t: t, rt: rt, cancels: map[uint64]context.CancelFunc{}}
+}
+
+// Serve reads requests from in and writes responses to out until in is closed
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.encoder = json.NewEncoder(out)
+	decoder := json.NewDecoder(in)
+
+	var wg sync.WaitGroup
+	defer func() {
+		s.lock.Lock()
+		for _, cancel := range s.cancels {
+			cancel()
+		}
+		s.lock.Unlock()
+		wg.Wait()
+	}()
+
+	for {
+		req := &Request{}
+		if err := decoder.Decode(req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to decode request: %w", err)
+		}
+
+		// the handshake must be finished before any other requests
+		if req.Method == MethodHandshake || s.version == 0 {
+			s.handshake(req)
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		s.lock.Lock()
+		s.cancels[req.ID] = cancel
+		s.lock.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				s.lock.Lock()
+				delete(s.cancels, req.ID)
+				s.lock.Unlock()
+				cancel()
+			}()
+			s.handle(ctx, req)
+		}()
+	}
+}
+
+func (s *Server) handshake(req *Request) {
+	if req.Method != MethodHandshake {
+		s.writeError(req.ID, fmt.Errorf("method %s is invoked before handshake", req.Method))
+		return
+	}
+
+	params := &HandshakeRequest{}
+	if err := json.Unmarshal(req.Params, params); err != nil {
+		s.writeError(req.ID, err)
+		return
+	}
+	if params.Type != s.t {
+		s.writeError(req.ID, fmt.Errorf("plugin manages resources of type %s instead of %s", s.t, params.Type))
+		return
+	}
+	version := negotiateVersion(params.ProtocolVersions)
+	if version == 0 {
+		s.writeError(req.ID, fmt.Errorf("no compatible protocol version, kusion supports %v and plugin supports %v",
+			params.ProtocolVersions, SupportedProtocolVersions))
+		return
+	}
+
+	s.version = version
+	s.writeResult(req.ID, &HandshakeResponse{ProtocolVersion: version, Type: s.t}, true)
+}
+
+func (s *Server) handle(ctx context.Context, req *Request) {
+	switch req.Method {
+	case MethodApply:
+		params := &ApplyRequest{}
+		if err := json.Unmarshal(req.Params, params); err != nil {
+			s.writeError(req.ID, err)
+			return
+		}
+		result := &ResourceResponse{}
+		if response := s.rt.Apply(ctx, &runtime.ApplyRequest{
+			PriorResource:   params.PriorResource,
+			PlanResource:    params.PlanResource,
+			DryRun:          params.DryRun,
+			ServerSideApply: params.ServerSideApply,
+			ForceConflicts:  params.ForceConflicts,
+		}); response != nil {
+			result.Resource, result.Status = response.Resource, fromStatus(response.Status)
+		}
+		s.writeResult(req.ID, result, true)
+	case MethodRead:
+		params := &ResourceRequest{}
+		if err := json.Unmarshal(req.Params, params); err != nil {
+			s.writeError(req.ID, err)
+			return
+		}
+		result := &ResourceResponse{}
+		if response := s.rt.Read(ctx, &runtime.ReadRequest{Resource: params.Resource}); response != nil {
+			result.Resource, result.Status = response.Resource, fromStatus(response.Status)
+		}
+		s.writeResult(req.ID, result, true)
+	case MethodDelete:
+		params := &DeleteRequest{}
+		if err := json.Unmarshal(req.Params, params); err != nil {
+			s.writeError(req.ID, err)
+			return
+		}
+		result := &StatusResponse{}
+		if response := s.rt.Delete(ctx, &runtime.DeleteRequest{Resource: params.Resource, DryRun: params.DryRun}); response != nil {
+			result.Status = fromStatus(response.Status)
+		}
+		s.writeResult(req.ID, result, true)
+	case MethodWatch:
+		params := &ResourceRequest{}
+		if err := json.Unmarshal(req.Params, params); err != nil {
+			s.writeError(req.ID, err)
+			return
+		}
+		s.watch(ctx, req.ID, params.Resource)
+	case MethodCancel:
+		params := &CancelRequest{}
+		if err := json.Unmarshal(req.Params, params); err != nil {
+			s.writeError(req.ID, err)
+			return
+		}
+		s.lock.Lock()
+		if cancel, ok := s.cancels[params.ID]; ok {
+			cancel()
+		}
+		s.lock.Unlock()
+		s.writeResult(req.ID, &StatusResponse{}, true)
+	default:
+		s.writeError(req.ID, fmt.Errorf("unknown method %s", req.Method))
+	}
+}
+
+func (s *Server) watch(ctx context.Context, id uint64, resource *models.Resource) {
+	response := s.rt.Watch(ctx, &runtime.WatchRequest{Resource: resource})
+	if response == nil || (response.ResultChan == nil && response.Status == nil) {
+		s.writeResult(id, &WatchResponse{Unsupported: true}, true)
+		return
+	}
+	if response.ResultChan == nil {
+		s.writeResult(id, &WatchResponse{Status: fromStatus(response.Status)}, true)
+		return
+	}
+
+	s.writeResult(id, &WatchResponse{Status: fromStatus(response.Status)}, false)
+	for event := range response.ResultChan {
+		s.writeResult(id, &WatchEvent{
+			Resource: event.Resource,
+			Ready:    event.Ready,
+			Failed:   event.Failed,
+			Message:  event.Message,
+		}, false)
+	}
+	s.writeResult(id, nil, true)
+}
+
+func (s *Server) writeResult(id uint64, result interface{}, done bool) {
+	response := &Response{ID: id, Done: done}
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			s.writeError(id, err)
+			return
+		}
+		response.Result = data
+	}
+	s.write(response)
+}
+
+func (s *Server) writeError(id uint64, err error) {
+	s.write(&Response{ID: id, Error: err.Error(), Done: true})
+}
+
+func (s *Server) write(response *Response) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	// nothing we can do if Kusion is gone, and the read loop will return soon
+	_ = s.encoder.Encode(response)
+}
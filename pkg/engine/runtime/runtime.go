@@ -28,10 +28,29 @@ type Runtime interface {
 
 	// Watch the latest state or event of this Resource.
 	// This is an optional method for the Runtime to implement,
-	// but it will be very helpful for us to know what is happening when applying this Resource
+	// but it will be very helpful for us to know what is happening when applying this Resource.
+	// Runtimes that don't support Watch can return a nil response or a response without ResultChan,
+	// and the Resource will be regarded as ready as soon as it is applied
 	Watch(ctx context.Context, request *WatchRequest) *WatchResponse
 }
 
+// Importer is an optional interface for Runtimes. Runtimes implement it to convert a live resource into the state
+// recorded by Kusion, e.g. dropping fields populated by the actual infra. Live resources read by Runtime.Read
+// will be imported as they are if the Runtime doesn't implement it
+type Importer interface {
+	// Import reads the live resource located by the planed resource without making any changes in the actual infra
+	Import(ctx context.Context, request *ImportRequest) *ImportResponse
+}
+
+// Replacer is an optional interface for Runtimes. Runtimes implement it to report changes that can't be applied in place,
+// e.g. changes of immutable fields, and such resources will be replaced by deleting and creating them again instead of
+// failing at apply time. Resources are always updated in place if the Runtime doesn't implement it
+type Replacer interface {
+	// RequiresReplace reports whether the live resource must be replaced to reach the planed resource.
+	// It never makes any changes in the actual infra
+	RequiresReplace(ctx context.Context, request *RequiresReplaceRequest) *RequiresReplaceResponse
+}
+
 type ApplyRequest struct {
 	// PriorResource is the last applied resource saved in state storage
 	PriorResource *models.Resource
@@ -41,6 +60,13 @@ type ApplyRequest struct {
 
 	// DryRun means this a dry-run request and will not make any changes in actual infra
 	DryRun bool
+
+	// ServerSideApply means changes of this resource should be computed by the actual infra instead of Kusion.
+	// It is ignored by runtimes that don't support it
+	ServerSideApply bool
+
+	// ForceConflicts means taking the ownership of fields managed by others when ServerSideApply is enabled
+	ForceConflicts bool
 }
 
 type ApplyResponse struct {
@@ -67,6 +93,9 @@ type ReadResponse struct {
 type DeleteRequest struct {
 	// Resource represents the resource we want to delete from the actual infra
 	Resource *models.Resource
+
+	// DryRun means this a dry-run request and will not make any changes in actual infra
+	DryRun bool
 }
 
 type DeleteResponse struct {
@@ -80,9 +109,65 @@ type WatchRequest struct {
 }
 
 type WatchResponse struct {
+	// ResultChan streams the latest state of the watched resource. It will be closed by the Runtime
+	// after a ready event is sent or the ctx is done
+	ResultChan <-chan WatchEvent
+
+	// Status contains messages will show to users
+	Status status.Status
+}
+
+// WatchEvent is a snapshot of the watched resource
+type WatchEvent struct {
 	// Resource represents the resource we watched from the actual infra
 	Resource *models.Resource
 
+	// Ready means this resource has reached its desired state and can be depended on by other resources
+	Ready bool
+
+	// Failed means this resource will never be ready unless it is changed, e.g. a failed Job.
+	// No more events will be sent after a failed event
+	Failed bool
+
+	// Message describes what is happening to this resource
+	Message string
+}
+
+type ImportRequest struct {
+	// PlanResource is the resource compiled from the configuration, which is used to locate the live resource
+	PlanResource *models.Resource
+}
+
+type ImportResponse struct {
+	// Resource is the live resource that will be saved in the state storage. Nil means it doesn't exist
+	Resource *models.Resource
+
+	// Status contains messages will show to users
+	Status status.Status
+}
+
+type RequiresReplaceRequest struct {
+	// PriorResource is the last applied resource saved in state storage
+	PriorResource *models.Resource
+
+	// PlanResource is the resource we want to apply
+	PlanResource *models.Resource
+
+	// LiveResource is the resource read from the actual infra
+	LiveResource *models.Resource
+}
+
+type RequiresReplaceResponse struct {
+	// Replace means the live resource can't be updated in place and must be replaced
+	Replace bool
+
+	// Reasons describe why the resource must be replaced, e.g. paths of changed immutable fields
+	Reasons []string
+
+	// SameIdentity means the replacement has the same identity as the replaced resource, e.g. the name of a
+	// Kubernetes object, so it can't be created before the replaced resource is deleted
+	SameIdentity bool
+
 	// Status contains messages will show to users
 	Status status.Status
 }
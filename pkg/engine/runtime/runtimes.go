@@ -0,0 +1,42 @@
+package runtime
+
+import (
+	"fmt"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+)
+
+// Runtimes contains constructors of all Runtimes we supported, keyed by the Type of resources they manage
+var Runtimes = make(map[models.Type]func() (Runtime, error))
+
+func AddToRuntimes(t models.Type, runtime func() (Runtime, error)) {
+	Runtimes[t] = runtime
+}
+
+// InitRuntimes returns a Runtime for every Type of the given resources. Runtimes already in the runtimeMap will be reused
+// and others will be created by constructors registered in Runtimes
+func InitRuntimes(runtimeMap map[models.Type]Runtime, resources ...models.Resources) (map[models.Type]Runtime, error) {
+	result := make(map[models.Type]Runtime, len(runtimeMap))
+	for t, r := range runtimeMap {
+		result[t] = r
+	}
+
+	for _, rs := range resources {
+		for i := range rs {
+			t := rs[i].RuntimeType()
+			if result[t] != nil {
+				continue
+			}
+			newRuntime, ok := Runtimes[t]
+			if !ok {
+				return nil, fmt.Errorf("unsupported type:%s of resource:%s", t, rs[i].ResourceKey())
+			}
+			r, err := newRuntime()
+			if err != nil {
+				return nil, err
+			}
+			result[t] = r
+		}
+	}
+	return result, nil
+}
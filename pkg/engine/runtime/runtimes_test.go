@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+)
+
+const fakeType models.Type = "Fake"
+
+var _ Runtime = (*fakeRuntime)(nil)
+
+type fakeRuntime struct{}
+
+func (f *fakeRuntime) Apply(ctx context.Context, request *ApplyRequest) *ApplyResponse {
+	return &ApplyResponse{Resource: request.PlanResource}
+}
+
+func (f *fakeRuntime) Read(ctx context.Context, request *ReadRequest) *ReadResponse {
+	return &ReadResponse{Resource: request.Resource}
+}
+
+func (f *fakeRuntime) Delete(ctx context.Context, request *DeleteRequest) *DeleteResponse {
+	return &DeleteResponse{}
+}
+
+func (f *fakeRuntime) Watch(ctx context.Context, request *WatchRequest) *WatchResponse {
+	return nil
+}
+
+func TestInitRuntimes(t *testing.T) {
+	existing := &fakeRuntime{}
+	AddToRuntimes(fakeType, func() (Runtime, error) {
+		return nil, errors.New("should not be invoked")
+	})
+	defer delete(Runtimes, fakeType)
+
+	t.Run("reuse existing runtime", func(t *testing.T) {
+		got, err := InitRuntimes(map[models.Type]Runtime{fakeType: existing},
+			models.Resources{{ID: "a", Type: fakeType}}, models.Resources{{ID: "b", Type: fakeType}})
+		assert.Nil(t, err)
+		assert.Same(t, existing, got[fakeType])
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		_, err := InitRuntimes(nil, models.Resources{{ID: "a", Type: "Unknown"}})
+		assert.EqualError(t, err, "unsupported type:Unknown of resource:a")
+	})
+
+	t.Run("constructor error", func(t *testing.T) {
+		_, err := InitRuntimes(nil, models.Resources{{ID: "a", Type: fakeType}})
+		assert.EqualError(t, err, "should not be invoked")
+	})
+}
@@ -2,9 +2,11 @@ package local
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/fs"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"time"
 
 	"kusionstack.io/kusion/pkg/engine/states"
@@ -19,7 +21,7 @@ func init() {
 	states.AddToBackends("local", NewFileSystemState)
 }
 
-var _ states.StateStorage = &FileSystemState{}
+var _ states.HistoryStateStorage = &FileSystemState{}
 
 type FileSystemState struct {
 	// state Path is in the same dir where command line is invoked
@@ -30,7 +32,12 @@ func NewFileSystemState() states.StateStorage {
 	return &FileSystemState{}
 }
 
-const KusionState = "kusion_state.json"
+const (
+	KusionState = "kusion_state.json"
+	// KusionStateHistory is the directory in the same dir of the state file, where every applied state is kept
+	// in a file named by its serial
+	KusionStateHistory = "kusion_state_history"
+)
 
 func (f *FileSystemState) ConfigSchema() cty.Type {
 	config := map[string]cty.Type{
@@ -57,27 +64,17 @@ func (f *FileSystemState) GetLatestState(query *states.StateQuery) (*states.Stat
 	}
 	defer file.Close()
 
-	jsonFile, err := ioutil.ReadFile(f.Path)
-	if err != nil {
-		return nil, err
-	}
+	return loadState(f.Path)
+}
 
-	if len(jsonFile) != 0 {
-		state := &states.State{}
-		// JSON is a subset of YAML.
-		// We are using yaml.Unmarshal here (instead of json.Unmarshal) because the
-		// Go JSON library doesn't try to pick the right number type (int, float,
-		// etc.) when unmarshalling to interface{}, it just picks float64 universally.
-		// go-yaml does the right thing.
-		err = yaml.Unmarshal(jsonFile, state)
-		if err != nil {
-			return nil, err
-		}
-		return state, nil
-	} else {
-		log.Infof("file %s is empty. Skip unmarshal json", f.Path)
+// GetStateBySerial returns the state of the serial kept in the history directory
+func (f *FileSystemState) GetStateBySerial(query *states.StateQuery, serial uint64) (*states.State, error) {
+	path := f.historyPath(serial)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		log.Infof("can't find state of serial %d in %s", serial, path)
 		return nil, nil
 	}
+	return loadState(path)
 }
 
 func (f *FileSystemState) Apply(state *states.State) error {
@@ -88,6 +85,14 @@ func (f *FileSystemState) Apply(state *states.State) error {
 	if err != nil {
 		return err
 	}
+
+	// keep the state in the history, so that it can be rolled back to
+	if err = os.MkdirAll(filepath.Join(filepath.Dir(f.Path), KusionStateHistory), fs.ModePerm); err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(f.historyPath(state.Serial), jsonByte, fs.ModePerm); err != nil {
+		return err
+	}
 	return ioutil.WriteFile(f.Path, jsonByte, fs.ModePerm)
 }
 
@@ -97,5 +102,35 @@ func (f *FileSystemState) Delete(id string) error {
 	if err != nil {
 		return err
 	}
-	return nil
+	return os.RemoveAll(filepath.Join(filepath.Dir(f.Path), KusionStateHistory))
+}
+
+// historyPath returns the path of the state of the serial in the history directory
+func (f *FileSystemState) historyPath(serial uint64) string {
+	return filepath.Join(filepath.Dir(f.Path), KusionStateHistory, fmt.Sprintf("%d.json", serial))
+}
+
+// loadState reads the state in the file, and returns nil if the file is empty
+func loadState(path string) (*states.State, error) {
+	jsonFile, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(jsonFile) != 0 {
+		state := &states.State{}
+		// JSON is a subset of YAML.
+		// We are using yaml.Unmarshal here (instead of json.Unmarshal) because the
+		// Go JSON library doesn't try to pick the right number type (int, float,
+		// etc.) when unmarshalling to interface{}, it just picks float64 universally.
+		// go-yaml does the right thing.
+		err = yaml.Unmarshal(jsonFile, state)
+		if err != nil {
+			return nil, err
+		}
+		return state, nil
+	} else {
+		log.Infof("file %s is empty. Skip unmarshal json", path)
+		return nil, nil
+	}
 }
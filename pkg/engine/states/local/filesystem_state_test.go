@@ -168,7 +168,7 @@ func FileSystemStateSetUp(t *testing.T) *FileSystemState {
 		return nil
 	})
 
-	return &FileSystemState{Path: "kusion_state_filesystem.json"}
+	return &FileSystemState{Path: filepath.Join(t.TempDir(), "kusion_state_filesystem.json")}
 }
 
 func TestFileSystemState(t *testing.T) {
@@ -182,3 +182,24 @@ func TestFileSystemState(t *testing.T) {
 	err = fileSystemState.Delete("kusion_state_filesystem.json")
 	assert.NoError(t, err)
 }
+
+func TestFileSystemState_GetStateBySerial(t *testing.T) {
+	s := &FileSystemState{Path: filepath.Join(t.TempDir(), KusionState)}
+	for serial := uint64(1); serial <= 3; serial++ {
+		assert.NoError(t, s.Apply(&states.State{Project: "test_project", Stack: "test_env", Serial: serial}))
+	}
+
+	query := &states.StateQuery{Project: "test_project", Stack: "test_env"}
+	for serial := uint64(1); serial <= 3; serial++ {
+		state, err := s.GetStateBySerial(query, serial)
+		assert.NoError(t, err)
+		assert.Equal(t, serial, state.Serial)
+	}
+	latest, err := s.GetLatestState(query)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), latest.Serial)
+
+	state, err := s.GetStateBySerial(query, 4)
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+}
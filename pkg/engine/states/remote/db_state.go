@@ -29,7 +29,7 @@ func init() {
 	states.AddToBackends("db", NewDBState)
 }
 
-var _ states.StateStorage = &DBState{}
+var _ states.HistoryStateStorage = &DBState{}
 
 func NewDBState() states.StateStorage {
 	result := &DBState{}
@@ -104,6 +104,40 @@ func (s *DBState) Delete(id string) error {
 }
 
 func (s *DBState) GetLatestState(q *states.StateQuery) (*states.State, error) {
+	where, err := queryWhere(q)
+	if err != nil {
+		return nil, err
+	}
+	where["_orderby"] = "serial desc"
+
+	stateDO, err := mapper.GetOne(s.DB, where)
+	if errors.Is(err, scanner.ErrEmptyResult) {
+		return nil, nil
+	}
+	res := do2Bo(stateDO)
+	return res, err
+}
+
+// GetStateBySerial returns the state of the serial in the add-only history
+func (s *DBState) GetStateBySerial(q *states.StateQuery, serial uint64) (*states.State, error) {
+	where, err := queryWhere(q)
+	if err != nil {
+		return nil, err
+	}
+	where["serial"] = serial
+
+	stateDO, err := mapper.GetOne(s.DB, where)
+	if errors.Is(err, scanner.ErrEmptyResult) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return do2Bo(stateDO), nil
+}
+
+// queryWhere converts the query to conditions of the state table
+func queryWhere(q *states.StateQuery) (map[string]interface{}, error) {
 	where := make(map[string]interface{})
 
 	if len(q.Tenant) == 0 {
@@ -123,14 +157,7 @@ func (s *DBState) GetLatestState(q *states.StateQuery) (*states.State, error) {
 	if len(q.Stack) != 0 {
 		where["stack"] = q.Stack
 	}
-	where["_orderby"] = "serial desc"
-
-	stateDO, err := mapper.GetOne(s.DB, where)
-	if errors.Is(err, scanner.ErrEmptyResult) {
-		return nil, nil
-	}
-	res := do2Bo(stateDO)
-	return res, err
+	return where, nil
 }
 
 func do2Bo(dbState *mapper.StateDO) *states.State {
@@ -171,3 +171,26 @@ func TestDBState(t *testing.T) {
 	}()
 	dbState.Delete("test")
 }
+
+func TestDBState_GetStateBySerial(t *testing.T) {
+	defer monkey.UnpatchAll()
+	var gotWhere map[string]interface{}
+	monkey.Patch(mapper.GetOne, func(db *sql.DB, where map[string]interface{}) (*mapper.StateDO, error) {
+		gotWhere = where
+		return &mapper.StateDO{GlobalTenant: "test_global_tenant", Project: "test_project", Serial: 2, Resources: "[]"}, nil
+	})
+	dbState := &DBState{DB: &sql.DB{}}
+
+	state, err := dbState.GetStateBySerial(&states.StateQuery{Tenant: "test_global_tenant", Stack: "test_env", Project: "test_project"}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), state.Serial)
+	assert.Equal(t, map[string]interface{}{
+		"global_tenant": "test_global_tenant",
+		"project":       "test_project",
+		"stack":         "test_env",
+		"serial":        uint64(2),
+	}, gotWhere)
+
+	_, err = dbState.GetStateBySerial(&states.StateQuery{Project: "test_project"}, 2)
+	assert.Error(t, err)
+}
@@ -31,6 +31,9 @@ import (
 //	project = "p"
 //	stack = "s"
 //	the final request URL = "http://kusionstack.io/apis/v1/tenants/t/projects/p/stacks/s/states"
+//
+// The state of a previous serial is requested by the URL to get the latest state with a "serial" query parameter,
+// e.g. "http://kusionstack.io/apis/v1/tenants/t/projects/p/stacks/s/states?serial=3"
 type HTTPState struct {
 	// urlPrefix is the prefix added in front of all request URLs. e.g. "http://kusionstack.io/"
 	urlPrefix string
@@ -101,9 +104,36 @@ func (s *HTTPState) Configure(obj cty.Value) error {
 	return nil
 }
 
+var _ states.HistoryStateStorage = &HTTPState{}
+
 // GetLatestState is an implementation of StateStorage.GetLatestState
 func (s *HTTPState) GetLatestState(query *states.StateQuery) (*states.State, error) {
 	url := fmt.Sprintf("%s"+s.getLatestURLFormat, s.urlPrefix, query.Tenant, query.Project, query.Stack)
+	return s.getState(url)
+}
+
+// GetStateBySerial is an implementation of HistoryStateStorage.GetStateBySerial
+func (s *HTTPState) GetStateBySerial(query *states.StateQuery, serial uint64) (*states.State, error) {
+	url := fmt.Sprintf("%s"+s.getLatestURLFormat, s.urlPrefix, query.Tenant, query.Project, query.Stack)
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	url = fmt.Sprintf("%s%sserial=%d", url, separator, serial)
+
+	state, err := s.getState(url)
+	if err != nil {
+		return nil, err
+	}
+	// services without the history of states ignore the parameter and return the latest state
+	if state != nil && state.Serial != serial {
+		return nil, fmt.Errorf("get the state of serial %d failed, got serial %d. The state service may not support the history of states", serial, state.Serial)
+	}
+	return state, nil
+}
+
+// getState gets the state by the URL, and returns nil if it is not found
+func (s *HTTPState) getState(url string) (*states.State, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -115,7 +145,7 @@ func (s *HTTPState) GetLatestState(query *states.StateQuery) (*states.State, err
 	defer res.Body.Close()
 
 	if res.StatusCode == 404 {
-		log.Info("Can't find the latest state by request:%s", url)
+		log.Info("Can't find the state by request:%s", url)
 		return nil, nil
 	}
 	if res.StatusCode != 200 {
@@ -249,3 +249,34 @@ func TestNewHTTPState(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPState_GetStateBySerial(t *testing.T) {
+	defer monkey.UnpatchAll()
+	s := &HTTPState{
+		urlPrefix:          prefix,
+		applyURLFormat:     format,
+		getLatestURLFormat: format,
+	}
+	query := &states.StateQuery{Tenant: "t", Project: "p", Stack: "s"}
+	state := states.NewState()
+	state.Serial = 3
+
+	var gotURL string
+	monkey.Patch((*http.Client).Do, func(c *http.Client, req *http.Request) (*http.Response, error) {
+		gotURL = req.URL.String()
+		return &http.Response{
+			Status:     "Success",
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(json_util.Marshal2String(state))),
+		}, nil
+	})
+
+	got, err := s.GetStateBySerial(query, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, state, got)
+	assert.Equal(t, prefix+"/apis/v1/tenants/t/projects/p/stacks/s/states/?serial=3", gotURL)
+
+	// the service returns the latest state if it doesn't support the history
+	_, err = s.GetStateBySerial(query, 2)
+	assert.Error(t, err)
+}
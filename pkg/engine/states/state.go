@@ -26,6 +26,14 @@ type StateStorage interface {
 	Delete(id string) error
 }
 
+// HistoryStateStorage is implemented by StateStorage backends keeping the history of states, so that stacks can be
+// rolled back to the states of previous serials
+type HistoryStateStorage interface {
+	StateStorage
+	// GetStateBySerial returns the state of the serial in the history, and nil if it doesn't exist
+	GetStateBySerial(query *StateQuery, serial uint64) (*State, error)
+}
+
 type StateQuery struct {
 	Tenant  string `json:"tenant"`
 	Stack   string `json:"stack"`
@@ -53,6 +61,11 @@ type State struct {
 	Operator string `json:"operator,omitempty"`
 	// Resources records all resources in this operation
 	Resources models.Resources `json:"resources"`
+	// Outputs records outputs of the stack evaluated in the last apply
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+	// OutputDefinitions records outputs declared in the spec of the last apply, which may refer to attributes of
+	// resources. They are evaluated again when the stack is rolled back to this State
+	OutputDefinitions map[string]interface{} `json:"outputDefinitions,omitempty"`
 	// CreatTime is the time State is created
 	CreatTime time.Time `json:"creatTime"`
 	// ModifiedTime is the time State is modified each time
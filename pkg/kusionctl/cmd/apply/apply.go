@@ -4,6 +4,7 @@ import (
 	"github.com/spf13/cobra"
 	"k8s.io/kubectl/pkg/util/templates"
 
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
 	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
 	"kusionstack.io/kusion/pkg/util/i18n"
 )
@@ -17,7 +18,10 @@ var (
 		Create or update or delete resources according to the KCL files within a stack.
 		By default, Kusion will generate an execution plan and present it for your approval before taking any action.
 
-		You can check the plan details and then decide if the actions should be taken or aborted.`
+		You can check the plan details and then decide if the actions should be taken or aborted.
+
+		A plan file saved by 'kusion preview --out' can be applied instead of KCL files. It is applied exactly as
+		previewed without compiling KCL, and refused if the state has been changed since it was saved.`
 
 	applyExample = `
 		# Apply with specifying work directory
@@ -30,14 +34,17 @@ var (
 		kusion apply -Y settings.yaml
 
 		# Skip interactive approval of plan details before applying
-		kusion apply --yes`
+		kusion apply --yes
+
+		# Apply a plan saved by kusion preview --out
+		kusion apply plan.json`
 )
 
 func NewCmdApply() *cobra.Command {
 	o := NewApplyOptions()
 
 	cmd := &cobra.Command{
-		Use:     "apply",
+		Use:     "apply [plan file]",
 		Short:   i18n.T(applyShort),
 		Long:    templates.LongDesc(i18n.T(applyLong)),
 		Example: templates.Examples(i18n.T(applyExample)),
@@ -67,7 +74,27 @@ func NewCmdApply() *cobra.Command {
 	cmd.Flags().BoolVarP(&o.NoStyle, "no-style", "", false,
 		i18n.T("no-style sets to RawOutput mode and disables all of styling"))
 	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false,
-		i18n.T("dry-run to preview the execution effect (always successful) without actually applying the changes"))
+		i18n.T("dry-run to validate the changes by the runtimes (e.g. server-side dry run of Kubernetes) without actually applying them or updating the state"))
+	cmd.Flags().DurationVarP(&o.WatchTimeout, "watch-timeout", "", graph.DefaultWatchTimeout,
+		i18n.T("The max duration to wait for each applied resource to be ready"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0,
+		i18n.T("The max duration of the whole command, in-flight resources are canceled after it. Zero means no timeout"))
+	cmd.Flags().DurationVarP(&o.ResourceTimeout, "resource-timeout", "", 0,
+		i18n.T("The max duration to read, apply and wait for each resource. Zero means no timeout"))
+	cmd.Flags().IntVarP(&o.Parallelism, "parallelism", "", 0,
+		i18n.T("The max number of resources applied concurrently. Zero means no limit"))
+	cmd.Flags().StringToIntVarP(&o.RuntimeParallelism, "runtime-parallelism", "", map[string]int{},
+		i18n.T("The max number of resources applied concurrently per runtime, e.g. Kubernetes=10. Zero means no limit"))
+	cmd.Flags().StringSliceVarP(&o.Targets, "target", "", []string{},
+		i18n.T("Only apply resources matching the IDs or glob patterns, together with resources they depend on"))
+	cmd.Flags().StringSliceVarP(&o.Excludes, "exclude", "", []string{},
+		i18n.T("Do not apply resources matching the IDs or glob patterns"))
+	cmd.Flags().BoolVarP(&o.ServerSide, "server-side", "", false,
+		i18n.T("Apply Kubernetes resources by server-side apply instead of client-side three-way merge patch"))
+	cmd.Flags().BoolVarP(&o.ForceConflicts, "force-conflicts", "", false,
+		i18n.T("Take the ownership of fields managed by other field managers when applying with --server-side"))
+	cmd.Flags().BoolVarP(&o.NoKindOrder, "no-kind-order", "", false,
+		i18n.T("Do not order Kubernetes resources by their kinds, e.g. Namespaces and CRDs before other resources, but only by dependencies"))
 
 	return cmd
 }
@@ -1,12 +1,15 @@
 package apply
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"kusionstack.io/kusion/pkg/engine/states/local"
 
@@ -23,20 +26,58 @@ import (
 	"kusionstack.io/kusion/pkg/engine/runtime"
 	"kusionstack.io/kusion/pkg/engine/states"
 	compilecmd "kusionstack.io/kusion/pkg/kusionctl/cmd/compile"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util/pretty"
+	"kusionstack.io/kusion/pkg/util/signals"
 )
 
 // ApplyOptions defines flags for the `apply` command
 type ApplyOptions struct {
 	compilecmd.CompileOptions
-	Operator    string
-	Yes         bool
-	Detail      bool
-	NoStyle     bool
-	DryRun      bool
-	OnlyPreview bool
+	Operator     string
+	Yes          bool
+	Detail       bool
+	NoStyle      bool
+	DryRun       bool
+	OnlyPreview  bool
+	WatchTimeout time.Duration
+
+	// Timeout is the max duration of the whole command, and ResourceTimeout is the max duration of each resource.
+	// Zero means no timeout
+	Timeout         time.Duration
+	ResourceTimeout time.Duration
+
+	// Parallelism is the max number of resources executed concurrently, and RuntimeParallelism is that of every
+	// runtime keyed by resource types. Zero means no limit
+	Parallelism        int
+	RuntimeParallelism map[string]int
+
+	// Targets and Excludes are resource IDs or glob patterns to select resources in the stack
+	Targets  []string
+	Excludes []string
+
+	ServerSide     bool
+	ForceConflicts bool
+
+	// PlanOut is the file to save the preview as a plan, and PlanFile is a saved plan to apply without compiling
+	// KCL and previewing again
+	PlanOut  string
+	PlanFile string
+
+	// plan is the loaded PlanFile
+	plan *opsmodels.Plan
+
+	// Spec is previewed and applied instead of compiling KCL, e.g. resources of a previous state to roll back to
+	Spec *models.Spec
+
+	// NoKindOrder turns off the default order of Kubernetes resources by their kinds
+	NoKindOrder bool
+
+	// stopCh is closed on interrupts to stop applying gracefully
+	stopCh <-chan struct{}
 }
 
 // NewApplyOptions returns a new ApplyOptions instance
@@ -52,10 +93,30 @@ func NewApplyOptions() *ApplyOptions {
 }
 
 func (o *ApplyOptions) Complete(args []string) {
+	// KCL files end with .k, so a JSON file is a saved plan
+	if len(args) == 1 && filepath.Ext(args[0]) == ".json" {
+		o.PlanFile = args[0]
+		args = nil
+	}
 	o.CompileOptions.Complete(args)
 }
 
 func (o *ApplyOptions) Validate() error {
+	if o.ForceConflicts && !o.ServerSide {
+		return errors.New("--force-conflicts only works with --server-side")
+	}
+	if err := util.ValidateParallelism(o.Parallelism, o.RuntimeParallelism); err != nil {
+		return err
+	}
+	if o.PlanFile != "" {
+		if o.PlanOut != "" || o.OnlyPreview {
+			return errors.New("a saved plan can only be applied")
+		}
+		if len(o.Targets) != 0 || len(o.Excludes) != 0 || len(o.Arguments) != 0 || len(o.Overrides) != 0 || o.NoKindOrder {
+			return errors.New("--target, --exclude, --argument, --overrides and --no-kind-order can't be used with a saved plan, " +
+				"since they are decided when previewing it")
+		}
+	}
 	return o.CompileOptions.Validate()
 }
 
@@ -66,33 +127,70 @@ func (o *ApplyOptions) Run() error {
 		pterm.EnableColor()
 	}
 
+	ctx, cancel := util.NewTimeoutContext(o.Timeout)
+	defer cancel()
+	// stop gracefully and save the state of completed resources on interrupts. The preview is canceled by the
+	// stopCtx, while runtime calls in flight when applying are not, since they may have changed the actual infra
+	stopCtx, stopApplying := context.WithCancel(ctx)
+	defer stopApplying()
+	o.stopCh = stopCtx.Done()
+	stop := signals.HandleInterrupt(stopApplying)
+	defer stop()
+
 	// Parse project and stack of work directory
 	project, stack, err := projectstack.DetectProjectAndStack(o.CompileOptions.WorkDir)
 	if err != nil {
 		return err
 	}
+	if o.PlanFile != "" {
+		return o.applyPlan(ctx, project, stack)
+	}
 
 	// Get compile result
-	planResources, sp, err := compile.CompileWithSpinner(o.CompileOptions.WorkDir, o.CompileOptions.Filenames, o.CompileOptions.Settings, o.CompileOptions.Arguments, o.Overrides, stack)
-	if err != nil {
-		sp.Fail()
-		return err
+	planResources := o.Spec
+	if planResources == nil {
+		var sp *pterm.SpinnerPrinter
+		planResources, sp, err = compile.CompileWithSpinner(o.CompileOptions.WorkDir, o.CompileOptions.Filenames, o.CompileOptions.Settings, o.CompileOptions.Arguments, o.Overrides, stack)
+		if err != nil {
+			sp.Fail()
+			return err
+		}
+		sp.Success() // Resolve spinner with success message.
+		pterm.Println()
 	}
-	sp.Success() // Resolve spinner with success message.
-	pterm.Println()
 
 	// Compute changes for preview
 	stateStorage := &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)}
-	kubernetesRuntime, err := runtime.NewKubernetesRuntime()
+	runtimes, err := runtime.InitRuntimes(nil, planResources.Resources)
 	if err != nil {
 		return err
 	}
 
-	changes, err := Preview(o, kubernetesRuntime, stateStorage, planResources, project, stack, os.Stdout)
+	// the state the preview is computed against, which is recorded in the saved plan
+	var priorState *states.State
+	if o.PlanOut != "" {
+		priorState, err = stateStorage.GetLatestState(&states.StateQuery{
+			Tenant:  project.Tenant,
+			Project: project.Name,
+			Stack:   stack.Name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	changes, err := Preview(stopCtx, o, runtimes, stateStorage, planResources, project, stack, os.Stdout)
 	if err != nil {
 		return err
 	}
 
+	if o.PlanOut != "" {
+		if err = savePlan(o, planResources, changes, priorState); err != nil {
+			return err
+		}
+		fmt.Printf("Plan saved to %s. Apply it by `kusion apply %s`\n\n", o.PlanOut, o.PlanOut)
+	}
+
 	if allUnChange(changes) {
 		fmt.Println("All resources are reconciled. No diff found")
 		return nil
@@ -100,6 +198,7 @@ func (o *ApplyOptions) Run() error {
 
 	// Summary preview table
 	changes.Summary()
+	warnPartial(o.Targets, o.Excludes)
 
 	// Detail detection
 	if o.Detail && !o.Yes {
@@ -108,46 +207,123 @@ func (o *ApplyOptions) Run() error {
 	}
 
 	// Prompt
-	if !o.Yes {
-		for {
-			input, err := prompt(o.OnlyPreview)
-			if err != nil {
-				return err
-			}
-			if input == "yes" {
-				break
-			} else if input == "details" {
-				target, err := changes.PromptDetails()
-				if err != nil {
-					return err
-				}
-				changes.OutputDiff(target)
-			} else {
-				fmt.Println("Operation apply canceled")
-				return nil
-			}
-		}
+	if confirmed, err := o.confirm(changes); err != nil || !confirmed {
+		return err
 	}
 
 	if !o.OnlyPreview {
 		fmt.Println("Start applying diffs ...")
-		if err := Apply(o, kubernetesRuntime, stateStorage, planResources, changes, os.Stdout); err != nil {
+		if err := Apply(ctx, o, runtimes, stateStorage, planResources, changes, os.Stdout); err != nil {
 			return err
 		}
 
 		// If dry run, print the hint
 		if o.DryRun {
-			fmt.Printf("\nNOTE: Currently running in the --dry-run mode, the above configuration does not really take effect\n")
+			fmt.Printf("\nNOTE: Currently running in the --dry-run mode, all changes are validated by the runtimes but not persisted, and the state is not updated\n")
 		}
 	}
 
 	return nil
 }
 
+// applyPlan applies the saved plan without compiling KCL and previewing again. It is refused if the state has been
+// changed since the plan was created, and fails if the action of any resource is different from the plan
+func (o *ApplyOptions) applyPlan(ctx context.Context, project *projectstack.Project, stack *projectstack.Stack) error {
+	plan, err := opsmodels.LoadPlan(o.PlanFile)
+	if err != nil {
+		return err
+	}
+	stateStorage := &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)}
+	latestState, err := stateStorage.GetLatestState(&states.StateQuery{
+		Tenant:  project.Tenant,
+		Project: project.Name,
+		Stack:   stack.Name,
+	})
+	if err != nil {
+		return err
+	}
+	if err = plan.Check(project.Name, stack.Name, latestState); err != nil {
+		return fmt.Errorf("can't apply plan %s: %v", o.PlanFile, err)
+	}
+
+	changes := opsmodels.NewChanges(project, stack, plan.ChangeOrder)
+	if allUnChange(changes) {
+		fmt.Println("All resources are reconciled. No diff found")
+		return nil
+	}
+	changes.Summary()
+	warnPartial(plan.Targets, plan.Excludes)
+	if confirmed, err := o.confirm(changes); err != nil || !confirmed {
+		return err
+	}
+
+	runtimes, err := runtime.InitRuntimes(nil, plan.Spec.Resources)
+	if err != nil {
+		return err
+	}
+	o.Targets, o.Excludes, o.NoKindOrder = plan.Targets, plan.Excludes, plan.DisableKindOrder
+	o.plan = plan
+
+	fmt.Println("Start applying diffs ...")
+	if err = Apply(ctx, o, runtimes, stateStorage, plan.Spec, changes, os.Stdout); err != nil {
+		return err
+	}
+	if o.DryRun {
+		fmt.Printf("\nNOTE: Currently running in the --dry-run mode, all changes are validated by the runtimes but not persisted, and the state is not updated\n")
+	}
+	return nil
+}
+
+// confirm prompts users to apply the changes unless Yes is set. It returns false if users cancel the operation
+func (o *ApplyOptions) confirm(changes *opsmodels.Changes) (bool, error) {
+	if o.Yes {
+		return true, nil
+	}
+	for {
+		input, err := prompt(o.OnlyPreview)
+		if err != nil {
+			return false, err
+		}
+		if input == "yes" {
+			return true, nil
+		} else if input == "details" {
+			target, err := changes.PromptDetails()
+			if err != nil {
+				return false, err
+			}
+			changes.OutputDiff(target)
+		} else {
+			fmt.Println("Operation apply canceled")
+			return false, nil
+		}
+	}
+}
+
+// savePlan saves the preview as a plan into PlanOut
+func savePlan(o *ApplyOptions, planResources *models.Spec, changes *opsmodels.Changes, priorState *states.State) error {
+	plan, err := opsmodels.NewPlan(&opsmodels.Request{
+		Tenant:   changes.Project().Tenant,
+		Project:  changes.Project().Name,
+		Operator: o.Operator,
+		Stack:    changes.Stack().Name,
+		Spec:     planResources,
+		Targets:  o.Targets,
+		Excludes: o.Excludes,
+		// working directories of hooks are kept relative to the stack, so that the plan can be applied elsewhere
+		Hooks: changes.Stack().Hooks,
+
+		DisableKindOrder: o.NoKindOrder,
+	}, changes.ChangeOrder, priorState)
+	if err != nil {
+		return err
+	}
+	return plan.Save(o.PlanOut)
+}
+
 // The Preview function calculates the upcoming actions of each resource
 // through the execution Kusion Engine, and you can customize the
 // runtime of engine and the state storage through `runtime` and
-// `storage` parameters.
+// `storage` parameters. The preview is canceled when the ctx is done.
 //
 // Example:
 //   o := NewApplyOptions()
@@ -159,15 +335,16 @@ func (o *ApplyOptions) Run() error {
 //       return err
 //   }
 //
-//   changes, err := Preview(o, kubernetesRuntime, stateStorage,
+//   changes, err := Preview(context.Background(), o, kubernetesRuntime, stateStorage,
 //       planResources, project, stack, os.Stdout)
 //   if err != nil {
 //       return err
 //   }
 // todo @elliotxx io.Writer is not used now
 func Preview(
+	ctx context.Context,
 	o *ApplyOptions,
-	runtime runtime.Runtime,
+	runtimes map[models.Type]runtime.Runtime,
 	storage states.StateStorage,
 	planResources *models.Spec,
 	project *projectstack.Project,
@@ -179,22 +356,30 @@ func Preview(
 	// Construct the preview operation
 	pc := &operation.PreviewOperation{
 		Operation: opsmodels.Operation{
-			OperationType: types.ApplyPreview,
-			Runtime:       runtime,
-			StateStorage:  storage,
-			ChangeOrder:   &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
+			OperationType:      types.ApplyPreview,
+			RuntimeMap:         runtimes,
+			StateStorage:       storage,
+			StackStorage:       util.NewLocalStackStorage(project),
+			ChangeOrder:        &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
+			ResourceTimeout:    o.ResourceTimeout,
+			Parallelism:        o.Parallelism,
+			RuntimeParallelism: util.ToRuntimeParallelism(o.RuntimeParallelism),
 		},
 	}
 
 	log.Info("Start call pc.Preview() ...")
 
-	rsp, s := pc.Preview(&operation.PreviewRequest{
+	rsp, s := pc.Preview(ctx, &operation.PreviewRequest{
 		Request: opsmodels.Request{
 			Tenant:   project.Tenant,
 			Project:  project.Name,
 			Operator: o.Operator,
 			Stack:    stack.Name,
 			Spec:     planResources,
+			Targets:  o.Targets,
+			Excludes: o.Excludes,
+
+			DisableKindOrder: o.NoKindOrder,
 		},
 	})
 	if status.IsErr(s) {
@@ -210,6 +395,9 @@ func Preview(
 //
 // You can customize the runtime of engine and the state
 // storage through `runtime` and `storage` parameters.
+// In-flight resources are canceled when the ctx is done.
+// Once the stopCh of o is closed, resources not started are skipped, and
+// in-flight resources finish and are saved in the state.
 //
 // Example:
 //   o := NewApplyOptions()
@@ -221,13 +409,14 @@ func Preview(
 //       return err
 //   }
 //
-//   err = Apply(o, kubernetesRuntime, stateStorage, planResources, changes, os.Stdout)
+//   err = Apply(context.Background(), o, kubernetesRuntime, stateStorage, planResources, changes, os.Stdout)
 //   if err != nil {
 //       return err
 //   }
 func Apply(
+	ctx context.Context,
 	o *ApplyOptions,
-	runtime runtime.Runtime,
+	runtimes map[models.Type]runtime.Runtime,
 	storage states.StateStorage,
 	planResources *models.Spec,
 	changes *opsmodels.Changes,
@@ -236,9 +425,18 @@ func Apply(
 	// Construct the apply operation
 	ac := &operation.ApplyOperation{
 		Operation: opsmodels.Operation{
-			Runtime:      runtime,
-			StateStorage: storage,
-			MsgCh:        make(chan opsmodels.Message),
+			RuntimeMap:         runtimes,
+			StateStorage:       storage,
+			StackStorage:       util.NewLocalStackStorage(changes.Project()),
+			MsgCh:              make(chan opsmodels.Message),
+			StopCh:             o.stopCh,
+			WatchTimeout:       o.WatchTimeout,
+			ResourceTimeout:    o.ResourceTimeout,
+			Parallelism:        o.Parallelism,
+			RuntimeParallelism: util.ToRuntimeParallelism(o.RuntimeParallelism),
+			DryRun:             o.DryRun,
+			ServerSideApply:    o.ServerSide,
+			ForceConflicts:     o.ForceConflicts,
 		},
 	}
 
@@ -250,16 +448,18 @@ func Apply(
 	if err != nil {
 		return err
 	}
+	// Results of resources, which are reported if the operation is interrupted
+	results := map[string]opsmodels.OpResult{}
 	// Wait msgCh close
 	var wg sync.WaitGroup
 	// Receive msg and print detail
+	wg.Add(1)
 	go func() {
 		defer func() {
 			if p := recover(); p != nil {
 				log.Errorf("failed to receive msg and print detail as %v", p)
 			}
 		}()
-		wg.Add(1)
 
 		for {
 			select {
@@ -269,6 +469,14 @@ func Apply(
 					return
 				}
 				changeStep := changes.Get(msg.ResourceID)
+				if changeStep == nil {
+					// messages of hooks, which are not change steps
+					printHookMsg(out, progressbar, msg)
+					continue
+				}
+				if msg.OpResult != "" {
+					results[msg.ResourceID] = msg.OpResult
+				}
 
 				switch msg.OpResult {
 				case opsmodels.Success, opsmodels.Skip:
@@ -309,40 +517,56 @@ func Apply(
 		}
 	}()
 
-	if o.DryRun {
-		for _, r := range planResources.Resources {
-			ac.MsgCh <- opsmodels.Message{
-				ResourceID: r.ResourceKey(),
-				OpResult:   opsmodels.Success,
-				OpErr:      nil,
-			}
-		}
-		close(ac.MsgCh)
-	} else {
-		_, st := ac.Apply(&operation.ApplyRequest{
-			Request: opsmodels.Request{
-				Tenant:   changes.Project().Tenant,
-				Project:  changes.Project().Name,
-				Operator: o.Operator,
-				Stack:    changes.Stack().Name,
-				Spec:     planResources,
-			},
-		})
-		if status.IsErr(st) {
-			return fmt.Errorf("apply failed, status: %v", st)
+	hooks := stackHooks(changes.Stack())
+	if o.plan != nil {
+		// apply exactly what is previewed in the plan
+		ac.PlannedOrder = o.plan.ChangeOrder
+		hooks = resolveHooks(o.plan.Hooks, changes.Stack().Path)
+	}
+
+	_, st := ac.Apply(ctx, &operation.ApplyRequest{
+		Request: opsmodels.Request{
+			Tenant:   changes.Project().Tenant,
+			Project:  changes.Project().Name,
+			Operator: o.Operator,
+			Stack:    changes.Stack().Name,
+			Spec:     planResources,
+			Targets:  o.Targets,
+			Excludes: o.Excludes,
+			Hooks:    hooks,
+
+			DisableKindOrder: o.NoKindOrder,
+		},
+	})
+	if status.IsErr(st) {
+		if st.Code() == status.Canceled {
+			// Wait for msgCh closed, and then report which resources are completed
+			wg.Wait()
+			_, _ = progressbar.Stop()
+			pterm.Fprintln(out)
+			changes.InterruptedSummary("Apply", results)
 		}
+		return fmt.Errorf("apply failed, status: %v", st)
 	}
 
 	// Wait for msgCh closed
 	wg.Wait()
 	// Print summary
 	pterm.Fprintln(out)
-	pterm.Fprintln(out, fmt.Sprintf("Apply complete! Resources: %d created, %d updated, %d deleted.", ls.created, ls.updated, ls.deleted))
+	pterm.Fprintln(out, fmt.Sprintf("Apply complete! Resources: %d created, %d updated, %d replaced, %d deleted, %d retained.",
+		ls.created, ls.updated, ls.replaced, ls.deleted, ls.retained))
 	return nil
 }
 
+// warnPartial warns that the stack will be partially applied if resources are selected by --target or --exclude
+func warnPartial(targets, excludes []string) {
+	if len(targets) != 0 || len(excludes) != 0 {
+		pterm.Warning.Println("Only resources selected by --target and --exclude are previewed and applied, the stack will be partially applied")
+	}
+}
+
 type lineSummary struct {
-	created, updated, deleted int
+	created, updated, replaced, deleted, retained int
 }
 
 func (ls *lineSummary) Count(op types.ActionType) {
@@ -351,8 +575,12 @@ func (ls *lineSummary) Count(op types.ActionType) {
 		ls.created++
 	case types.Update:
 		ls.updated++
+	case types.Replace, types.ReplaceCreateBeforeDelete:
+		ls.replaced++
 	case types.Delete:
 		ls.deleted++
+	case types.Retain:
+		ls.retained++
 	}
 }
 
@@ -387,3 +615,38 @@ func prompt(onlyPreview bool) (string, error) {
 	}
 	return input, nil
 }
+
+// printHookMsg prints logs and results of hooks, which don't count in the progress of resources
+func printHookMsg(out io.Writer, progressbar *pterm.ProgressbarPrinter, msg opsmodels.Message) {
+	switch {
+	case msg.Log != "":
+		pterm.Fprintln(out, pretty.Gray("%s | %s", msg.ResourceID, msg.Log))
+	case msg.OpResult == opsmodels.Success:
+		pterm.Success.WithWriter(out).Printf("Run %s succeeded\n", pterm.Bold.Sprint(msg.ResourceID))
+	case msg.OpResult == opsmodels.Failed:
+		pterm.Error.WithWriter(out).Printf("Run %s failed, %v\n", pterm.Bold.Sprint(msg.ResourceID), msg.OpErr)
+	default:
+		progressbar.UpdateTitle(fmt.Sprintf("Running %s", pterm.Bold.Sprint(msg.ResourceID)))
+	}
+}
+
+// stackHooks returns hooks of the stack, whose relative working directories are resolved against the stack directory
+func stackHooks(stack *projectstack.Stack) []*models.Hook {
+	if stack == nil {
+		return nil
+	}
+	return resolveHooks(stack.Hooks, stack.Path)
+}
+
+// resolveHooks returns copies of hooks, whose relative working directories are resolved against the dir
+func resolveHooks(hooks []*models.Hook, dir string) []*models.Hook {
+	resolved := make([]*models.Hook, 0, len(hooks))
+	for _, h := range hooks {
+		hook := *h
+		if !filepath.IsAbs(hook.WorkDir) {
+			hook.WorkDir = filepath.Join(dir, hook.WorkDir)
+		}
+		resolved = append(resolved, &hook)
+	}
+	return resolved
+}
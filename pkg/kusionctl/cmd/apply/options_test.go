@@ -97,6 +97,29 @@ var (
 	}
 )
 
+func TestApplyOptions_Validate(t *testing.T) {
+	t.Run("force conflicts without server side", func(t *testing.T) {
+		o := NewApplyOptions()
+		o.ForceConflicts = true
+		assert.Error(t, o.Validate())
+	})
+	t.Run("negative runtime parallelism", func(t *testing.T) {
+		o := NewApplyOptions()
+		o.RuntimeParallelism = map[string]int{"Kubernetes": -1}
+		assert.Error(t, o.Validate())
+	})
+	t.Run("targets with a plan file", func(t *testing.T) {
+		o := NewApplyOptions()
+		o.Complete([]string{"plan.json"})
+		assert.Equal(t, "plan.json", o.PlanFile)
+		assert.Empty(t, o.Filenames)
+		assert.NoError(t, o.Validate())
+
+		o.Targets = []string{"v1:Namespace:default"}
+		assert.Error(t, o.Validate())
+	})
+}
+
 func mockDetectProjectAndStack() {
 	monkey.Patch(projectstack.DetectProjectAndStack, func(stackDir string) (*projectstack.Project, *projectstack.Stack, error) {
 		project.Path = stackDir
@@ -126,7 +149,7 @@ func Test_preview(t *testing.T) {
 		mockOperationPreview()
 
 		o := NewApplyOptions()
-		_, err := Preview(o, &fakerRuntime{}, stateStorage, &models.Spec{Resources: []models.Resource{sa1, sa2, sa3}}, project, stack, os.Stdout)
+		_, err := Preview(context.Background(), o, map[models.Type]runtime.Runtime{models.Kubernetes: &fakerRuntime{}}, stateStorage, &models.Spec{Resources: []models.Resource{sa1, sa2, sa3}}, project, stack, os.Stdout)
 		assert.Nil(t, err)
 	})
 }
@@ -171,7 +194,7 @@ func (f *fakerRuntime) Watch(ctx context.Context, request *runtime.WatchRequest)
 
 func mockOperationPreview() {
 	monkey.Patch((*operation.PreviewOperation).Preview,
-		func(*operation.PreviewOperation, *operation.PreviewRequest) (rsp *operation.PreviewResponse, s status.Status) {
+		func(*operation.PreviewOperation, context.Context, *operation.PreviewRequest) (rsp *operation.PreviewResponse, s status.Status) {
 			return &operation.PreviewResponse{
 				Order: &opsmodels.ChangeOrder{
 					StepKeys: []string{sa1.ID, sa2.ID, sa3.ID},
@@ -232,6 +255,12 @@ func Test_apply(t *testing.T) {
 	stateStorage := &local.FileSystemState{Path: filepath.Join("", local.KusionState)}
 	t.Run("dry run", func(t *testing.T) {
 		defer monkey.UnpatchAll()
+		monkey.Patch((*operation.ApplyOperation).Apply,
+			func(o *operation.ApplyOperation, ctx context.Context, request *operation.ApplyRequest) (*operation.ApplyResponse, status.Status) {
+				assert.True(t, o.DryRun)
+				close(o.MsgCh)
+				return &operation.ApplyResponse{}, nil
+			})
 
 		planResources := &models.Spec{Resources: []models.Resource{sa1}}
 		order := &opsmodels.ChangeOrder{
@@ -248,7 +277,7 @@ func Test_apply(t *testing.T) {
 		changes := opsmodels.NewChanges(project, stack, order)
 		o := NewApplyOptions()
 		o.DryRun = true
-		err := Apply(o, &fakerRuntime{}, stateStorage, planResources, changes, os.Stdout)
+		err := Apply(context.Background(), o, map[models.Type]runtime.Runtime{models.Kubernetes: &fakerRuntime{}}, stateStorage, planResources, changes, os.Stdout)
 		assert.Nil(t, err)
 	})
 	t.Run("apply success", func(t *testing.T) {
@@ -276,7 +305,7 @@ func Test_apply(t *testing.T) {
 		}
 		changes := opsmodels.NewChanges(project, stack, order)
 
-		err := Apply(o, &fakerRuntime{}, stateStorage, planResources, changes, os.Stdout)
+		err := Apply(context.Background(), o, map[models.Type]runtime.Runtime{models.Kubernetes: &fakerRuntime{}}, stateStorage, planResources, changes, os.Stdout)
 		assert.Nil(t, err)
 	})
 	t.Run("apply failed", func(t *testing.T) {
@@ -298,14 +327,39 @@ func Test_apply(t *testing.T) {
 		}
 		changes := opsmodels.NewChanges(project, stack, order)
 
-		err := Apply(o, &fakerRuntime{}, stateStorage, planResources, changes, os.Stdout)
+		err := Apply(context.Background(), o, map[models.Type]runtime.Runtime{models.Kubernetes: &fakerRuntime{}}, stateStorage, planResources, changes, os.Stdout)
+		assert.NotNil(t, err)
+	})
+	t.Run("apply interrupted", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		monkey.Patch((*operation.ApplyOperation).Apply,
+			func(o *operation.ApplyOperation, ctx context.Context, request *operation.ApplyRequest) (*operation.ApplyResponse, status.Status) {
+				// sa1 is completed, and sa2 is not started before the operation is canceled
+				o.MsgCh <- opsmodels.Message{ResourceID: sa1.ID}
+				o.MsgCh <- opsmodels.Message{ResourceID: sa1.ID, OpResult: opsmodels.Success}
+				close(o.MsgCh)
+				return nil, status.NewErrorStatusWithCode(status.Canceled, context.Canceled)
+			})
+
+		o := NewApplyOptions()
+		planResources := &models.Spec{Resources: []models.Resource{sa1, sa2}}
+		order := &opsmodels.ChangeOrder{
+			StepKeys: []string{sa1.ID, sa2.ID},
+			ChangeSteps: map[string]*opsmodels.ChangeStep{
+				sa1.ID: {ID: sa1.ID, Action: types.Create, Modified: &sa1},
+				sa2.ID: {ID: sa2.ID, Action: types.Create, Modified: &sa2},
+			},
+		}
+		changes := opsmodels.NewChanges(project, stack, order)
+
+		err := Apply(context.Background(), o, map[models.Type]runtime.Runtime{models.Kubernetes: &fakerRuntime{}}, stateStorage, planResources, changes, os.Stdout)
 		assert.NotNil(t, err)
 	})
 }
 
 func mockOperationApply(res opsmodels.OpResult) {
 	monkey.Patch((*operation.ApplyOperation).Apply,
-		func(o *operation.ApplyOperation, request *operation.ApplyRequest) (*operation.ApplyResponse, status.Status) {
+		func(o *operation.ApplyOperation, ctx context.Context, request *operation.ApplyRequest) (*operation.ApplyResponse, status.Status) {
 			var err error
 			if res == opsmodels.Failed {
 				err = errors.New("mock error")
@@ -331,6 +385,35 @@ func mockOperationApply(res opsmodels.OpResult) {
 		})
 }
 
+func Test_savePlan(t *testing.T) {
+	hookStack := &projectstack.Stack{
+		StackConfiguration: projectstack.StackConfiguration{
+			Name:  "dev",
+			Hooks: []*models.Hook{{Name: "migrate", Phase: models.PreApply, Command: "make migrate", WorkDir: "scripts"}},
+		},
+		Path: "/path/to/dev",
+	}
+	order := &opsmodels.ChangeOrder{
+		StepKeys: []string{sa1.ID},
+		ChangeSteps: map[string]*opsmodels.ChangeStep{
+			sa1.ID: {ID: sa1.ID, Action: types.Create, Modified: &sa1},
+		},
+	}
+	o := NewApplyOptions()
+	o.PlanOut = filepath.Join(t.TempDir(), "plan.json")
+	err := savePlan(o, &models.Spec{Resources: []models.Resource{sa1}}, opsmodels.NewChanges(project, hookStack, order), nil)
+	assert.Nil(t, err)
+
+	plan, err := opsmodels.LoadPlan(o.PlanOut)
+	assert.Nil(t, err)
+	assert.Equal(t, types.Create, plan.ChangeOrder.Get(sa1.ID).Action)
+	assert.Equal(t, "scripts", plan.Hooks[0].WorkDir)
+	// working directories are resolved against the stack where the plan is applied
+	assert.Equal(t, "/other/dev/scripts", resolveHooks(plan.Hooks, "/other/dev")[0].WorkDir)
+	// hooks in the plan are copied instead of being resolved in place
+	assert.Equal(t, "scripts", plan.Hooks[0].WorkDir)
+}
+
 func Test_prompt(t *testing.T) {
 	t.Run("prompt error", func(t *testing.T) {
 		monkey.Patch(
@@ -8,16 +8,23 @@ import (
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/kubectl/pkg/util/templates"
 
+	"kusionstack.io/kusion/pkg/engine/runtime/plugin"
 	"kusionstack.io/kusion/pkg/kusionctl/cmd/apply"
 	"kusionstack.io/kusion/pkg/kusionctl/cmd/check"
 	"kusionstack.io/kusion/pkg/kusionctl/cmd/compile"
 	"kusionstack.io/kusion/pkg/kusionctl/cmd/deps"
 	"kusionstack.io/kusion/pkg/kusionctl/cmd/destroy"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/drift"
 	"kusionstack.io/kusion/pkg/kusionctl/cmd/env"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/imports"
 	cmdinit "kusionstack.io/kusion/pkg/kusionctl/cmd/init"
 	"kusionstack.io/kusion/pkg/kusionctl/cmd/ls"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/output"
 	"kusionstack.io/kusion/pkg/kusionctl/cmd/preview"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/refresh"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/rollback"
 	"kusionstack.io/kusion/pkg/kusionctl/cmd/version"
+	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/util/i18n"
 )
 
@@ -53,6 +60,11 @@ func NewKusionctlCmd(in io.Reader, out, err io.Writer) *cobra.Command {
 	// the language, instead of just loading from the LANG env. variable.
 	_ = i18n.LoadTranslations("kusion", nil)
 
+	// Register runtime plugins installed under the kusion data folder, so that they can be used like built-in runtimes
+	if err := plugin.RegisterRuntimes(); err != nil {
+		log.Warnf("failed to register runtime plugins: %v", err)
+	}
+
 	// Parent command to which all subcommands are added.
 	cmds := &cobra.Command{
 		Use:           "kusion",
@@ -84,6 +96,11 @@ func NewKusionctlCmd(in io.Reader, out, err io.Writer) *cobra.Command {
 				preview.NewCmdPreview(),
 				apply.NewCmdApply(),
 				destroy.NewCmdDestroy(),
+				imports.NewCmdImport(),
+				refresh.NewCmdRefresh(),
+				drift.NewCmdDrift(),
+				output.NewCmdOutput(),
+				rollback.NewCmdRollback(),
 			},
 		},
 	}
@@ -12,10 +12,12 @@ var (
 	destroyShort = `Destroy a configuration stack to resource(s) by work directory`
 
 	destroyLong = `
-		Delete resources by resource spec.
+		Delete resources of the stack recorded in the state.
 
-		Only KCL files are accepted. Only one type of arguments may be specified: filenames,
-		resources and names, or resources and label selector.
+		By default, all resources in the state are deleted without compiling KCL, so resources are never left behind
+		even if the KCL code has been deleted or no longer compiles. With --compile, the KCL code is compiled and only
+		resources in both the compiled spec and the state are deleted. After all resources are deleted, the state is
+		marked empty.
 
 		Note that the destroy command does NOT do resource version checks, so if someone submits an
 		update to a resource right when you submit a destroy, their update will be lost along with the
@@ -23,7 +25,10 @@ var (
 
 	destroyExample = `
 		# Delete the configuration of current stack
-		kusion destroy`
+		kusion destroy
+
+		# Only delete resources in the compiled KCL files
+		kusion destroy main.k --compile`
 )
 
 func NewCmdDestroy() *cobra.Command {
@@ -48,7 +53,7 @@ func NewCmdDestroy() *cobra.Command {
 	cmd.Flags().StringVarP(&o.Operator, "operator", "", "",
 		i18n.T("Specify the operator"))
 	cmd.Flags().StringSliceVarP(&o.CompileOptions.Arguments, "argument", "D", []string{},
-		i18n.T("Specify the arguments for compile KCL"))
+		i18n.T("Specify the arguments for compile KCL, which only works with --compile"))
 	cmd.Flags().StringSliceVarP(&o.CompileOptions.Settings, "setting", "Y", []string{},
 		i18n.T("Specify the command line setting files"))
 	cmd.Flags().StringSliceVarP(&o.CompileOptions.Overrides, "overrides", "O", []string{},
@@ -57,6 +62,22 @@ func NewCmdDestroy() *cobra.Command {
 		i18n.T("Automatically approve and perform the update after previewing it"))
 	cmd.Flags().BoolVarP(&o.Detail, "detail", "d", false,
 		i18n.T("Automatically show plan details after previewing it"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0,
+		i18n.T("The max duration of the whole command, in-flight resources are canceled after it. Zero means no timeout"))
+	cmd.Flags().DurationVarP(&o.ResourceTimeout, "resource-timeout", "", 0,
+		i18n.T("The max duration to read and delete each resource. Zero means no timeout"))
+	cmd.Flags().IntVarP(&o.Parallelism, "parallelism", "", 0,
+		i18n.T("The max number of resources deleted concurrently. Zero means no limit"))
+	cmd.Flags().StringToIntVarP(&o.RuntimeParallelism, "runtime-parallelism", "", map[string]int{},
+		i18n.T("The max number of resources deleted concurrently per runtime, e.g. Kubernetes=10. Zero means no limit"))
+	cmd.Flags().StringSliceVarP(&o.Targets, "target", "", []string{},
+		i18n.T("Only destroy resources matching the IDs or glob patterns, together with resources depending on them"))
+	cmd.Flags().StringSliceVarP(&o.Excludes, "exclude", "", []string{},
+		i18n.T("Do not destroy resources matching the IDs or glob patterns"))
+	cmd.Flags().BoolVarP(&o.Compile, "compile", "", false,
+		i18n.T("Compile KCL and only destroy resources in both the compiled spec and the state"))
+	cmd.Flags().BoolVarP(&o.NoKindOrder, "no-kind-order", "", false,
+		i18n.T("Do not order Kubernetes resources by their kinds, e.g. Namespaces and CRDs after other resources, but only by dependencies"))
 
 	return cmd
 }
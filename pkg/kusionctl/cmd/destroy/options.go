@@ -1,10 +1,13 @@
 package destroy
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"kusionstack.io/kusion/pkg/engine/states/local"
 
@@ -18,8 +21,8 @@ import (
 
 	"kusionstack.io/kusion/pkg/compile"
 	"kusionstack.io/kusion/pkg/engine/models"
-	"kusionstack.io/kusion/pkg/engine/runtime"
 	compilecmd "kusionstack.io/kusion/pkg/kusionctl/cmd/compile"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
 	"kusionstack.io/kusion/pkg/log"
 	"kusionstack.io/kusion/pkg/projectstack"
 	"kusionstack.io/kusion/pkg/status"
@@ -31,6 +34,27 @@ type DestroyOptions struct {
 	Operator string
 	Yes      bool
 	Detail   bool
+
+	// Timeout is the max duration of the whole command, and ResourceTimeout is the max duration of each resource.
+	// Zero means no timeout
+	Timeout         time.Duration
+	ResourceTimeout time.Duration
+
+	// Parallelism is the max number of resources executed concurrently, and RuntimeParallelism is that of every
+	// runtime keyed by resource types. Zero means no limit
+	Parallelism        int
+	RuntimeParallelism map[string]int
+
+	// Targets and Excludes are resource IDs or glob patterns to select resources in the stack
+	Targets  []string
+	Excludes []string
+
+	// Compile compiles the KCL code, and only destroys resources in both the compiled spec and the state. By default,
+	// all resources in the state are destroyed without compiling
+	Compile bool
+
+	// NoKindOrder turns off the default order of Kubernetes resources by their kinds
+	NoKindOrder bool
 }
 
 func NewDestroyOptions() *DestroyOptions {
@@ -49,40 +73,64 @@ func (o *DestroyOptions) Complete(args []string) {
 }
 
 func (o *DestroyOptions) Validate() error {
+	if err := util.ValidateParallelism(o.Parallelism, o.RuntimeParallelism); err != nil {
+		return err
+	}
+	if !o.Compile && (len(o.Filenames) != 0 || len(o.Arguments) != 0 || len(o.Overrides) != 0) {
+		return errors.New("KCL files, --argument and --overrides only work with --compile")
+	}
 	return o.CompileOptions.Validate()
 }
 
 func (o *DestroyOptions) Run() error {
-	// listen for interrupts or the SIGTERM signal
-	signals.HandleInterrupt()
+	ctx, cancel := util.NewTimeoutContext(o.Timeout)
+	defer cancel()
+	// stop gracefully and save the state of completed resources on interrupts. The preview is canceled by the
+	// stopCtx, while runtime calls in flight when destroying are not, since they may have changed the actual infra
+	stopCtx, stopDestroying := context.WithCancel(ctx)
+	defer stopDestroying()
+	stop := signals.HandleInterrupt(stopDestroying)
+	defer stop()
+
 	// Parse project and stack of work directory
 	project, stack, err := projectstack.DetectProjectAndStack(o.CompileOptions.WorkDir)
 	if err != nil {
 		return err
 	}
 
-	// Get compile result
-	planResources, sp, err := compile.CompileWithSpinner(o.CompileOptions.WorkDir, o.CompileOptions.Filenames, o.CompileOptions.Settings, o.CompileOptions.Arguments, o.Overrides, stack)
-	if err != nil {
-		sp.Fail()
-		return err
-	}
-	sp.Success() // Resolve spinner with success message.
-	pterm.Println()
+	// Get compile result, which selects resources to destroy in the state
+	var planResources *models.Spec
+	if o.Compile {
+		var sp *pterm.SpinnerPrinter
+		planResources, sp, err = compile.CompileWithSpinner(o.CompileOptions.WorkDir, o.CompileOptions.Filenames, o.CompileOptions.Settings, o.CompileOptions.Arguments, o.Overrides, stack)
+		if err != nil {
+			sp.Fail()
+			return err
+		}
+		sp.Success() // Resolve spinner with success message.
+		pterm.Println()
 
-	if planResources == nil || len(planResources.Resources) == 0 {
-		pterm.Println("No resources to destroy")
-		return nil
+		if planResources == nil || len(planResources.Resources) == 0 {
+			pterm.Println("No resources to destroy")
+			return nil
+		}
 	}
 
 	// Compute changes for preview
-	changes, err := o.preview(planResources, project, stack)
+	changes, err := o.preview(stopCtx, planResources, project, stack)
 	if err != nil {
 		return err
 	}
+	if len(changes.StepKeys) == 0 {
+		pterm.Println("No resources to destroy")
+		return nil
+	}
 
 	// Preview
 	changes.Summary()
+	if len(o.Targets) != 0 || len(o.Excludes) != 0 {
+		pterm.Warning.Println("Only resources selected by --target and --exclude are destroyed, the stack will be partially destroyed")
+	}
 
 	// Detail detection
 	if o.Detail {
@@ -114,40 +162,43 @@ func (o *DestroyOptions) Run() error {
 
 	// Destroy
 	fmt.Println("Start destroying resources......")
-	if err := o.destroy(planResources, changes); err != nil {
+	if err := o.destroy(ctx, stopCtx.Done(), planResources, changes); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (o *DestroyOptions) preview(planResources *models.Spec,
+// preview computes changes to destroy resources in the state. If planResources is not nil, only resources in it are destroyed
+func (o *DestroyOptions) preview(ctx context.Context, planResources *models.Spec,
 	project *projectstack.Project, stack *projectstack.Stack,
 ) (*opsmodels.Changes, error) {
 	log.Info("Start compute preview changes ...")
 
-	kubernetesRuntime, err := runtime.NewKubernetesRuntime()
-	if err != nil {
-		return nil, err
-	}
-
+	// runtimes of resources in the state are initialized by the operation
 	pc := &operation.PreviewOperation{
 		Operation: opsmodels.Operation{
-			OperationType: types.DestroyPreview,
-			Runtime:       kubernetesRuntime,
-			StateStorage:  &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)},
-			ChangeOrder:   &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
+			OperationType:      types.DestroyPreview,
+			StateStorage:       &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)},
+			ChangeOrder:        &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}},
+			ResourceTimeout:    o.ResourceTimeout,
+			Parallelism:        o.Parallelism,
+			RuntimeParallelism: util.ToRuntimeParallelism(o.RuntimeParallelism),
 		},
 	}
 
 	log.Info("Start call pc.Preview() ...")
 
-	rsp, s := pc.Preview(&operation.PreviewRequest{
+	rsp, s := pc.Preview(ctx, &operation.PreviewRequest{
 		Request: opsmodels.Request{
 			Tenant:   project.Tenant,
 			Project:  project.Name,
 			Operator: o.Operator,
 			Stack:    stack.Name,
 			Spec:     planResources,
+			Targets:  o.Targets,
+			Excludes: o.Excludes,
+
+			DisableKindOrder: o.NoKindOrder,
 		},
 	})
 	if status.IsErr(s) {
@@ -157,39 +208,41 @@ func (o *DestroyOptions) preview(planResources *models.Spec,
 	return opsmodels.NewChanges(project, stack, rsp.Order), nil
 }
 
-func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.Changes) error {
-	// Build apply operation
-	kubernetesRuntime, err := runtime.NewKubernetesRuntime()
-	if err != nil {
-		return err
-	}
-
+// destroy destroys resources in the changes. In-flight resources are canceled when the ctx is done, while they finish
+// and are saved in the state once the stopCh is closed
+func (o *DestroyOptions) destroy(ctx context.Context, stopCh <-chan struct{}, planResources *models.Spec, changes *opsmodels.Changes) error {
+	// Build destroy operation, and runtimes of resources in the state are initialized by it
 	do := &operation.DestroyOperation{
 		Operation: opsmodels.Operation{
-			Runtime:      kubernetesRuntime,
-			StateStorage: &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)},
-			MsgCh:        make(chan opsmodels.Message),
+			StateStorage:       &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)},
+			MsgCh:              make(chan opsmodels.Message),
+			StopCh:             stopCh,
+			ResourceTimeout:    o.ResourceTimeout,
+			Parallelism:        o.Parallelism,
+			RuntimeParallelism: util.ToRuntimeParallelism(o.RuntimeParallelism),
 		},
 	}
 
 	// line summary
-	var deleted int
+	var deleted, retained int
 
 	// progress bar, print dag walk detail
 	progressbar, err := pterm.DefaultProgressbar.WithTotal(len(changes.StepKeys)).Start()
 	if err != nil {
 		return err
 	}
+	// results of resources, which are reported if the operation is interrupted
+	results := map[string]opsmodels.OpResult{}
 	// wait msgCh close
 	var wg sync.WaitGroup
 	// receive msg and print detail
+	wg.Add(1)
 	go func() {
 		defer func() {
 			if p := recover(); p != nil {
 				log.Errorf("failed to receive msg and print detail as %v", p)
 			}
 		}()
-		wg.Add(1)
 
 		for {
 			select {
@@ -199,6 +252,9 @@ func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.
 					return
 				}
 				changeStep := changes.Get(msg.ResourceID)
+				if msg.OpResult != "" {
+					results[msg.ResourceID] = msg.OpResult
+				}
 
 				switch msg.OpResult {
 				case opsmodels.Success, opsmodels.Skip:
@@ -219,7 +275,11 @@ func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.
 					pterm.Success.Println(title)
 					progressbar.UpdateTitle(title)
 					progressbar.Increment()
-					deleted++
+					if changeStep.Action == types.Retain {
+						retained++
+					} else {
+						deleted++
+					}
 				case opsmodels.Failed:
 					title := fmt.Sprintf("%s %s %s",
 						changeStep.Action.String(),
@@ -239,16 +299,27 @@ func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.
 		}
 	}()
 
-	st := do.Destroy(&operation.DestroyRequest{
+	st := do.Destroy(ctx, &operation.DestroyRequest{
 		Request: opsmodels.Request{
 			Tenant:   changes.Project().Tenant,
 			Project:  changes.Project().Name,
 			Operator: o.Operator,
 			Stack:    changes.Stack().Name,
 			Spec:     planResources,
+			Targets:  o.Targets,
+			Excludes: o.Excludes,
+
+			DisableKindOrder: o.NoKindOrder,
 		},
 	})
 	if status.IsErr(st) {
+		if st.Code() == status.Canceled {
+			// wait for msgCh closed, and then report which resources are deleted
+			wg.Wait()
+			_, _ = progressbar.Stop()
+			pterm.Println()
+			changes.InterruptedSummary("Destroy", results)
+		}
 		return fmt.Errorf("destroy failed, status: %v", st)
 	}
 
@@ -256,7 +327,7 @@ func (o *DestroyOptions) destroy(planResources *models.Spec, changes *opsmodels.
 	wg.Wait()
 	// Print summary
 	pterm.Println()
-	pterm.Printf("Destroy complete! Resources: %d deleted.\n", deleted)
+	pterm.Printf("Destroy complete! Resources: %d deleted, %d retained.\n", deleted, retained)
 	return nil
 }
 
@@ -33,7 +33,6 @@ func TestDestroyOptions_Run(t *testing.T) {
 	t.Run("Detail is true", func(t *testing.T) {
 		defer monkey.UnpatchAll()
 		mockDetectProjectAndStack()
-		mockCompileWithSpinner()
 		mockNewKubernetesRuntime()
 		mockOperationPreview()
 
@@ -46,7 +45,6 @@ func TestDestroyOptions_Run(t *testing.T) {
 	t.Run("prompt no", func(t *testing.T) {
 		defer monkey.UnpatchAll()
 		mockDetectProjectAndStack()
-		mockCompileWithSpinner()
 		mockNewKubernetesRuntime()
 		mockOperationPreview()
 
@@ -59,16 +57,44 @@ func TestDestroyOptions_Run(t *testing.T) {
 	t.Run("prompt yes", func(t *testing.T) {
 		defer monkey.UnpatchAll()
 		mockDetectProjectAndStack()
-		mockCompileWithSpinner()
 		mockNewKubernetesRuntime()
 		mockOperationPreview()
-		mockOperationDestroy(opsmodels.Success)
+		mockOperationDestroy(opsmodels.Success, sa1)
 
 		o := NewDestroyOptions()
 		mockPromptOutput("yes")
 		err := o.Run()
 		assert.Nil(t, err)
 	})
+
+	t.Run("compile spec to select resources", func(t *testing.T) {
+		defer monkey.UnpatchAll()
+		mockDetectProjectAndStack()
+		mockCompileWithSpinner()
+		mockNewKubernetesRuntime()
+		var previewed *models.Spec
+		monkey.Patch((*operation.PreviewOperation).Preview,
+			func(_ *operation.PreviewOperation, _ context.Context, request *operation.PreviewRequest) (*operation.PreviewResponse, status.Status) {
+				previewed = request.Spec
+				return &operation.PreviewResponse{Order: &opsmodels.ChangeOrder{}}, nil
+			},
+		)
+
+		o := NewDestroyOptions()
+		o.Compile = true
+		err := o.Run()
+		assert.Nil(t, err)
+		assert.Equal(t, &models.Spec{Resources: []models.Resource{sa1}}, previewed)
+	})
+}
+
+func TestDestroyOptions_Validate(t *testing.T) {
+	o := NewDestroyOptions()
+	o.Complete([]string{"main.k"})
+	assert.Error(t, o.Validate())
+
+	o.Compile = true
+	assert.NoError(t, o.Validate())
 }
 
 var (
@@ -114,7 +140,7 @@ func Test_preview(t *testing.T) {
 		mockOperationPreview()
 
 		o := NewDestroyOptions()
-		_, err := o.preview(&models.Spec{Resources: []models.Resource{sa1}}, project, stack)
+		_, err := o.preview(context.Background(), nil, project, stack)
 		assert.Nil(t, err)
 	})
 }
@@ -159,7 +185,7 @@ func (f *fakerRuntime) Watch(ctx context.Context, request *runtime.WatchRequest)
 
 func mockOperationPreview() {
 	monkey.Patch((*operation.PreviewOperation).Preview,
-		func(*operation.PreviewOperation, *operation.PreviewRequest) (rsp *operation.PreviewResponse, s status.Status) {
+		func(*operation.PreviewOperation, context.Context, *operation.PreviewRequest) (rsp *operation.PreviewResponse, s status.Status) {
 			return &operation.PreviewResponse{
 				Order: &opsmodels.ChangeOrder{
 					StepKeys: []string{sa1.ID},
@@ -207,10 +233,9 @@ func Test_destroy(t *testing.T) {
 	t.Run("destroy success", func(t *testing.T) {
 		defer monkey.UnpatchAll()
 		mockNewKubernetesRuntime()
-		mockOperationDestroy(opsmodels.Success)
+		mockOperationDestroy(opsmodels.Success, sa1, sa2)
 
 		o := NewDestroyOptions()
-		planResources := &models.Spec{Resources: []models.Resource{sa2}}
 		order := &opsmodels.ChangeOrder{
 			StepKeys: []string{sa1.ID, sa2.ID},
 			ChangeSteps: map[string]*opsmodels.ChangeStep{
@@ -230,16 +255,15 @@ func Test_destroy(t *testing.T) {
 		}
 		changes := opsmodels.NewChanges(project, stack, order)
 
-		err := o.destroy(planResources, changes)
+		err := o.destroy(context.Background(), nil, nil, changes)
 		assert.Nil(t, err)
 	})
 	t.Run("destroy failed", func(t *testing.T) {
 		defer monkey.UnpatchAll()
 		mockNewKubernetesRuntime()
-		mockOperationDestroy(opsmodels.Failed)
+		mockOperationDestroy(opsmodels.Failed, sa1)
 
 		o := NewDestroyOptions()
-		planResources := &models.Spec{Resources: []models.Resource{sa1}}
 		order := &opsmodels.ChangeOrder{
 			StepKeys: []string{sa1.ID},
 			ChangeSteps: map[string]*opsmodels.ChangeStep{
@@ -253,19 +277,20 @@ func Test_destroy(t *testing.T) {
 		}
 		changes := opsmodels.NewChanges(project, stack, order)
 
-		err := o.destroy(planResources, changes)
+		err := o.destroy(context.Background(), nil, nil, changes)
 		assert.NotNil(t, err)
 	})
 }
 
-func mockOperationDestroy(res opsmodels.OpResult) {
+// mockOperationDestroy mocks destroying the resources in the state with the result
+func mockOperationDestroy(res opsmodels.OpResult, resources ...models.Resource) {
 	monkey.Patch((*operation.DestroyOperation).Destroy,
-		func(o *operation.DestroyOperation, request *operation.DestroyRequest) status.Status {
+		func(o *operation.DestroyOperation, ctx context.Context, request *operation.DestroyRequest) status.Status {
 			var err error
 			if res == opsmodels.Failed {
 				err = errors.New("mock error")
 			}
-			for _, r := range request.Spec.Resources {
+			for _, r := range resources {
 				// ing -> $res
 				o.MsgCh <- opsmodels.Message{
 					ResourceID: r.ResourceKey(),
@@ -0,0 +1,68 @@
+package drift
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	driftShort = `Detect drift between the actual infra and the configuration stack`
+
+	driftLong = `
+		Detect whether resources of current stack have drifted.
+
+		The stack is compiled, the latest state is loaded and every resource is read from the actual infra.
+		For each resource, drift is reported against the plan compiled from the configuration and against
+		the last-applied state. Nothing will be changed in the actual infra or the state.
+
+		Exit codes:
+		  0  no drift
+		  1  error
+		  2  drift detected`
+
+	driftExample = `
+		# Detect drift of current stack
+		kusion drift
+
+		# Detect drift and output the result in JSON
+		kusion drift -o json`
+)
+
+func NewCmdDrift() *cobra.Command {
+	o := NewDriftOptions()
+
+	cmd := &cobra.Command{
+		Use:          "drift",
+		Short:        i18n.T(driftShort),
+		Long:         templates.LongDesc(i18n.T(driftLong)),
+		Example:      templates.Examples(i18n.T(driftExample)),
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.CompileOptions.WorkDir, "workdir", "w", "",
+		i18n.T("Specify the work directory"))
+	cmd.Flags().StringSliceVarP(&o.CompileOptions.Arguments, "argument", "D", []string{},
+		i18n.T("Specify the arguments for compile KCL"))
+	cmd.Flags().StringSliceVarP(&o.CompileOptions.Settings, "setting", "Y", []string{},
+		i18n.T("Specify the command line setting files"))
+	cmd.Flags().StringSliceVarP(&o.CompileOptions.Overrides, "overrides", "O", []string{},
+		i18n.T("Specify the configuration override path and value"))
+	cmd.Flags().StringVarP(&o.Output, "output", "o", OutputTable,
+		i18n.T("Specify the output format. One of table and json"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0,
+		i18n.T("The max duration of the whole command, reading resources is canceled after it. Zero means no timeout"))
+	cmd.Flags().BoolVarP(&o.NoStyle, "no-style", "", false,
+		i18n.T("no-style sets to RawOutput mode and disables all of styling"))
+
+	return cmd
+}
@@ -0,0 +1,56 @@
+package drift
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/operation"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+func TestDriftCommandRun(t *testing.T) {
+	t.Run("no project", func(t *testing.T) {
+		cmd := NewCmdDrift()
+		cmd.SetArgs([]string{"--workdir", t.TempDir()})
+		err := cmd.Execute()
+		assert.NotNil(t, err)
+	})
+}
+
+func TestDriftOptions_Validate(t *testing.T) {
+	o := NewDriftOptions()
+	o.Output = "yaml"
+	assert.NotNil(t, o.Validate())
+}
+
+func TestDriftOptions_output(t *testing.T) {
+	stack := &projectstack.Stack{StackConfiguration: projectstack.StackConfiguration{Name: "dev"}}
+	rsp := &operation.DriftResponse{Resources: []*operation.ResourceDrift{
+		{ID: "in-sync", Live: true},
+		{ID: "drifted", Live: true, StateDrifted: true},
+	}}
+
+	t.Run("json", func(t *testing.T) {
+		o := NewDriftOptions()
+		o.Output = OutputJSON
+		out := &bytes.Buffer{}
+		assert.Nil(t, o.output(out, stack, rsp))
+
+		got := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal(out.Bytes(), &got))
+		assert.Equal(t, "dev", got["stack"])
+		assert.Equal(t, true, got["drifted"])
+		assert.Len(t, got["resources"], 2)
+	})
+
+	t.Run("table", func(t *testing.T) {
+		o := NewDriftOptions()
+		out := &bytes.Buffer{}
+		assert.Nil(t, o.output(out, stack, rsp))
+		assert.Contains(t, out.String(), "drifted")
+		assert.Contains(t, out.String(), "Drift detected! Resources: 1 of 2 drifted.")
+	})
+}
@@ -0,0 +1,203 @@
+package drift
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"kusionstack.io/kusion/pkg/compile"
+	"kusionstack.io/kusion/pkg/engine"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	compilecmd "kusionstack.io/kusion/pkg/kusionctl/cmd/compile"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util/signals"
+)
+
+// Supported output formats
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// Exit codes of the drift command. Errors exit with 1 like other commands
+const (
+	ExitNoDrift = 0
+	ExitDrift   = 2
+)
+
+// DriftOptions defines flags for the `drift` command
+type DriftOptions struct {
+	compilecmd.CompileOptions
+	Output  string
+	NoStyle bool
+
+	// Timeout is the max duration of the whole command. Zero means no timeout
+	Timeout time.Duration
+}
+
+// NewDriftOptions returns a new DriftOptions instance
+func NewDriftOptions() *DriftOptions {
+	return &DriftOptions{
+		CompileOptions: compilecmd.CompileOptions{
+			Filenames: []string{},
+			Arguments: []string{},
+			Settings:  []string{},
+			Overrides: []string{},
+		},
+		Output: OutputTable,
+	}
+}
+
+func (o *DriftOptions) Complete(args []string) {
+	o.CompileOptions.Complete(args)
+}
+
+func (o *DriftOptions) Validate() error {
+	if o.Output != OutputTable && o.Output != OutputJSON {
+		return fmt.Errorf("invalid output format %q, only %s and %s are supported", o.Output, OutputTable, OutputJSON)
+	}
+	return o.CompileOptions.Validate()
+}
+
+func (o *DriftOptions) Run() error {
+	ctx, cancel := util.NewTimeoutContext(o.Timeout)
+	defer cancel()
+	// nothing is changed by drift detection, so reading resources is simply canceled on interrupts
+	stop := signals.HandleInterrupt(cancel)
+	defer stop()
+
+	// Set no style
+	if o.NoStyle || o.Output == OutputJSON {
+		pterm.DisableStyling()
+		pterm.EnableColor()
+	}
+
+	// Parse project and stack of work directory
+	project, stack, err := projectstack.DetectProjectAndStack(o.CompileOptions.WorkDir)
+	if err != nil {
+		return err
+	}
+
+	planResources, err := o.compile(stack)
+	if err != nil {
+		return err
+	}
+
+	do := &operation.DriftOperation{
+		Operation: opsmodels.Operation{
+			StateStorage: &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)},
+		},
+	}
+	rsp, s := do.Drift(ctx, &operation.DriftRequest{
+		Request: opsmodels.Request{
+			Tenant:  project.Tenant,
+			Project: project.Name,
+			Stack:   stack.Name,
+			Spec:    planResources,
+		},
+	})
+	if status.IsErr(s) {
+		return fmt.Errorf("drift detection failed, status: %v", s)
+	}
+
+	if err = o.output(os.Stdout, stack, rsp); err != nil {
+		return err
+	}
+	if rsp.Drifted() {
+		return &util.ExitError{Code: ExitDrift}
+	}
+	return nil
+}
+
+// compile the stack. The spinner is disabled when outputting JSON, so that the output can be parsed by machines
+func (o *DriftOptions) compile(stack *projectstack.Stack) (*models.Spec, error) {
+	if o.Output == OutputJSON {
+		r, err := compile.Compile(o.WorkDir, o.Filenames, o.Settings, o.Arguments, o.Overrides, true, false)
+		if err != nil {
+			return nil, err
+		}
+		return engine.ConvertKCLResult2Resources(r.Documents)
+	}
+
+	planResources, sp, err := compile.CompileWithSpinner(o.WorkDir, o.Filenames, o.Settings, o.Arguments, o.Overrides, stack)
+	if err != nil {
+		sp.Fail()
+		return nil, err
+	}
+	sp.Success() // Resolve spinner with success message.
+	pterm.Println()
+	return planResources, nil
+}
+
+// driftResult is the JSON output of the drift command
+type driftResult struct {
+	Stack   string `json:"stack"`
+	Drifted bool   `json:"drifted"`
+	*operation.DriftResponse
+}
+
+func (o *DriftOptions) output(out io.Writer, stack *projectstack.Stack, rsp *operation.DriftResponse) error {
+	if o.Output == OutputJSON {
+		data, err := json.MarshalIndent(&driftResult{Stack: stack.Name, Drifted: rsp.Drifted(), DriftResponse: rsp}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+
+	tableData := pterm.TableData{{fmt.Sprintf("Stack: %s", stack.Name), "ID", "Live", "Plan", "State"}}
+	drifted := 0
+	for i, d := range rsp.Resources {
+		itemPrefix := " * ├─"
+		if i == len(rsp.Resources)-1 {
+			itemPrefix = " * └─"
+		}
+		if d.Drifted() {
+			drifted++
+		}
+		tableData = append(tableData, []string{itemPrefix, d.ID, existence(d.Live), driftString(d.PlanDrifted), driftString(d.StateDrifted)})
+	}
+	table, err := pterm.DefaultTable.WithHasHeader().
+		WithHeaderStyle(&pterm.ThemeDefault.TableHeaderStyle).
+		WithLeftAlignment(true).
+		WithSeparator("  ").
+		WithData(tableData).
+		Srender()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, table)
+	fmt.Fprintln(out)
+
+	if drifted == 0 {
+		_, err = fmt.Fprintln(out, "No drift detected")
+	} else {
+		_, err = fmt.Fprintf(out, "Drift detected! Resources: %d of %d drifted.\n", drifted, len(rsp.Resources))
+	}
+	return err
+}
+
+func existence(live bool) string {
+	if live {
+		return "Exist"
+	}
+	return "Missing"
+}
+
+func driftString(drifted bool) string {
+	if drifted {
+		return "Drifted"
+	}
+	return "InSync"
+}
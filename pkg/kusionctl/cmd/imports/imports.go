@@ -0,0 +1,64 @@
+package imports
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	importShort = `Import existing resources in the actual infra into the state of current stack`
+
+	importLong = `
+		Import existing resources into the state of current stack, so that they can be managed by Kusion.
+
+		Resources are specified by their IDs in the compiled spec, and all resources in the spec that exist in the
+		actual infra will be imported if no ID is given. Resources already in the state will not be imported again.
+
+		Import only reads resources from the actual infra and never changes them. After importing, the differences
+		between imported resources and the spec are shown, and they will be reconciled by the next apply.`
+
+	importExample = `
+		# Import all existing resources of current stack
+		kusion import
+
+		# Import specified resources by their IDs
+		kusion import v1:Namespace:default apps/v1:Deployment:default:nginx`
+)
+
+func NewCmdImport() *cobra.Command {
+	o := NewImportOptions()
+
+	cmd := &cobra.Command{
+		Use:     "import [ID...]",
+		Short:   i18n.T(importShort),
+		Long:    templates.LongDesc(i18n.T(importLong)),
+		Example: templates.Examples(i18n.T(importExample)),
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.CompileOptions.WorkDir, "workdir", "w", "",
+		i18n.T("Specify the work directory"))
+	cmd.Flags().StringVarP(&o.Operator, "operator", "", "",
+		i18n.T("Specify the operator"))
+	cmd.Flags().StringSliceVarP(&o.CompileOptions.Arguments, "argument", "D", []string{},
+		i18n.T("Specify the arguments for compile KCL"))
+	cmd.Flags().StringSliceVarP(&o.CompileOptions.Settings, "setting", "Y", []string{},
+		i18n.T("Specify the command line setting files"))
+	cmd.Flags().StringSliceVarP(&o.CompileOptions.Overrides, "overrides", "O", []string{},
+		i18n.T("Specify the configuration override path and value"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0,
+		i18n.T("The max duration of the whole command, reading resources is canceled after it. Zero means no timeout"))
+	cmd.Flags().BoolVarP(&o.NoStyle, "no-style", "", false,
+		i18n.T("no-style sets to RawOutput mode and disables all of styling"))
+
+	return cmd
+}
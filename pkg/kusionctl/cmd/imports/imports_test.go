@@ -0,0 +1,15 @@
+package imports
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportCommandRun(t *testing.T) {
+	t.Run("validate error", func(t *testing.T) {
+		cmd := NewCmdImport()
+		err := cmd.Execute()
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,152 @@
+package imports
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"kusionstack.io/kusion/pkg/compile"
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+	"kusionstack.io/kusion/pkg/engine/runtime"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	compilecmd "kusionstack.io/kusion/pkg/kusionctl/cmd/compile"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
+	"kusionstack.io/kusion/pkg/log"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util/signals"
+)
+
+// ImportOptions defines flags for the `import` command
+type ImportOptions struct {
+	compilecmd.CompileOptions
+	IDs      []string
+	Operator string
+	NoStyle  bool
+
+	// Timeout is the max duration of the whole command. Zero means no timeout
+	Timeout time.Duration
+}
+
+// NewImportOptions returns a new ImportOptions instance
+func NewImportOptions() *ImportOptions {
+	return &ImportOptions{
+		CompileOptions: compilecmd.CompileOptions{
+			Filenames: []string{},
+			Arguments: []string{},
+			Settings:  []string{},
+			Overrides: []string{},
+		},
+	}
+}
+
+func (o *ImportOptions) Complete(args []string) {
+	// args are IDs of resources instead of KCL files
+	o.IDs = args
+	o.CompileOptions.Complete([]string{})
+}
+
+func (o *ImportOptions) Validate() error {
+	return o.CompileOptions.Validate()
+}
+
+func (o *ImportOptions) Run() error {
+	ctx, cancel := util.NewTimeoutContext(o.Timeout)
+	defer cancel()
+	// the state is saved after all resources are read, so reading is canceled on interrupts without changing it
+	stop := signals.HandleInterrupt(cancel)
+	defer stop()
+
+	// Set no style
+	if o.NoStyle {
+		pterm.DisableStyling()
+		pterm.EnableColor()
+	}
+
+	// Parse project and stack of work directory
+	project, stack, err := projectstack.DetectProjectAndStack(o.CompileOptions.WorkDir)
+	if err != nil {
+		return err
+	}
+
+	// Get compile result
+	planResources, sp, err := compile.CompileWithSpinner(o.CompileOptions.WorkDir, o.CompileOptions.Filenames, o.CompileOptions.Settings, o.CompileOptions.Arguments, o.Overrides, stack)
+	if err != nil {
+		sp.Fail()
+		return err
+	}
+	sp.Success() // Resolve spinner with success message.
+	pterm.Println()
+
+	if planResources == nil || len(planResources.Resources) == 0 {
+		pterm.Println("No resources to import")
+		return nil
+	}
+
+	runtimes, err := runtime.InitRuntimes(nil, planResources.Resources)
+	if err != nil {
+		return err
+	}
+	iop := &operation.ImportOperation{
+		Operation: opsmodels.Operation{
+			RuntimeMap:   runtimes,
+			StateStorage: &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)},
+		},
+	}
+	rsp, s := iop.Import(ctx, &operation.ImportRequest{
+		Request: opsmodels.Request{
+			Tenant:   project.Tenant,
+			Project:  project.Name,
+			Operator: o.Operator,
+			Stack:    stack.Name,
+			Spec:     planResources,
+		},
+		IDs: o.IDs,
+	})
+	if status.IsErr(s) {
+		return fmt.Errorf("import failed, status: %v", s)
+	}
+
+	for _, id := range rsp.Skipped {
+		pterm.Info.Printf("Skip %s, it is already managed by Kusion or doesn't exist\n", pterm.Bold.Sprint(id))
+	}
+	if len(rsp.Resources) == 0 {
+		fmt.Println("No resources imported")
+		return nil
+	}
+
+	// Show differences between imported resources and the spec, which will be reconciled by the next apply
+	changes := opsmodels.NewChanges(project, stack, importChangeOrder(rsp.Resources, planResources))
+	changes.Summary()
+	changes.OutputDiff("all")
+
+	pterm.Printf("Import complete! Resources: %d imported.\n", len(rsp.Resources))
+	return nil
+}
+
+// importChangeOrder compares imported resources with the spec. Imported resources are both the prior and live resources
+func importChangeOrder(imported models.Resources, planResources *models.Spec) *opsmodels.ChangeOrder {
+	order := &opsmodels.ChangeOrder{StepKeys: []string{}, ChangeSteps: map[string]*opsmodels.ChangeStep{}}
+	planIndex := planResources.Resources.Index()
+	for i := range imported {
+		r := &imported[i]
+		plan := planIndex[r.ResourceKey()]
+
+		action := types.Update
+		equal, err := runtime.GetComparator(plan.RuntimeType()).Equal(r, plan, r)
+		if err != nil {
+			log.Warnf("compare imported resource:%s failed. %v", r.ResourceKey(), err)
+		} else if equal {
+			action = types.UnChange
+		}
+
+		order.StepKeys = append(order.StepKeys, r.ResourceKey())
+		order.ChangeSteps[r.ResourceKey()] = opsmodels.NewChangeStep(r.ResourceKey(), action, r, plan, r)
+	}
+	return order
+}
@@ -0,0 +1,42 @@
+package imports
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+)
+
+func TestImportOptions_Complete(t *testing.T) {
+	o := NewImportOptions()
+	o.Complete([]string{"v1:Namespace:default"})
+	assert.Equal(t, []string{"v1:Namespace:default"}, o.IDs)
+	assert.Empty(t, o.Filenames)
+}
+
+func Test_importChangeOrder(t *testing.T) {
+	newResource := func(id, image string) models.Resource {
+		return models.Resource{
+			ID:   id,
+			Type: "Fake",
+			Attributes: map[string]interface{}{
+				"image": image,
+			},
+		}
+	}
+	planResources := &models.Spec{Resources: models.Resources{
+		newResource("unchanged", "nginx:1.20"),
+		newResource("updated", "nginx:1.21"),
+	}}
+	imported := models.Resources{
+		newResource("unchanged", "nginx:1.20"),
+		newResource("updated", "nginx:1.20"),
+	}
+
+	order := importChangeOrder(imported, planResources)
+	assert.Equal(t, []string{"unchanged", "updated"}, order.StepKeys)
+	assert.Equal(t, types.UnChange, order.Get("unchanged").Action)
+	assert.Equal(t, types.Update, order.Get("updated").Action)
+}
@@ -0,0 +1,115 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// OutputOptions defines flags for the `output` command
+type OutputOptions struct {
+	WorkDir string
+	Name    string
+	JSON    bool
+}
+
+// NewOutputOptions returns a new OutputOptions instance
+func NewOutputOptions() *OutputOptions {
+	return &OutputOptions{}
+}
+
+func (o *OutputOptions) Complete(args []string) {
+	if len(args) > 0 {
+		o.Name = args[0]
+	}
+}
+
+func (o *OutputOptions) Validate() error {
+	if o.WorkDir == "" {
+		return nil
+	}
+	if _, err := os.Stat(o.WorkDir); err != nil {
+		return fmt.Errorf("invalid work directory %s: %w", o.WorkDir, err)
+	}
+	return nil
+}
+
+func (o *OutputOptions) Run() error {
+	// Parse project and stack of work directory
+	project, stack, err := projectstack.DetectProjectAndStack(o.WorkDir)
+	if err != nil {
+		return err
+	}
+
+	storage := &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)}
+	state, err := storage.GetLatestState(&states.StateQuery{
+		Tenant:  project.Tenant,
+		Project: project.Name,
+		Stack:   stack.Name,
+	})
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return errors.New("no state of current stack. Please apply it first")
+	}
+	return printOutputs(os.Stdout, state.Outputs, o.Name, o.JSON)
+}
+
+// printOutputs prints all outputs as `name = value` lines, or the value of the output of the name
+func printOutputs(out io.Writer, outputs map[string]interface{}, name string, asJSON bool) error {
+	if name != "" {
+		value, ok := outputs[name]
+		if !ok {
+			return fmt.Errorf("output:%s is not found. Please check the outputs of the spec and apply it", name)
+		}
+		if s, ok := value.(string); ok && !asJSON {
+			_, err := fmt.Fprintln(out, s)
+			return err
+		}
+		return printJSON(out, value)
+	}
+
+	if asJSON {
+		if outputs == nil {
+			outputs = map[string]interface{}{}
+		}
+		return printJSON(out, outputs)
+	}
+	if len(outputs) == 0 {
+		_, err := fmt.Fprintln(out, "No outputs in the state")
+		return err
+	}
+	names := make([]string, 0, len(outputs))
+	for n := range outputs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		data, err := json.Marshal(outputs[n])
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(out, "%s = %s\n", n, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printJSON(out io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
@@ -0,0 +1,58 @@
+package output
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	outputShort = `Show outputs of current stack`
+
+	outputLong = `
+		Show outputs of current stack saved in the state by the last apply.
+
+		Outputs are declared in the outputs section of the spec, and may refer to attributes of applied
+		resources by implicit references like $kusion_path.resourceKey.attribute. They are evaluated after
+		apply, so this command doesn't compile KCL files or read the actual infra.
+
+		If a name is given, only the value of this output is shown, and strings are printed without quotes,
+		which is handy in scripts.`
+
+	outputExample = `
+		# Show all outputs of current stack
+		kusion output
+
+		# Show the value of an output
+		kusion output service_ip
+
+		# Show all outputs as JSON
+		kusion output --json`
+)
+
+func NewCmdOutput() *cobra.Command {
+	o := NewOutputOptions()
+
+	cmd := &cobra.Command{
+		Use:     "output [name]",
+		Short:   i18n.T(outputShort),
+		Long:    templates.LongDesc(i18n.T(outputLong)),
+		Example: templates.Examples(i18n.T(outputExample)),
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.WorkDir, "workdir", "w", "",
+		i18n.T("Specify the work directory"))
+	cmd.Flags().BoolVarP(&o.JSON, "json", "", false,
+		i18n.T("Show outputs as JSON"))
+
+	return cmd
+}
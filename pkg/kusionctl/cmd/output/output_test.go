@@ -0,0 +1,98 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputCommandRun(t *testing.T) {
+	t.Run("no project", func(t *testing.T) {
+		cmd := NewCmdOutput()
+		cmd.SetArgs([]string{"--workdir", t.TempDir()})
+		err := cmd.Execute()
+		assert.NotNil(t, err)
+	})
+}
+
+func TestOutputOptions_Validate(t *testing.T) {
+	o := NewOutputOptions()
+	assert.Nil(t, o.Validate())
+
+	o.WorkDir = "not-exist-dir"
+	assert.NotNil(t, o.Validate())
+}
+
+func Test_printOutputs(t *testing.T) {
+	outputs := map[string]interface{}{
+		"service_ip": "10.0.0.1",
+		"ports":      []interface{}{80, 443},
+	}
+	tests := []struct {
+		name    string
+		outputs map[string]interface{}
+		output  string
+		asJSON  bool
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "all",
+			outputs: outputs,
+			want:    "ports = [80,443]\nservice_ip = \"10.0.0.1\"\n",
+		},
+		{
+			name:    "all as json",
+			outputs: outputs,
+			asJSON:  true,
+			want:    "{\n  \"ports\": [\n    80,\n    443\n  ],\n  \"service_ip\": \"10.0.0.1\"\n}\n",
+		},
+		{
+			name: "no outputs",
+			want: "No outputs in the state\n",
+		},
+		{
+			name:   "no outputs as json",
+			asJSON: true,
+			want:   "{}\n",
+		},
+		{
+			name:    "string output",
+			outputs: outputs,
+			output:  "service_ip",
+			want:    "10.0.0.1\n",
+		},
+		{
+			name:    "string output as json",
+			outputs: outputs,
+			output:  "service_ip",
+			asJSON:  true,
+			want:    "\"10.0.0.1\"\n",
+		},
+		{
+			name:    "list output",
+			outputs: outputs,
+			output:  "ports",
+			want:    "[\n  80,\n  443\n]\n",
+		},
+		{
+			name:    "output not found",
+			outputs: outputs,
+			output:  "cluster_ip",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := printOutputs(out, tt.outputs, tt.output, tt.asJSON)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
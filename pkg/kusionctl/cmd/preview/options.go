@@ -1,8 +1,11 @@
 package preview
 
 import (
+	"time"
+
 	applycmd "kusionstack.io/kusion/pkg/kusionctl/cmd/apply"
 	compilecmd "kusionstack.io/kusion/pkg/kusionctl/cmd/compile"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
 )
 
 type PreviewOptions struct {
@@ -10,6 +13,26 @@ type PreviewOptions struct {
 	Yes     bool
 	Detail  bool
 	NoStyle bool
+
+	// Timeout is the max duration of the whole command, and ResourceTimeout is the max duration to read each resource.
+	// Zero means no timeout
+	Timeout         time.Duration
+	ResourceTimeout time.Duration
+
+	// Parallelism is the max number of resources executed concurrently, and RuntimeParallelism is that of every
+	// runtime keyed by resource types. Zero means no limit
+	Parallelism        int
+	RuntimeParallelism map[string]int
+
+	// Targets and Excludes are resource IDs or glob patterns to select resources in the stack
+	Targets  []string
+	Excludes []string
+
+	// Out is the file to save the preview as a plan
+	Out string
+
+	// NoKindOrder turns off the default order of Kubernetes resources by their kinds
+	NoKindOrder bool
 }
 
 func NewPreviewOptions() *PreviewOptions {
@@ -28,16 +51,27 @@ func (o *PreviewOptions) Complete(args []string) {
 }
 
 func (o *PreviewOptions) Validate() error {
+	if err := util.ValidateParallelism(o.Parallelism, o.RuntimeParallelism); err != nil {
+		return err
+	}
 	return o.CompileOptions.Validate()
 }
 
 func (o *PreviewOptions) Run() error {
 	applyOptions := applycmd.ApplyOptions{
-		CompileOptions: o.CompileOptions,
-		Yes:            o.Yes,
-		Detail:         o.Detail,
-		NoStyle:        o.NoStyle,
-		OnlyPreview:    true,
+		CompileOptions:     o.CompileOptions,
+		Yes:                o.Yes,
+		Detail:             o.Detail,
+		NoStyle:            o.NoStyle,
+		OnlyPreview:        true,
+		Timeout:            o.Timeout,
+		ResourceTimeout:    o.ResourceTimeout,
+		Parallelism:        o.Parallelism,
+		RuntimeParallelism: o.RuntimeParallelism,
+		Targets:            o.Targets,
+		Excludes:           o.Excludes,
+		PlanOut:            o.Out,
+		NoKindOrder:        o.NoKindOrder,
 	}
 
 	return applyOptions.Run()
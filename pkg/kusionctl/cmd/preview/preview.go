@@ -25,7 +25,11 @@ var (
 		kusion preview -D name=test -D age=18
 
 		# Preview with specifying setting file
-		kusion preview -Y settings.yaml`
+		kusion preview -Y settings.yaml
+
+		# Save the preview as a plan, and apply exactly that plan later
+		kusion preview --out plan.json
+		kusion apply plan.json`
 )
 
 func NewCmdPreview() *cobra.Command {
@@ -59,6 +63,22 @@ func NewCmdPreview() *cobra.Command {
 		i18n.T("Automatically show plan details after previewing it"))
 	cmd.Flags().BoolVarP(&o.NoStyle, "no-style", "", false,
 		i18n.T("no-style sets to RawOutput mode and disables all of styling"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0,
+		i18n.T("The max duration of the whole command. Zero means no timeout"))
+	cmd.Flags().DurationVarP(&o.ResourceTimeout, "resource-timeout", "", 0,
+		i18n.T("The max duration to read each resource. Zero means no timeout"))
+	cmd.Flags().IntVarP(&o.Parallelism, "parallelism", "", 0,
+		i18n.T("The max number of resources previewed concurrently. Zero means no limit"))
+	cmd.Flags().StringToIntVarP(&o.RuntimeParallelism, "runtime-parallelism", "", map[string]int{},
+		i18n.T("The max number of resources previewed concurrently per runtime, e.g. Kubernetes=10. Zero means no limit"))
+	cmd.Flags().StringSliceVarP(&o.Targets, "target", "", []string{},
+		i18n.T("Only preview resources matching the IDs or glob patterns, together with resources they depend on"))
+	cmd.Flags().StringSliceVarP(&o.Excludes, "exclude", "", []string{},
+		i18n.T("Do not preview resources matching the IDs or glob patterns"))
+	cmd.Flags().StringVarP(&o.Out, "out", "", "",
+		i18n.T("Save the preview as a plan file, which is applied by `kusion apply <plan file>` exactly as previewed"))
+	cmd.Flags().BoolVarP(&o.NoKindOrder, "no-kind-order", "", false,
+		i18n.T("Do not order Kubernetes resources by their kinds, e.g. Namespaces and CRDs before other resources, but only by dependencies"))
 
 	return cmd
 }
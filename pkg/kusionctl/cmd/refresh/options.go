@@ -0,0 +1,106 @@
+package refresh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"kusionstack.io/kusion/pkg/engine/operation"
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/operation/types"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
+	"kusionstack.io/kusion/pkg/projectstack"
+	"kusionstack.io/kusion/pkg/status"
+	"kusionstack.io/kusion/pkg/util/signals"
+)
+
+// RefreshOptions defines flags for the `refresh` command
+type RefreshOptions struct {
+	WorkDir  string
+	Operator string
+	Detail   bool
+	NoStyle  bool
+
+	// Timeout is the max duration of the whole command. Zero means no timeout
+	Timeout time.Duration
+}
+
+// NewRefreshOptions returns a new RefreshOptions instance
+func NewRefreshOptions() *RefreshOptions {
+	return &RefreshOptions{}
+}
+
+func (o *RefreshOptions) Complete(args []string) {}
+
+func (o *RefreshOptions) Validate() error {
+	if o.WorkDir == "" {
+		return nil
+	}
+	if _, err := os.Stat(o.WorkDir); err != nil {
+		return fmt.Errorf("invalid work directory %s: %w", o.WorkDir, err)
+	}
+	return nil
+}
+
+func (o *RefreshOptions) Run() error {
+	ctx, cancel := util.NewTimeoutContext(o.Timeout)
+	defer cancel()
+	// the state is saved after all resources are read, so reading is canceled on interrupts without changing it
+	stop := signals.HandleInterrupt(cancel)
+	defer stop()
+
+	// Set no style
+	if o.NoStyle {
+		pterm.DisableStyling()
+		pterm.EnableColor()
+	}
+
+	// Parse project and stack of work directory
+	project, stack, err := projectstack.DetectProjectAndStack(o.WorkDir)
+	if err != nil {
+		return err
+	}
+
+	ro := &operation.RefreshOperation{
+		Operation: opsmodels.Operation{
+			OperationType: types.Refresh,
+			StateStorage:  &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)},
+		},
+	}
+	rsp, s := ro.Refresh(ctx, &operation.RefreshRequest{
+		Request: opsmodels.Request{
+			Tenant:   project.Tenant,
+			Project:  project.Name,
+			Operator: o.Operator,
+			Stack:    stack.Name,
+		},
+	})
+	if status.IsErr(s) {
+		return fmt.Errorf("refresh failed, status: %v", s)
+	}
+
+	if len(rsp.Order.StepKeys) == 0 {
+		fmt.Println("No resources in the state")
+		return nil
+	}
+	changes := opsmodels.NewChanges(project, stack, rsp.Order)
+	changes.Summary()
+
+	updated := len(changes.Values(opsmodels.UpdateChangeStepFilter))
+	deleted := len(changes.Values(opsmodels.DeleteChangeStepFilter))
+	if updated == 0 && deleted == 0 {
+		fmt.Println("All resources are up to date. State is not changed")
+		return nil
+	}
+	if o.Detail {
+		for _, step := range changes.Values(func(c *opsmodels.ChangeStep) bool { return c.Action != types.UnChange }) {
+			changes.OutputDiff(step.ID)
+		}
+	}
+	pterm.Printf("Refresh complete! Resources: %d updated, %d dropped. State serial: %d\n", updated, deleted, rsp.State.Serial)
+	return nil
+}
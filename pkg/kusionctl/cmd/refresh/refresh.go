@@ -0,0 +1,59 @@
+package refresh
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	refreshShort = `Reconcile the state of current stack with the actual infra`
+
+	refreshLong = `
+		Refresh the state of current stack by reading every resource in the state from the actual infra.
+
+		Resources modified out-of-band are updated with their live attributes, and resources that no longer
+		exist are dropped from the state. A new state serial is saved only if any resource is changed.
+
+		Refresh never changes the actual infra and doesn't compile KCL files.`
+
+	refreshExample = `
+		# Refresh the state of current stack
+		kusion refresh
+
+		# Refresh and show details of changed resources
+		kusion refresh --detail`
+)
+
+func NewCmdRefresh() *cobra.Command {
+	o := NewRefreshOptions()
+
+	cmd := &cobra.Command{
+		Use:     "refresh",
+		Short:   i18n.T(refreshShort),
+		Long:    templates.LongDesc(i18n.T(refreshLong)),
+		Example: templates.Examples(i18n.T(refreshExample)),
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.WorkDir, "workdir", "w", "",
+		i18n.T("Specify the work directory"))
+	cmd.Flags().StringVarP(&o.Operator, "operator", "", "",
+		i18n.T("Specify the operator"))
+	cmd.Flags().BoolVarP(&o.Detail, "detail", "d", false,
+		i18n.T("Show details of changed resources after refreshing"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0,
+		i18n.T("The max duration of the whole command, reading resources is canceled after it. Zero means no timeout"))
+	cmd.Flags().BoolVarP(&o.NoStyle, "no-style", "", false,
+		i18n.T("no-style sets to RawOutput mode and disables all of styling"))
+
+	return cmd
+}
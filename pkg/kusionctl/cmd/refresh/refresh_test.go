@@ -0,0 +1,24 @@
+package refresh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshCommandRun(t *testing.T) {
+	t.Run("no project", func(t *testing.T) {
+		cmd := NewCmdRefresh()
+		cmd.SetArgs([]string{"--workdir", t.TempDir()})
+		err := cmd.Execute()
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRefreshOptions_Validate(t *testing.T) {
+	o := NewRefreshOptions()
+	assert.Nil(t, o.Validate())
+
+	o.WorkDir = "not-exist-dir"
+	assert.NotNil(t, o.Validate())
+}
@@ -0,0 +1,111 @@
+package rollback
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	applycmd "kusionstack.io/kusion/pkg/kusionctl/cmd/apply"
+	compilecmd "kusionstack.io/kusion/pkg/kusionctl/cmd/compile"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// RollbackOptions defines flags for the `rollback` command
+type RollbackOptions struct {
+	WorkDir string
+	// Serial is the serial of the state to roll back to
+	Serial uint64
+
+	Operator string
+	Yes      bool
+	Detail   bool
+	NoStyle  bool
+	DryRun   bool
+
+	// WatchTimeout is the max duration to wait for each applied resource to be ready
+	WatchTimeout time.Duration
+
+	// Timeout is the max duration of the whole command, and ResourceTimeout is the max duration of each resource.
+	// Zero means no timeout
+	Timeout         time.Duration
+	ResourceTimeout time.Duration
+
+	// Parallelism is the max number of resources executed concurrently, and RuntimeParallelism is that of every
+	// runtime keyed by resource types. Zero means no limit
+	Parallelism        int
+	RuntimeParallelism map[string]int
+}
+
+// NewRollbackOptions returns a new RollbackOptions instance
+func NewRollbackOptions() *RollbackOptions {
+	return &RollbackOptions{}
+}
+
+func (o *RollbackOptions) Complete(args []string) {}
+
+func (o *RollbackOptions) Validate() error {
+	if o.Serial == 0 {
+		return errors.New("--serial is required")
+	}
+	if err := util.ValidateParallelism(o.Parallelism, o.RuntimeParallelism); err != nil {
+		return err
+	}
+	if o.WorkDir == "" {
+		return nil
+	}
+	if _, err := os.Stat(o.WorkDir); err != nil {
+		return fmt.Errorf("invalid work directory %s: %w", o.WorkDir, err)
+	}
+	return nil
+}
+
+func (o *RollbackOptions) Run() error {
+	// Parse project and stack of work directory
+	project, stack, err := projectstack.DetectProjectAndStack(o.WorkDir)
+	if err != nil {
+		return err
+	}
+
+	storage := &local.FileSystemState{Path: filepath.Join(o.WorkDir, local.KusionState)}
+	state, err := storage.GetStateBySerial(&states.StateQuery{
+		Tenant:  project.Tenant,
+		Project: project.Name,
+		Stack:   stack.Name,
+	}, o.Serial)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("can't find the state of serial %d in the history of stack:%s", o.Serial, stack.Name)
+	}
+	fmt.Printf("Roll back stack %s to the state of serial %d, which was modified at %s\n\n",
+		stack.Name, state.Serial, state.ModifiedTime.Format(time.RFC3339))
+
+	// outputs are evaluated against resources after rolling back, and values recorded in the state may be stale
+	if len(state.Outputs) != 0 && len(state.OutputDefinitions) == 0 {
+		fmt.Printf("Definitions of outputs are not recorded in the state of serial %d, outputs will be dropped\n\n", state.Serial)
+	}
+
+	// preview and apply resources and outputs in the state as the spec, so the actual infra returns to that snapshot
+	applyOptions := applycmd.ApplyOptions{
+		CompileOptions:     compilecmd.CompileOptions{WorkDir: o.WorkDir},
+		Operator:           o.Operator,
+		Yes:                o.Yes,
+		Detail:             o.Detail,
+		NoStyle:            o.NoStyle,
+		DryRun:             o.DryRun,
+		WatchTimeout:       o.WatchTimeout,
+		Timeout:            o.Timeout,
+		ResourceTimeout:    o.ResourceTimeout,
+		Parallelism:        o.Parallelism,
+		RuntimeParallelism: o.RuntimeParallelism,
+		Spec:               &models.Spec{Resources: state.Resources, Outputs: state.OutputDefinitions},
+	}
+	return applyOptions.Run()
+}
@@ -0,0 +1,93 @@
+//go:build !arm64
+// +build !arm64
+
+package rollback
+
+import (
+	"path/filepath"
+	"testing"
+
+	"bou.ke/monkey"
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	applycmd "kusionstack.io/kusion/pkg/kusionctl/cmd/apply"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+var (
+	project = &projectstack.Project{
+		ProjectConfiguration: projectstack.ProjectConfiguration{
+			Name:   "testdata",
+			Tenant: "admin",
+		},
+	}
+	stack = &projectstack.Stack{
+		StackConfiguration: projectstack.StackConfiguration{
+			Name: "dev",
+		},
+	}
+	sa1 = models.Resource{ID: "v1:ServiceAccount:test-ns:sa1", Attributes: map[string]interface{}{"kind": "ServiceAccount"}}
+	sa2 = models.Resource{ID: "v1:ServiceAccount:test-ns:sa2", Attributes: map[string]interface{}{"kind": "ServiceAccount"}}
+)
+
+func TestRollbackOptions_Validate(t *testing.T) {
+	o := NewRollbackOptions()
+	assert.Error(t, o.Validate())
+
+	o.Serial = 1
+	assert.NoError(t, o.Validate())
+
+	o.WorkDir = "not-exist-dir"
+	assert.Error(t, o.Validate())
+}
+
+func TestRollbackOptions_Run(t *testing.T) {
+	workDir := t.TempDir()
+	storage := &local.FileSystemState{Path: filepath.Join(workDir, local.KusionState)}
+	assert.NoError(t, storage.Apply(&states.State{
+		Project:           "testdata",
+		Stack:             "dev",
+		Serial:            1,
+		Resources:         models.Resources{sa1},
+		Outputs:           map[string]interface{}{"name": "sa1"},
+		OutputDefinitions: map[string]interface{}{"name": graph.ImplicitRefPrefix + sa1.ID + ".metadata.name"},
+	}))
+	assert.NoError(t, storage.Apply(&states.State{Project: "testdata", Stack: "dev", Serial: 2,
+		Resources: models.Resources{sa1, sa2}}))
+
+	defer monkey.UnpatchAll()
+	monkey.Patch(projectstack.DetectProjectAndStack, func(stackDir string) (*projectstack.Project, *projectstack.Stack, error) {
+		return project, stack, nil
+	})
+	var applied *applycmd.ApplyOptions
+	monkey.Patch((*applycmd.ApplyOptions).Run, func(o *applycmd.ApplyOptions) error {
+		applied = o
+		return nil
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		o := NewRollbackOptions()
+		o.WorkDir = workDir
+		o.Serial = 1
+		o.Yes = true
+		assert.NoError(t, o.Run())
+		assert.Equal(t, workDir, applied.WorkDir)
+		assert.True(t, applied.Yes)
+		assert.Len(t, applied.Spec.Resources, 1)
+		assert.Equal(t, sa1.ID, applied.Spec.Resources[0].ID)
+		// outputs are evaluated again against the rolled back resources
+		assert.Equal(t, map[string]interface{}{"name": graph.ImplicitRefPrefix + sa1.ID + ".metadata.name"},
+			applied.Spec.Outputs)
+	})
+
+	t.Run("serial not found", func(t *testing.T) {
+		o := NewRollbackOptions()
+		o.WorkDir = workDir
+		o.Serial = 3
+		assert.Error(t, o.Run())
+	})
+}
@@ -0,0 +1,74 @@
+package rollback
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"kusionstack.io/kusion/pkg/engine/operation/graph"
+	"kusionstack.io/kusion/pkg/kusionctl/cmd/util"
+	"kusionstack.io/kusion/pkg/util/i18n"
+)
+
+var (
+	rollbackShort = `Roll back current stack to the state of a previous serial`
+
+	rollbackLong = `
+		Roll back current stack to the state of a previous serial.
+
+		Every applied state is kept in the history with its serial. Resources in the state of the given serial
+		are previewed and applied as the spec instead of compiling KCL, so the actual infra returns to that
+		snapshot. Resources created after it are deleted. By default, Kusion will show the changes and present
+		them for your approval before taking any action.`
+
+	rollbackExample = `
+		# Roll back current stack to the state of serial 3
+		kusion rollback --serial 3
+
+		# Roll back with specifying work directory, and skip interactive approval
+		kusion rollback -w /path/to/workdir --serial 3 --yes`
+)
+
+func NewCmdRollback() *cobra.Command {
+	o := NewRollbackOptions()
+
+	cmd := &cobra.Command{
+		Use:     "rollback",
+		Short:   i18n.T(rollbackShort),
+		Long:    templates.LongDesc(i18n.T(rollbackLong)),
+		Example: templates.Examples(i18n.T(rollbackExample)),
+		RunE: func(_ *cobra.Command, args []string) (err error) {
+			defer util.RecoverErr(&err)
+			o.Complete(args)
+			util.CheckErr(o.Validate())
+			util.CheckErr(o.Run())
+			return
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.WorkDir, "workdir", "w", "",
+		i18n.T("Specify the work directory"))
+	cmd.Flags().Uint64VarP(&o.Serial, "serial", "", 0,
+		i18n.T("The serial of the state to roll back to"))
+	cmd.Flags().StringVarP(&o.Operator, "operator", "", "",
+		i18n.T("Specify the operator"))
+	cmd.Flags().BoolVarP(&o.Yes, "yes", "y", false,
+		i18n.T("Automatically approve and perform the rollback after previewing it"))
+	cmd.Flags().BoolVarP(&o.Detail, "detail", "d", false,
+		i18n.T("Automatically show plan details after previewing it"))
+	cmd.Flags().BoolVarP(&o.NoStyle, "no-style", "", false,
+		i18n.T("no-style sets to RawOutput mode and disables all of styling"))
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false,
+		i18n.T("dry-run to validate the changes by the runtimes without actually applying them or updating the state"))
+	cmd.Flags().DurationVarP(&o.WatchTimeout, "watch-timeout", "", graph.DefaultWatchTimeout,
+		i18n.T("The max duration to wait for each applied resource to be ready"))
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0,
+		i18n.T("The max duration of the whole command, in-flight resources are canceled after it. Zero means no timeout"))
+	cmd.Flags().DurationVarP(&o.ResourceTimeout, "resource-timeout", "", 0,
+		i18n.T("The max duration to read, apply and wait for each resource. Zero means no timeout"))
+	cmd.Flags().IntVarP(&o.Parallelism, "parallelism", "", 0,
+		i18n.T("The max number of resources applied concurrently. Zero means no limit"))
+	cmd.Flags().StringToIntVarP(&o.RuntimeParallelism, "runtime-parallelism", "", map[string]int{},
+		i18n.T("The max number of resources applied concurrently per runtime, e.g. Kubernetes=10. Zero means no limit"))
+
+	return cmd
+}
@@ -0,0 +1,16 @@
+package rollback
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackCommandRun(t *testing.T) {
+	t.Run("no serial", func(t *testing.T) {
+		cmd := NewCmdRollback()
+		cmd.SetArgs([]string{"--workdir", t.TempDir()})
+		err := cmd.Execute()
+		assert.NotNil(t, err)
+	})
+}
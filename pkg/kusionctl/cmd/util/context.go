@@ -0,0 +1,14 @@
+package util
+
+import (
+	"context"
+	"time"
+)
+
+// NewTimeoutContext returns a context canceled after the timeout. Zero or negative timeout means no timeout
+func NewTimeoutContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
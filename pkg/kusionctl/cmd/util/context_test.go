@@ -0,0 +1,23 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewTimeoutContext(t *testing.T) {
+	t.Run("no timeout", func(t *testing.T) {
+		ctx, cancel := NewTimeoutContext(0)
+		defer cancel()
+		if _, ok := ctx.Deadline(); ok {
+			t.Errorf("NewTimeoutContext(0) should not set a deadline")
+		}
+	})
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := NewTimeoutContext(time.Minute)
+		defer cancel()
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("NewTimeoutContext(time.Minute) should set a deadline")
+		}
+	})
+}
@@ -0,0 +1,18 @@
+package util
+
+import "fmt"
+
+// ExitError makes the command exit with specified code instead of the default 1.
+// It is used by commands whose exit codes are consumed by machines, like CI pipelines
+type ExitError struct {
+	Code int
+	// Message is printed before exiting if it is not empty
+	Message string
+}
+
+func (e *ExitError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("exit status %d", e.Code)
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"fmt"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+)
+
+// ValidateParallelism validates values of the --parallelism and --runtime-parallelism flags
+func ValidateParallelism(parallelism int, runtimeParallelism map[string]int) error {
+	if parallelism < 0 {
+		return fmt.Errorf("invalid parallelism %d, it should not be negative", parallelism)
+	}
+	for t, n := range runtimeParallelism {
+		if n < 0 {
+			return fmt.Errorf("invalid parallelism %d of runtime %s, it should not be negative", n, t)
+		}
+	}
+	return nil
+}
+
+// ToRuntimeParallelism converts the value of the --runtime-parallelism flag to parallelism keyed by resource types
+func ToRuntimeParallelism(runtimeParallelism map[string]int) map[models.Type]int {
+	result := make(map[models.Type]int, len(runtimeParallelism))
+	for t, n := range runtimeParallelism {
+		result[models.Type(t)] = n
+	}
+	return result
+}
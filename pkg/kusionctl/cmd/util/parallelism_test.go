@@ -0,0 +1,36 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"kusionstack.io/kusion/pkg/engine/models"
+)
+
+func TestValidateParallelism(t *testing.T) {
+	tests := []struct {
+		name               string
+		parallelism        int
+		runtimeParallelism map[string]int
+		wantErr            bool
+	}{
+		{name: "valid", parallelism: 10, runtimeParallelism: map[string]int{"Kubernetes": 5}},
+		{name: "negative parallelism", parallelism: -1, wantErr: true},
+		{name: "negative runtime parallelism", runtimeParallelism: map[string]int{"Kubernetes": -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateParallelism(tt.parallelism, tt.runtimeParallelism); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateParallelism() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestToRuntimeParallelism(t *testing.T) {
+	got := ToRuntimeParallelism(map[string]int{"Kubernetes": 5})
+	want := map[models.Type]int{models.Kubernetes: 5}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToRuntimeParallelism() = %v, want %v", got, want)
+	}
+}
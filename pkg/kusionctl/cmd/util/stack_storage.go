@@ -0,0 +1,50 @@
+package util
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	opsmodels "kusionstack.io/kusion/pkg/engine/operation/models"
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// NewLocalStackStorage returns a StackStorageFunc locating states of other stacks by the local backend, which are
+// state files in directories of these stacks, as the state of the current stack is. Stacks of the current project
+// are searched in the project directory, and stacks of other projects are searched in the parent directory of it
+func NewLocalStackStorage(project *projectstack.Project) opsmodels.StackStorageFunc {
+	var lock sync.Mutex
+	found := map[string]states.StateStorage{}
+	return func(projectName, stackName string) (states.StateStorage, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		key := projectName + "/" + stackName
+		if storage, ok := found[key]; ok {
+			return storage, nil
+		}
+		dir := filepath.Dir(project.Path)
+		if projectName == project.Name {
+			dir = project.Path
+		}
+		projects, err := projectstack.FindAllProjectsFrom(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range projects {
+			if p.Name != projectName {
+				continue
+			}
+			for _, s := range p.Stacks {
+				if s.Name == stackName {
+					storage := &local.FileSystemState{Path: filepath.Join(s.Path, local.KusionState)}
+					found[key] = storage
+					return storage, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("no directory of stack %s is found in %s", key, dir)
+	}
+}
@@ -0,0 +1,74 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kusionstack.io/kusion/pkg/engine/states"
+	"kusionstack.io/kusion/pkg/engine/states/local"
+	"kusionstack.io/kusion/pkg/projectstack"
+)
+
+// newStack creates the stack directory in the workspace, and applies a state of it by the local backend
+func newStack(t *testing.T, workspace, project, stack string) {
+	projectDir := filepath.Join(workspace, project)
+	stackDir := filepath.Join(projectDir, stack)
+	assert.NoError(t, os.MkdirAll(stackDir, 0o755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(projectDir, projectstack.ProjectFile), []byte("name: "+project), 0o644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(stackDir, projectstack.StackFile), []byte("name: "+stack), 0o644))
+	storage := &local.FileSystemState{Path: filepath.Join(stackDir, local.KusionState)}
+	assert.NoError(t, storage.Apply(&states.State{Project: project, Stack: stack, Serial: 1}))
+}
+
+func TestNewLocalStackStorage(t *testing.T) {
+	workspace := t.TempDir()
+	newStack(t, workspace, "network", "prod")
+	newStack(t, workspace, "app", "dev")
+	newStack(t, workspace, "app", "prod")
+	project, err := projectstack.GetProjectFrom(filepath.Join(workspace, "app"))
+	assert.NoError(t, err)
+	stackStorage := NewLocalStackStorage(project)
+
+	tests := []struct {
+		name    string
+		project string
+		stack   string
+		wantErr bool
+	}{
+		{
+			name:    "stack of the current project",
+			project: "app",
+			stack:   "prod",
+		},
+		{
+			name:    "stack of another project",
+			project: "network",
+			stack:   "prod",
+		},
+		{
+			name:    "stack not found",
+			project: "network",
+			stack:   "dev",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, err := stackStorage(tt.project, tt.stack)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			// the local backend ignores the query, so the storage must be that of the referenced stack
+			state, err := storage.GetLatestState(&states.StateQuery{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.project, state.Project)
+			assert.Equal(t, tt.stack, state.Stack)
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/pterm/pterm"
+
+	"kusionstack.io/kusion/pkg/engine/models"
 	"kusionstack.io/kusion/pkg/log"
 )
 
@@ -92,7 +94,8 @@ func (p *Project) TableReport() string {
 
 // StackConfiguration is the stack configuration
 type StackConfiguration struct {
-	Name string `json:"name" yaml:"name"` // Stack name
+	Name  string         `json:"name" yaml:"name"`                       // Stack name
+	Hooks []*models.Hook `json:"hooks,omitempty" yaml:"hooks,omitempty"` // Hooks executed during apply
 }
 
 type Stack struct {
@@ -20,9 +20,11 @@ const (
 	Unavailable      Code = "UNAVAILABLE"
 	Unimplemented    Code = "UNIMPLEMENTED"
 	Canceled         Code = "CANCELED"
+	DeadlineExceeded Code = "DEADLINE_EXCEEDED"
 	InvalidArgument  Code = "INVALID_ARGUMENT"
 	NotFound         Code = "NOTFOUND"
 	AlreadyExists    Code = "ALREADY_EXISTS"
+	Conflict         Code = "CONFLICT"
 	PermissionDenied Code = "PERMISSION_DENIED"
 	Internal         Code = "INTERNAL"
 	Unauthenticated  Code = "UNAUTHENTICATED"
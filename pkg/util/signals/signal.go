@@ -1,6 +1,8 @@
 package signals
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,13 +12,36 @@ import (
 
 var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
 
-// listen for interrupts or the SIGTERM signal and
-// executing clean job
-func HandleInterrupt() {
-	stopCh := make(chan os.Signal, 1)
+// exit is replaced in tests
+var exit = os.Exit
+
+// HandleInterrupt listens for interrupts or the SIGTERM signal. The first signal calls cancel, so that the running
+// operation stops scheduling new resources, lets in-flight resources finish, and saves the state of
+// completed ones. The second signal exits the process immediately.
+// The returned function stops listening, and should be called once the operation is finished
+func HandleInterrupt(cancel context.CancelFunc) (stop func()) {
+	stopCh := make(chan os.Signal, 2)
+	doneCh := make(chan struct{})
 	signal.Notify(stopCh, shutdownSignals...)
 	go func() {
-		<-stopCh
-		log.Info("Received termination, signaling shutdown, executing clean job")
+		select {
+		case <-stopCh:
+		case <-doneCh:
+			return
+		}
+		log.Info("Received termination, signaling shutdown, waiting for in-flight resources")
+		fmt.Fprintln(os.Stderr, "\nInterrupted, stopping gracefully and saving the state. Press Ctrl+C again to exit immediately")
+		cancel()
+
+		select {
+		case <-stopCh:
+			log.Info("Received termination again, exit immediately")
+			exit(130)
+		case <-doneCh:
+		}
 	}()
+	return func() {
+		signal.Stop(stopCh)
+		close(doneCh)
+	}
 }
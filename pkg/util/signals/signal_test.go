@@ -0,0 +1,45 @@
+//go:build !windows
+// +build !windows
+
+package signals
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleInterrupt(t *testing.T) {
+	exitCh := make(chan int, 1)
+	exit = func(code int) { exitCh <- code }
+	defer func() { exit = os.Exit }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := HandleInterrupt(cancel)
+	defer stop()
+
+	// the first signal cancels the ctx
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("ctx is not canceled after the first signal")
+	}
+
+	// the second signal exits
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case code := <-exitCh:
+		if code != 130 {
+			t.Errorf("exit code = %d, want 130", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("not exit after the second signal")
+	}
+}